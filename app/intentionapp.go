@@ -5,8 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 	"github.com/illmade-knight/action-intention/pkg/crypto"
 	"github.com/illmade-knight/action-intention/pkg/intentions"
 	"github.com/illmade-knight/action-intention/pkg/locations"
@@ -34,12 +37,21 @@ type App struct {
 	LocationSvc  *locations.Service
 	PersonSvc    *people.Service
 	Reconciler   *reconciliation.Reconciler
+	Translator   *reconciliation.Translator
+	Tasks        *reconciliation.TaskService
 	KeyClient    KeyFetcher
 	RouteClient  EnvelopeSender
 	Logger       zerolog.Logger
+
+	// The stores and options the Reconciler and Tasks are built from.
+	taskStore      reconciliation.TaskStore
+	mappingStore   reconciliation.MappingStore
+	reconcilerOpts []reconciliation.ReconcilerOption
 }
 
-// New creates a new, fully initialized App.
+// New creates a new, fully initialized App. Reconciliation tasks and identity
+// mappings are kept in memory until UseTaskStore and UseMappingStore are
+// called with persistent stores.
 func New(
 	intentionSvc *intentions.IntentionService,
 	locationSvc *locations.Service,
@@ -48,19 +60,23 @@ func New(
 	routeClient EnvelopeSender,
 	logger zerolog.Logger,
 ) *App {
-	reconciler := reconciliation.NewReconciler(locationSvc.GetStore(), personSvc.GetStore())
-	return &App{
+	a := &App{
 		IntentionSvc: intentionSvc,
 		LocationSvc:  locationSvc,
 		PersonSvc:    personSvc,
-		Reconciler:   reconciler,
+		Translator:   reconciliation.NewTranslator(intentionSvc),
 		KeyClient:    keyClient,
 		RouteClient:  routeClient,
 		Logger:       logger,
+		taskStore:    reconciliation.NewInMemoryTaskStore(),
+		mappingStore: reconciliation.NewInMemoryMappingStore(),
 	}
+	a.buildReconciliation()
+	return a
 }
 
 // ShareIntention orchestrates the entire process of securely sharing an intention.
+// If the recipient has been invited to the intention, the payload is sent as an invite.
 func (a *App) ShareIntention(ctx context.Context, senderID, recipientID string, intentionID uuid.UUID, privateKeyPEM []byte) error {
 	logger := a.Logger.With().
 		Str("sender_id", senderID).
@@ -75,18 +91,84 @@ func (a *App) ShareIntention(ctx context.Context, senderID, recipientID string,
 	if err != nil {
 		return fmt.Errorf("failed to build shared payload: %w", err)
 	}
+	payload.Kind = sharing.PayloadKindShare
+	if _, invited := payload.Intention.Invitation(recipientID); invited {
+		payload.Kind = sharing.PayloadKindInvite
+	}
+
+	// 2-4. Encrypt, sign and send it
+	if err := a.sendPayload(ctx, senderID, recipientID, payload, privateKeyPEM); err != nil {
+		return err
+	}
+
+	logger.Info().Str("kind", string(payload.Kind)).Msg("Successfully completed intention sharing workflow")
+	return nil
+}
+
+// ShareIntentionWithPerson shares an intention with one of the sender's
+// people, sending it to the user linked to them or to their user ID handle.
+func (a *App) ShareIntentionWithPerson(ctx context.Context, senderID string, personID, intentionID uuid.UUID, privateKeyPEM []byte) error {
+	person, err := a.PersonSvc.GetPerson(ctx, personID)
+	if err != nil {
+		return fmt.Errorf("failed to get person to share with: %w", err)
+	}
+	recipientID, ok := person.RecipientID()
+	if !ok {
+		return apperr.Errorf(apperr.ErrInvalid, "person %s has no user ID to share with", personID)
+	}
+	return a.ShareIntention(ctx, senderID, recipientID, intentionID, privateKeyPEM)
+}
+
+// InviteToIntention invites the recipient to take part in one of the sender's
+// intentions and sends them the intention as an invite.
+func (a *App) InviteToIntention(ctx context.Context, senderID, recipientID string, intentionID uuid.UUID, privateKeyPEM []byte) error {
+	if _, err := a.IntentionSvc.InviteParticipants(ctx, intentionID, recipientID); err != nil {
+		return fmt.Errorf("failed to invite participant: %w", err)
+	}
+	return a.ShareIntention(ctx, senderID, recipientID, intentionID, privateKeyPEM)
+}
+
+// RespondToInvitation sends a participant's RSVP back to the owner of an
+// intention they were invited to.
+func (a *App) RespondToInvitation(ctx context.Context, participantID, ownerID string, intentionID uuid.UUID, rsvp intentions.RSVP, privateKeyPEM []byte) error {
+	if !rsvp.Valid() {
+		return apperr.Errorf(apperr.ErrInvalid, "invalid RSVP: %q", rsvp)
+	}
+	response := sharing.RSVPResponse{
+		Kind:        sharing.PayloadKindRSVP,
+		IntentionID: intentionID,
+		Participant: participantID,
+		RSVP:        rsvp,
+		RespondedAt: time.Now(),
+	}
+	if err := a.sendPayload(ctx, participantID, ownerID, response, privateKeyPEM); err != nil {
+		return err
+	}
+
+	a.Logger.Info().
+		Str("participant_id", participantID).
+		Str("owner_id", ownerID).
+		Stringer("intention_id", intentionID).
+		Str("rsvp", string(rsvp)).
+		Msg("Sent RSVP")
+	return nil
+}
+
+// sendPayload marshals, encrypts and signs a payload and sends it to the
+// recipient through the routing service.
+func (a *App) sendPayload(ctx context.Context, senderID, recipientID string, payload any, privateKeyPEM []byte) error {
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal shared payload: %w", err)
 	}
 
-	// 2. Get the Recipient's Public Key
+	// Get the Recipient's Public Key
 	recipientPubKey, err := a.KeyClient.GetKey(ctx, recipientID)
 	if err != nil {
 		return fmt.Errorf("failed to get recipient's public key: %w", err)
 	}
 
-	// 3. Encrypt and Sign the payload
+	// Encrypt and Sign the payload
 	// The AAD (Additional Authenticated Data) includes sender and recipient IDs
 	// to prevent spoofing or re-routing attacks.
 	aad := []byte(senderID + ":" + recipientID)
@@ -100,7 +182,7 @@ func (a *App) ShareIntention(ctx context.Context, senderID, recipientID string,
 		return fmt.Errorf("failed to sign encrypted data: %w", err)
 	}
 
-	// 4. Create the SecureEnvelope and Send it
+	// Create the SecureEnvelope and Send it
 	envelope := &transport.SecureEnvelope{
 		SenderID:              senderID,
 		RecipientID:           recipientID,
@@ -112,60 +194,74 @@ func (a *App) ShareIntention(ctx context.Context, senderID, recipientID string,
 	if err := a.RouteClient.Send(ctx, envelope); err != nil {
 		return fmt.Errorf("failed to send envelope via routing service: %w", err)
 	}
-
-	logger.Info().Msg("Successfully completed intention sharing workflow")
 	return nil
 }
 
 // buildSharedPayload gathers an intention and all its related data into a portable struct.
 func (a *App) buildSharedPayload(ctx context.Context, intentionID uuid.UUID) (*sharing.SharedPayload, error) {
-	// For simplicity, we query for a single intention by its ID.
-	// A real implementation might use the intention service here.
-	querySpec := intentions.QuerySpec{} // Simplified for this example
-	allIntentions, err := a.IntentionSvc.GetStore().Query(ctx, querySpec)
+	targetIntention, err := a.IntentionSvc.GetIntention(ctx, intentionID)
 	if err != nil {
 		return nil, err
 	}
-	var targetIntention intentions.Intention
-	found := false
-	for _, intent := range allIntentions {
-		if intent.ID == intentionID {
-			targetIntention = intent
-			found = true
-			break
-		}
-	}
-	if !found {
-		return nil, fmt.Errorf("intention with ID %s not found", intentionID)
-	}
 
 	payload := &sharing.SharedPayload{
-		Intention: targetIntention,
-		Locations: make(map[string]locations.Location),
-		People:    make(map[string]people.Person),
-		Groups:    make(map[string]people.Group),
-	}
-
-	// Gather all related locations, people, and groups from the intention's targets.
-	for _, target := range targetIntention.Targets {
-		switch t := target.(type) {
-		case intentions.LocationTarget:
-			loc, err := a.LocationSvc.GetLocation(ctx, t.LocationID)
-			if err == nil {
-				payload.Locations[loc.ID.String()] = loc
+		Intention:         targetIntention,
+		RelatedIntentions: make(map[string]intentions.Intention),
+		Locations:         make(map[string]locations.Location),
+		People:            make(map[string]people.Person),
+		Groups:            make(map[string]people.Group),
+	}
+
+	// Gather all related locations, people, groups and intentions from the intention's targets.
+	// Referenced intentions (e.g. from an EventTarget) are followed so their own targets are
+	// included too; the visited set guards against reference cycles.
+	visited := map[uuid.UUID]bool{targetIntention.ID: true}
+	pending := []intentions.Intention{targetIntention}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+
+		for _, target := range current.Targets {
+			refs := intentions.TargetReferences(target)
+			for _, locationID := range refs.LocationIDs {
+				loc, err := a.LocationSvc.GetLocation(ctx, locationID)
+				if err == nil {
+					payload.Locations[loc.ID.String()] = loc
+				}
+			}
+			personIDs := refs.PersonIDs
+			for _, groupID := range refs.GroupIDs {
+				// A group is shared with its nested groups, and everyone they
+				// contain is shared as a person the recipient can reconcile.
+				groups, err := people.ExpandGroups(ctx, a.PersonSvc.GetGroup, groupID)
+				if err != nil {
+					continue
+				}
+				for _, g := range groups {
+					payload.Groups[g.ID.String()] = g
+				}
+				members, err := a.PersonSvc.ResolveMembers(ctx, groupID)
+				if err == nil {
+					personIDs = append(slices.Clip(personIDs), members...)
+				}
 			}
-		case intentions.ProximityTarget:
-			for _, personID := range t.PersonIDs {
+			for _, personID := range personIDs {
 				p, err := a.PersonSvc.GetPerson(ctx, personID)
 				if err == nil {
 					payload.People[p.ID.String()] = p
 				}
 			}
-			for _, groupID := range t.GroupIDs {
-				g, err := a.PersonSvc.GetGroup(ctx, groupID)
-				if err == nil {
-					payload.Groups[g.ID.String()] = g
+			for _, relatedID := range refs.IntentionIDs {
+				if visited[relatedID] {
+					continue
+				}
+				visited[relatedID] = true
+				related, err := a.IntentionSvc.GetIntention(ctx, relatedID)
+				if err != nil {
+					continue
 				}
+				payload.RelatedIntentions[related.ID.String()] = related
+				pending = append(pending, related)
 			}
 		}
 	}
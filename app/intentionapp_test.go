@@ -1,15 +1,22 @@
 package app_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/illmade-knight/action-intention/app"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 	"github.com/illmade-knight/action-intention/pkg/crypto"
 	"github.com/illmade-knight/action-intention/pkg/intentions"
 	"github.com/illmade-knight/action-intention/pkg/locations"
 	"github.com/illmade-knight/action-intention/pkg/people"
+	"github.com/illmade-knight/action-intention/pkg/reconciliation"
+	"github.com/illmade-knight/action-intention/pkg/sharing"
 	"github.com/illmade-knight/go-secure-messaging/pkg/transport"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
@@ -90,3 +97,850 @@ func TestApp_ShareIntention(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, sendCalled, "Expected the routing service client's Send method to be called")
 }
+
+func TestApp_ShareIntentionWithPerson(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: Bob is linked to a user; Carol only has an email address
+	senderPrivKey, _, err := crypto.GenerateKeys()
+	require.NoError(t, err)
+	_, recipientPubKey, err := crypto.GenerateKeys()
+	require.NoError(t, err)
+
+	locSvc := locations.NewService(locations.NewInMemoryStore())
+	testLoc, err := locSvc.AddSharedLocation(ctx, "Test Park", "Recreation")
+	require.NoError(t, err)
+	personSvc := people.NewService(people.NewInMemoryStore())
+	bob, err := personSvc.CreatePerson(ctx, "Bob", people.Handle{Kind: people.HandleUserID, Value: "user-bob"})
+	require.NoError(t, err)
+	carol, err := personSvc.CreatePerson(ctx, "Carol", people.Handle{Kind: people.HandleEmail, Value: "carol@example.com"})
+	require.NoError(t, err)
+
+	intentionSvc := intentions.NewIntentionService(intentions.NewInMemoryStore())
+	testIntent, err := intentionSvc.AddIntention(ctx, "sender", "Meet", []intentions.Target{
+		intentions.LocationTarget{LocationID: testLoc.ID},
+	}, time.Now(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	keyClient := &mockKeyClient{
+		GetKeyFunc: func(ctx context.Context, userID string) ([]byte, error) {
+			return recipientPubKey, nil
+		},
+	}
+	var recipients []string
+	routeClient := &mockRouteClient{
+		SendFunc: func(ctx context.Context, envelope *transport.SecureEnvelope) error {
+			recipients = append(recipients, envelope.RecipientID)
+			return nil
+		},
+	}
+	application := app.New(intentionSvc, locSvc, personSvc, keyClient, routeClient, zerolog.Nop())
+
+	// Act & Assert: Bob's handle resolves to his user ID
+	require.NoError(t, application.ShareIntentionWithPerson(ctx, "sender", bob.ID, testIntent.ID, senderPrivKey))
+	assert.Equal(t, []string{"user-bob"}, recipients)
+
+	// Act & Assert: Carol cannot be reached through the routing service
+	err = application.ShareIntentionWithPerson(ctx, "sender", carol.ID, testIntent.ID, senderPrivKey)
+	assert.ErrorIs(t, err, apperr.ErrInvalid)
+	err = application.ShareIntentionWithPerson(ctx, "sender", uuid.New(), testIntent.ID, senderPrivKey)
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+	assert.Len(t, recipients, 1)
+}
+
+func TestApp_ShareIntention_PayloadRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: Local data and an intention with both kinds of target
+	locSvc := locations.NewService(locations.NewInMemoryStore())
+	testLoc, err := locSvc.AddSharedLocation(ctx, "Test Park", "Recreation")
+	require.NoError(t, err)
+
+	personSvc := people.NewService(people.NewInMemoryStore())
+	testPerson, err := personSvc.CreatePerson(ctx, "Bob")
+	require.NoError(t, err)
+
+	intentionSvc := intentions.NewIntentionService(intentions.NewInMemoryStore())
+	testIntent, err := intentionSvc.AddIntention(ctx, "sender", "Meet", []intentions.Target{
+		intentions.LocationTarget{LocationID: testLoc.ID},
+		intentions.ProximityTarget{PersonIDs: []uuid.UUID{testPerson.ID}},
+	}, time.Now(), time.Now().Add(1*time.Hour))
+	require.NoError(t, err)
+
+	// Act: Share, then decrypt the envelope as the recipient would
+	payload := shareAndDecrypt(t, intentionSvc, locSvc, personSvc, testIntent.ID)
+
+	// Assert: The targets are rebuilt as their concrete types
+	require.Len(t, payload.Intention.Targets, 2)
+	assert.Equal(t, intentions.LocationTarget{LocationID: testLoc.ID}, payload.Intention.Targets[0])
+	assert.Equal(t, intentions.ProximityTarget{PersonIDs: []uuid.UUID{testPerson.ID}}, payload.Intention.Targets[1])
+	assert.Equal(t, testIntent.ID, payload.Intention.ID)
+	assert.Contains(t, payload.Locations, testLoc.ID.String())
+	assert.Contains(t, payload.People, testPerson.ID.String())
+}
+
+func TestApp_ShareIntention_RouteAndEventTargets(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: A route between two locations, and an event that references it
+	locSvc := locations.NewService(locations.NewInMemoryStore())
+	start, err := locSvc.AddSharedLocation(ctx, "Station", "Transport")
+	require.NoError(t, err)
+	finish, err := locSvc.AddSharedLocation(ctx, "Stadium", "Sport")
+	require.NoError(t, err)
+	personSvc := people.NewService(people.NewInMemoryStore())
+
+	intentionSvc := intentions.NewIntentionService(intentions.NewInMemoryStore())
+	now := time.Now()
+	match, err := intentionSvc.AddIntention(ctx, "sender", "Go to the match", []intentions.Target{
+		intentions.RouteTarget{LocationIDs: []uuid.UUID{start.ID, finish.ID}},
+	}, now, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	join, err := intentionSvc.AddIntention(ctx, "sender", "Watch together", []intentions.Target{
+		intentions.EventTarget{IntentionID: match.ID},
+		intentions.OnlineTarget{Platform: "Discord", URL: "https://discord.example/room"},
+	}, now, now.Add(2*time.Hour))
+	require.NoError(t, err)
+
+	// Act
+	payload := shareAndDecrypt(t, intentionSvc, locSvc, personSvc, join.ID)
+
+	// Assert: Targets survive the round trip and referenced data is hydrated
+	require.Len(t, payload.Intention.Targets, 2)
+	assert.Equal(t, intentions.EventTarget{IntentionID: match.ID}, payload.Intention.Targets[0])
+	assert.Equal(t, intentions.OnlineTarget{Platform: "Discord", URL: "https://discord.example/room"}, payload.Intention.Targets[1])
+
+	require.Contains(t, payload.RelatedIntentions, match.ID.String())
+	related := payload.RelatedIntentions[match.ID.String()]
+	assert.Equal(t, []intentions.Target{intentions.RouteTarget{LocationIDs: []uuid.UUID{start.ID, finish.ID}}}, related.Targets)
+	assert.Contains(t, payload.Locations, start.ID.String())
+	assert.Contains(t, payload.Locations, finish.ID.String())
+}
+
+func TestApp_ShareIntention_ExpandsNestedGroups(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: A team with a nested subteam, each with its own member
+	locSvc := locations.NewService(locations.NewInMemoryStore())
+	personSvc := people.NewService(people.NewInMemoryStore())
+	alice, err := personSvc.CreatePerson(ctx, "Alice")
+	require.NoError(t, err)
+	bob, err := personSvc.CreatePerson(ctx, "Bob")
+	require.NoError(t, err)
+	team, err := personSvc.CreateGroup(ctx, "Team")
+	require.NoError(t, err)
+	subteam, err := personSvc.CreateGroup(ctx, "Subteam")
+	require.NoError(t, err)
+	require.NoError(t, personSvc.AddMemberToGroup(ctx, team.ID, alice.ID))
+	require.NoError(t, personSvc.AddMemberToGroup(ctx, subteam.ID, bob.ID))
+	require.NoError(t, personSvc.AddSubgroup(ctx, team.ID, subteam.ID))
+
+	intentionSvc := intentions.NewIntentionService(intentions.NewInMemoryStore())
+	intent, err := intentionSvc.AddIntention(ctx, "sender", "Team lunch", []intentions.Target{
+		intentions.ProximityTarget{GroupIDs: []uuid.UUID{team.ID}},
+	}, time.Now(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// Act
+	payload := shareAndDecrypt(t, intentionSvc, locSvc, personSvc, intent.ID)
+
+	// Assert: Both groups and everyone they contain are shared
+	assert.Contains(t, payload.Groups, team.ID.String())
+	assert.Contains(t, payload.Groups, subteam.ID.String())
+	assert.Contains(t, payload.People, alice.ID.String())
+	assert.Contains(t, payload.People, bob.ID.String())
+}
+
+func TestApp_ShareIntention_CarriesStatus(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: A confirmed intention
+	locSvc := locations.NewService(locations.NewInMemoryStore())
+	testLoc, err := locSvc.AddSharedLocation(ctx, "Test Park", "Recreation")
+	require.NoError(t, err)
+	personSvc := people.NewService(people.NewInMemoryStore())
+	intentionSvc := intentions.NewIntentionService(intentions.NewInMemoryStore())
+	testIntent, err := intentionSvc.AddIntention(ctx, "sender", "Meet", []intentions.Target{
+		intentions.LocationTarget{LocationID: testLoc.ID},
+	}, time.Now(), time.Now().Add(1*time.Hour))
+	require.NoError(t, err)
+	confirmed, err := intentionSvc.ConfirmIntention(ctx, testIntent.ID)
+	require.NoError(t, err)
+
+	// Act
+	payload := shareAndDecrypt(t, intentionSvc, locSvc, personSvc, testIntent.ID)
+
+	// Assert: The recipient sees the status and when it changed
+	assert.Equal(t, intentions.StatusConfirmed, payload.Intention.Status)
+	require.NotNil(t, payload.Intention.ConfirmedAt)
+	assert.True(t, confirmed.ConfirmedAt.Equal(*payload.Intention.ConfirmedAt))
+}
+
+// shareAndDecrypt shares an intention through an App wired to mock clients and
+// returns the payload as the recipient would see it after decryption.
+func shareAndDecrypt(t *testing.T, intentionSvc *intentions.IntentionService, locSvc *locations.Service, personSvc *people.Service, intentionID uuid.UUID) sharing.SharedPayload {
+	t.Helper()
+	ctx := context.Background()
+
+	senderPrivKey, _, err := crypto.GenerateKeys()
+	require.NoError(t, err)
+	recipientPrivKey, recipientPubKey, err := crypto.GenerateKeys()
+	require.NoError(t, err)
+
+	keyClient := &mockKeyClient{
+		GetKeyFunc: func(ctx context.Context, userID string) ([]byte, error) {
+			return recipientPubKey, nil
+		},
+	}
+	var sent *transport.SecureEnvelope
+	routeClient := &mockRouteClient{
+		SendFunc: func(ctx context.Context, envelope *transport.SecureEnvelope) error {
+			sent = envelope
+			return nil
+		},
+	}
+	application := app.New(intentionSvc, locSvc, personSvc, keyClient, routeClient, zerolog.Nop())
+
+	err = application.ShareIntention(ctx, "sender", "recipient", intentionID, senderPrivKey)
+	require.NoError(t, err)
+	require.NotNil(t, sent)
+
+	plaintext, err := crypto.Decrypt(sent.EncryptedSymmetricKey, sent.EncryptedData, []byte("sender:recipient"), recipientPrivKey)
+	require.NoError(t, err)
+	var payload sharing.SharedPayload
+	require.NoError(t, json.Unmarshal(plaintext, &payload))
+	return payload
+}
+
+// testUser is one side of a twoUsers fixture: an App over its own in-memory
+// stores, and the private key it signs and decrypts with.
+type testUser struct {
+	id          string
+	privKey     []byte
+	intentions  *intentions.IntentionService
+	locations   *locations.Service
+	people      *people.Service
+	app         *app.App
+	keyClient   *mockKeyClient
+	routeClient *mockRouteClient
+}
+
+// rebuild replaces the user's location service and logger, for tests that
+// need a geocoder, a failing store or the log output. It must be called before
+// anything is stored through the old location service.
+func (u *testUser) rebuild(locSvc *locations.Service, logger zerolog.Logger) {
+	u.locations = locSvc
+	u.app = app.New(u.intentions, locSvc, u.people, u.keyClient, u.routeClient, logger)
+}
+
+// twoUsers is alice and bob, whose apps share mock clients: the key client
+// publishes both users' public keys and the routing client keeps every
+// envelope sent.
+type twoUsers struct {
+	alice, bob *testUser
+	sent       []*transport.SecureEnvelope
+}
+
+func newTwoUsers(t *testing.T) *twoUsers {
+	t.Helper()
+	f := &twoUsers{}
+	publicKeys := make(map[string][]byte)
+	keyClient := &mockKeyClient{
+		GetKeyFunc: func(ctx context.Context, userID string) ([]byte, error) {
+			key, ok := publicKeys[userID]
+			if !ok {
+				return nil, apperr.Errorf(apperr.ErrNotFound, "no key for %s", userID)
+			}
+			return key, nil
+		},
+	}
+	routeClient := &mockRouteClient{
+		SendFunc: func(ctx context.Context, envelope *transport.SecureEnvelope) error {
+			f.sent = append(f.sent, envelope)
+			return nil
+		},
+	}
+	newUser := func(id string) *testUser {
+		privKey, pubKey, err := crypto.GenerateKeys()
+		require.NoError(t, err)
+		publicKeys[id] = pubKey
+		u := &testUser{
+			id:          id,
+			privKey:     privKey,
+			intentions:  intentions.NewIntentionService(intentions.NewInMemoryStore()),
+			people:      people.NewService(people.NewInMemoryStore()),
+			keyClient:   keyClient,
+			routeClient: routeClient,
+		}
+		u.rebuild(locations.NewService(locations.NewInMemoryStore()), zerolog.Nop())
+		return u
+	}
+	f.alice, f.bob = newUser("alice"), newUser("bob")
+	return f
+}
+
+// share sends one of alice's intentions to bob and returns the envelope.
+func (f *twoUsers) share(t *testing.T, intentionID uuid.UUID) *transport.SecureEnvelope {
+	t.Helper()
+	require.NoError(t, f.alice.app.ShareIntention(context.Background(), f.alice.id, f.bob.id, intentionID, f.alice.privKey))
+	return f.sent[len(f.sent)-1]
+}
+
+// receive has bob process an envelope.
+func (f *twoUsers) receive(t *testing.T, envelope *transport.SecureEnvelope) *app.ReceiveResult {
+	t.Helper()
+	result, err := f.bob.app.ReceiveEnvelope(context.Background(), envelope, f.bob.privKey)
+	require.NoError(t, err)
+	return result
+}
+
+func TestApp_InvitationRSVP(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: Alice owns a picnic and Bob is her guest
+	users := newTwoUsers(t)
+	alice, bob := users.alice, users.bob
+	testLoc, err := alice.locations.AddSharedLocation(ctx, "Test Park", "Recreation")
+	require.NoError(t, err)
+	testIntent, err := alice.intentions.AddIntention(ctx, "alice", "Picnic", []intentions.Target{
+		intentions.LocationTarget{LocationID: testLoc.ID},
+	}, time.Now(), time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+
+	// Act: Alice invites Bob
+	require.NoError(t, alice.app.InviteToIntention(ctx, "alice", "bob", testIntent.ID, alice.privKey))
+
+	// Assert: Bob receives an invite-type payload with a pending invitation
+	require.Len(t, users.sent, 1)
+	aad := []byte("alice:bob")
+	plaintext, err := crypto.Decrypt(users.sent[0].EncryptedSymmetricKey, users.sent[0].EncryptedData, aad, bob.privKey)
+	require.NoError(t, err)
+	var invite sharing.SharedPayload
+	require.NoError(t, json.Unmarshal(plaintext, &invite))
+	assert.Equal(t, sharing.PayloadKindInvite, invite.Kind)
+	invitation, ok := invite.Intention.Invitation("bob")
+	require.True(t, ok)
+	assert.Equal(t, intentions.RSVPPending, invitation.RSVP)
+
+	// Act: Bob accepts, and Alice processes the reply
+	require.NoError(t, bob.app.RespondToInvitation(ctx, "bob", "alice", testIntent.ID, intentions.RSVPAccepted, bob.privKey))
+	require.Len(t, users.sent, 2)
+	result, err := alice.app.ReceiveEnvelope(ctx, users.sent[1], alice.privKey)
+
+	// Assert: Alice's copy records the acceptance
+	require.NoError(t, err)
+	assert.Equal(t, sharing.PayloadKindRSVP, result.Kind)
+	updated := result.Intention
+	invitation, ok = updated.Invitation("bob")
+	require.True(t, ok)
+	assert.Equal(t, intentions.RSVPAccepted, invitation.RSVP)
+	assert.NotNil(t, invitation.RespondedAt)
+	assert.Contains(t, updated.Participants, "bob")
+}
+
+func TestApp_ReceiveEnvelope(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: Alice and Bob each know the same cafe under their own IDs
+	users := newTwoUsers(t)
+	alice, bob := users.alice, users.bob
+	aliceCafe, err := alice.locations.AddSharedLocation(ctx, "Corner Cafe", "Cafe")
+	require.NoError(t, err)
+	coffee, err := alice.intentions.AddIntention(ctx, "alice", "Coffee", []intentions.Target{
+		intentions.LocationTarget{LocationID: aliceCafe.ID},
+	}, time.Now(), time.Now().Add(1*time.Hour))
+	require.NoError(t, err)
+	bobCafe, err := bob.locations.AddSharedLocation(ctx, "Corner Cafe", "Cafe")
+	require.NoError(t, err)
+
+	envelope := users.share(t, coffee.ID)
+
+	t.Run("Stores the intention in local IDs", func(t *testing.T) {
+		result := users.receive(t, envelope)
+
+		assert.Equal(t, sharing.PayloadKindShare, result.Kind)
+		assert.Equal(t, bobCafe.ID, result.Mappings.LocationMappings[aliceCafe.ID])
+		stored, err := bob.intentions.GetIntention(ctx, result.Intention.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []intentions.Target{intentions.LocationTarget{LocationID: bobCafe.ID}}, stored.Targets)
+		assert.Equal(t, "alice", stored.User)
+	})
+
+	t.Run("Rejects a tampered signature", func(t *testing.T) {
+		tampered := *envelope
+		tampered.Signature = append([]byte{}, envelope.Signature...)
+		tampered.Signature[0] ^= 0xFF
+
+		_, err := bob.app.ReceiveEnvelope(ctx, &tampered, bob.privKey)
+		var receiveErr *app.ReceiveError
+		require.ErrorAs(t, err, &receiveErr)
+		assert.Equal(t, app.StageVerify, receiveErr.Stage)
+		assert.ErrorIs(t, err, apperr.ErrUnauthorized)
+	})
+
+	t.Run("Rejects a re-routed envelope", func(t *testing.T) {
+		rerouted := *envelope
+		rerouted.RecipientID = "mallory"
+
+		_, err := bob.app.ReceiveEnvelope(ctx, &rerouted, bob.privKey)
+		var receiveErr *app.ReceiveError
+		require.ErrorAs(t, err, &receiveErr)
+		assert.Equal(t, app.StageDecrypt, receiveErr.Stage)
+		assert.ErrorIs(t, err, apperr.ErrUnauthorized)
+	})
+}
+
+func TestApp_ReconciliationTasks(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: Bob knows places with the same names as Alice's, but filed differently
+	users := newTwoUsers(t)
+	alice, bob := users.alice, users.bob
+	aliceCafe, err := alice.locations.AddSharedLocation(ctx, "Corner Cafe", "Cafe")
+	require.NoError(t, err)
+	alicePark, err := alice.locations.AddSharedLocation(ctx, "Riverside", "Park")
+	require.NoError(t, err)
+	walk, err := alice.intentions.AddIntention(ctx, "alice", "Coffee then a walk", []intentions.Target{
+		intentions.RouteTarget{LocationIDs: []uuid.UUID{aliceCafe.ID, alicePark.ID}},
+	}, time.Now(), time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	bobCafe, err := bob.locations.AddSharedLocation(ctx, "Corner Cafe", "Bar")
+	require.NoError(t, err)
+	bobRiverside, err := bob.locations.AddSharedLocation(ctx, "Riverside", "Apartments")
+	require.NoError(t, err)
+
+	// Act: Bob receives the intention
+	result := users.receive(t, users.share(t, walk.ID))
+
+	// Assert: Nothing is auto-mapped; the intention waits on one task per place
+	require.NotNil(t, result.Pending)
+	assert.Empty(t, result.Mappings.LocationMappings)
+	tasks, err := bob.app.ListReconciliationTasks(ctx, reconciliation.TaskPending)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	taskFor := make(map[uuid.UUID]reconciliation.Task)
+	for _, task := range tasks {
+		require.Len(t, task.Candidates, 1)
+		taskFor[task.SenderEntityID] = task
+	}
+	cafeTask, parkTask := taskFor[aliceCafe.ID], taskFor[alicePark.ID]
+	assert.Equal(t, bobCafe.ID, cafeTask.Candidates[0].LocalID)
+	assert.InDelta(t, 0.6, cafeTask.Candidates[0].Score, 0.001)
+	assert.Contains(t, cafeTask.Candidates[0].Explanation, "category differs")
+	assert.Equal(t, bobRiverside.ID, parkTask.Candidates[0].LocalID)
+	stored, _, err := bob.intentions.QueryIntentions(ctx, intentions.QuerySpec{})
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+
+	// Act: Bob confirms the cafe but says the park is somewhere new
+	_, err = bob.app.ConfirmMatch(ctx, cafeTask.ID, bobRiverside.ID)
+	require.ErrorIs(t, err, apperr.ErrInvalid, "only candidates can be confirmed")
+	finalized, err := bob.app.ConfirmMatch(ctx, cafeTask.ID, bobCafe.ID)
+	require.NoError(t, err)
+	assert.Nil(t, finalized, "the park task is still open")
+	finalized, err = bob.app.RejectMatch(ctx, parkTask.ID)
+	require.NoError(t, err)
+
+	// Assert: The intention is stored with the confirmed cafe and a new park
+	require.NotNil(t, finalized)
+	require.Len(t, finalized.Targets, 1)
+	route := finalized.Targets[0].(intentions.RouteTarget)
+	require.Len(t, route.LocationIDs, 2)
+	assert.Equal(t, bobCafe.ID, route.LocationIDs[0])
+	newPark, err := bob.locations.GetLocation(ctx, route.LocationIDs[1])
+	require.NoError(t, err)
+	assert.Equal(t, "Riverside", newPark.Name)
+	assert.Equal(t, "Park", newPark.Category)
+	assert.NotEqual(t, bobRiverside.ID, newPark.ID)
+
+	_, err = bob.intentions.GetIntention(ctx, finalized.ID)
+	require.NoError(t, err)
+	tasks, err = bob.app.ListReconciliationTasks(ctx, reconciliation.TaskPending)
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+	_, err = bob.app.ConfirmMatch(ctx, cafeTask.ID, bobCafe.ID)
+	assert.ErrorIs(t, err, reconciliation.ErrTaskResolved)
+	assert.ErrorIs(t, err, apperr.ErrConflict)
+	_, err = bob.app.ConfirmMatch(ctx, uuid.New(), bobCafe.ID)
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+}
+
+// failingLocationStore fails Add while failAdd is set.
+type failingLocationStore struct {
+	locations.Store
+	failAdd bool
+}
+
+func (s *failingLocationStore) Add(ctx context.Context, loc locations.Location) error {
+	if s.failAdd {
+		return errors.New("store unavailable")
+	}
+	return s.Store.Add(ctx, loc)
+}
+
+func TestApp_RejectMatch_RetriesAFailedCreate(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: Bob's only task is for a park he files as apartments, and his store is down
+	users := newTwoUsers(t)
+	alice, bob := users.alice, users.bob
+	bobLocStore := &failingLocationStore{Store: locations.NewInMemoryStore()}
+	bob.rebuild(locations.NewService(bobLocStore), zerolog.Nop())
+	alicePark, err := alice.locations.AddSharedLocation(ctx, "Riverside", "Park")
+	require.NoError(t, err)
+	walk, err := alice.intentions.AddIntention(ctx, "alice", "Walk", []intentions.Target{
+		intentions.LocationTarget{LocationID: alicePark.ID},
+	}, time.Now(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	_, err = bob.locations.AddSharedLocation(ctx, "Riverside", "Apartments")
+	require.NoError(t, err)
+	result := users.receive(t, users.share(t, walk.ID))
+	require.NotNil(t, result.Pending)
+	require.Len(t, result.Pending.TaskIDs, 1)
+	taskID := result.Pending.TaskIDs[0]
+	bobLocStore.failAdd = true
+
+	// Act: The first reject cannot create the park
+	finalized, err := bob.app.RejectMatch(ctx, taskID)
+
+	// Assert: The task stays open
+	require.Error(t, err)
+	assert.Nil(t, finalized)
+	tasks, err := bob.app.ListReconciliationTasks(ctx, reconciliation.TaskPending)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, taskID, tasks[0].ID)
+
+	// Act: Bob tries again once the store is back
+	bobLocStore.failAdd = false
+	finalized, err = bob.app.RejectMatch(ctx, taskID)
+
+	// Assert: The intention is finalized with the new park
+	require.NoError(t, err)
+	require.NotNil(t, finalized)
+	target, ok := finalized.Targets[0].(intentions.LocationTarget)
+	require.True(t, ok)
+	newPark, err := bob.locations.GetLocation(ctx, target.LocationID)
+	require.NoError(t, err)
+	assert.Equal(t, "Park", newPark.Category)
+	_, err = bob.intentions.GetIntention(ctx, finalized.ID)
+	assert.NoError(t, err)
+}
+
+func TestApp_ReceiveEnvelope_MatchesNearbyLocations(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: Bob has a "Corner Cafe" next to Alice's and another across the country
+	users := newTwoUsers(t)
+	alice, bob := users.alice, users.bob
+	cafeAt := func(name string, lat, lon float64) locations.Location {
+		return locations.Location{
+			ID:       uuid.New(),
+			Name:     name,
+			Type:     locations.LocationTypeShared,
+			Matcher:  locations.LocationMatcher{Name: name, Lat: &lat, Lon: &lon},
+			Category: "Cafe",
+		}
+	}
+
+	aliceCafe := cafeAt("Corner Cafe", 55.9533, -3.1883)
+	require.NoError(t, alice.locations.GetStore().Add(ctx, aliceCafe))
+	coffee, err := alice.intentions.AddIntention(ctx, "alice", "Coffee", []intentions.Target{
+		intentions.LocationTarget{LocationID: aliceCafe.ID},
+	}, time.Now(), time.Now().Add(1*time.Hour))
+	require.NoError(t, err)
+
+	bobLocStore := bob.locations.GetStore()
+	bobCafe := cafeAt("Corner Cafe", 55.9534, -3.1885)
+	require.NoError(t, bobLocStore.Add(ctx, bobCafe))
+	require.NoError(t, bobLocStore.Add(ctx, cafeAt("Corner Cafe", 51.5072, -0.1276)))
+	for i := 0; i < 100; i++ {
+		require.NoError(t, bobLocStore.Add(ctx, cafeAt("Elsewhere", 50+float64(i)/10, -2)))
+	}
+
+	nearby, err := bobLocStore.NearbyForMatching(ctx, 55.9533, -3.1883, locations.MaxMatchDistanceKm)
+	require.NoError(t, err)
+	require.Len(t, nearby, 1, "only the cafe next door is within matching distance")
+
+	// Act
+	result := users.receive(t, users.share(t, coffee.ID))
+
+	// Assert: The nearby cafe is mapped without a task for the distant one
+	assert.Nil(t, result.Pending)
+	assert.Equal(t, bobCafe.ID, result.Mappings.LocationMappings[aliceCafe.ID])
+	candidates := result.Mappings.LocationCandidates[aliceCafe.ID]
+	require.Len(t, candidates, 1)
+	assert.Equal(t, bobCafe.ID, candidates[0].LocalID)
+	assert.InDelta(t, 1, candidates[0].Score, 0.001)
+}
+
+func TestApp_ReceiveEnvelope_MatchesGeocodedAddress(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: Alice only knows the cafe's address; Bob dropped a pin on it
+	users := newTwoUsers(t)
+	alice, bob := users.alice, users.bob
+	geocoder := locations.NewFixtureGeocoder()
+	geocoder.Add(locations.Address{Street: "1 High Street", Locality: "Edinburgh", PostalCode: "EH1 1SR", Country: "UK"},
+		locations.Coordinates{Lat: 55.9500, Lon: -3.1880})
+	alice.rebuild(locations.NewService(locations.NewInMemoryStore(), locations.WithGeocoder(geocoder)), zerolog.Nop())
+
+	aliceCafe, err := alice.locations.AddUserLocationWithDetails(ctx, "alice", locations.LocationDetails{
+		Name:     "Corner Cafe",
+		Category: "Cafe",
+		Address:  &locations.Address{Street: "1 High St.", Locality: "Edinburgh", PostalCode: "EH1 1SR", Country: "UK"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, aliceCafe.Coordinates, "the postal code is enough to geocode the address")
+	assert.InDelta(t, 55.9500, *aliceCafe.Matcher.Lat, 0.0001)
+	unknown, err := alice.locations.AddUserLocationWithDetails(ctx, "alice", locations.LocationDetails{
+		Name:    "Somewhere",
+		Address: &locations.Address{Street: "2 Nowhere Lane", Country: "UK"},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, unknown.Coordinates)
+	coffee, err := alice.intentions.AddIntention(ctx, "alice", "Coffee", []intentions.Target{
+		intentions.LocationTarget{LocationID: aliceCafe.ID},
+	}, time.Now(), time.Now().Add(1*time.Hour))
+	require.NoError(t, err)
+
+	radius := 15.0
+	bobCafe, err := bob.locations.AddSharedLocationWithDetails(ctx, locations.LocationDetails{
+		Name:         "Corner Café",
+		Category:     "Coffee",
+		Coordinates:  &locations.Coordinates{Lat: 55.9501, Lon: -3.1881},
+		RadiusMeters: &radius,
+	})
+	require.NoError(t, err)
+
+	// Act
+	result := users.receive(t, users.share(t, coffee.ID))
+
+	// Assert: The coordinates outweigh the differing category
+	assert.Nil(t, result.Pending)
+	assert.Equal(t, bobCafe.ID, result.Mappings.LocationMappings[aliceCafe.ID])
+}
+
+func TestApp_ReceiveEnvelope_CreatesUnmatched(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: Alice shares a cafe and a nested group of climbers with Bob, who knows none of them
+	users := newTwoUsers(t)
+	alice, bob := users.alice, users.bob
+	aliceCafe, err := alice.locations.AddUserLocation(ctx, "alice", "Corner Cafe", "Cafe")
+	require.NoError(t, err)
+	jim, err := alice.people.CreatePerson(ctx, "Jim")
+	require.NoError(t, err)
+	sam, err := alice.people.CreatePerson(ctx, "Sam")
+	require.NoError(t, err)
+	climbers, err := alice.people.CreateGroup(ctx, "Climbers")
+	require.NoError(t, err)
+	belayers, err := alice.people.CreateGroup(ctx, "Belayers")
+	require.NoError(t, err)
+	require.NoError(t, alice.people.AddMemberToGroup(ctx, climbers.ID, jim.ID))
+	require.NoError(t, alice.people.AddMemberToGroup(ctx, belayers.ID, sam.ID))
+	require.NoError(t, alice.people.AddSubgroup(ctx, climbers.ID, belayers.ID))
+	climb, err := alice.intentions.AddIntention(ctx, "alice", "Climb", []intentions.Target{
+		intentions.LocationTarget{LocationID: aliceCafe.ID},
+		intentions.ProximityTarget{GroupIDs: []uuid.UUID{climbers.ID}},
+	}, time.Now(), time.Now().Add(1*time.Hour))
+	require.NoError(t, err)
+	bob.app.UseReconcilerOptions(reconciliation.WithCreateUnmatched())
+
+	envelope := users.share(t, climb.ID)
+
+	// Act
+	first := users.receive(t, envelope)
+
+	// Assert: Everything is created locally, marked with where it came from
+	require.Nil(t, first.Pending)
+	mappings := first.Mappings
+	require.Len(t, mappings.CreatedLocations, 1)
+	assert.Len(t, mappings.CreatedPeople, 2)
+	assert.Len(t, mappings.CreatedGroups, 2)
+
+	bobCafe, err := bob.locations.GetLocation(ctx, mappings.LocationMappings[aliceCafe.ID])
+	require.NoError(t, err)
+	assert.Equal(t, mappings.CreatedLocations[0], bobCafe.ID)
+	assert.Equal(t, "Corner Cafe", bobCafe.Name)
+	require.NotNil(t, bobCafe.UserID)
+	assert.Equal(t, "bob", *bobCafe.UserID, "a private location becomes the recipient's own")
+	require.NotNil(t, bobCafe.Provenance)
+	assert.True(t, bobCafe.Provenance.Is("alice", aliceCafe.ID))
+	trace, ok := mappings.Trace(reconciliation.EntityLocation, aliceCafe.ID)
+	require.True(t, ok)
+	assert.Equal(t, reconciliation.RuleNoCandidates, trace.Rule)
+	assert.Equal(t, reconciliation.OutcomeCreated, trace.Outcome)
+	assert.Equal(t, bobCafe.ID, trace.LocalID)
+	assert.Len(t, mappings.Traces, 5, "one per location, person and group")
+
+	bobJim, err := bob.people.GetPerson(ctx, mappings.PersonMappings[jim.ID])
+	require.NoError(t, err)
+	assert.True(t, bobJim.Provenance.Is("alice", jim.ID))
+
+	bobClimbers, err := bob.people.GetGroup(ctx, mappings.GroupMappings[climbers.ID])
+	require.NoError(t, err)
+	assert.Equal(t, "Climbers", bobClimbers.Name)
+	assert.Equal(t, []uuid.UUID{bobJim.ID}, bobClimbers.MemberIDs)
+	assert.Equal(t, []uuid.UUID{mappings.GroupMappings[belayers.ID]}, bobClimbers.SubgroupIDs)
+	bobBelayers, err := bob.people.GetGroup(ctx, mappings.GroupMappings[belayers.ID])
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{mappings.PersonMappings[sam.ID]}, bobBelayers.MemberIDs)
+
+	stored, err := bob.intentions.GetIntention(ctx, first.Intention.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.Targets, 2)
+	assert.Equal(t, intentions.LocationTarget{LocationID: bobCafe.ID}, stored.Targets[0])
+	proximity, ok := stored.Targets[1].(intentions.ProximityTarget)
+	require.True(t, ok)
+	assert.Equal(t, []uuid.UUID{bobClimbers.ID}, proximity.GroupIDs)
+
+	t.Run("Maps a second share onto the created records", func(t *testing.T) {
+		second := users.receive(t, envelope)
+
+		assert.Nil(t, second.Pending)
+		assert.Empty(t, second.Mappings.CreatedLocations)
+		assert.Empty(t, second.Mappings.CreatedPeople)
+		assert.Empty(t, second.Mappings.CreatedGroups)
+		assert.Equal(t, mappings.LocationMappings, second.Mappings.LocationMappings)
+		assert.Equal(t, mappings.PersonMappings, second.Mappings.PersonMappings)
+		assert.Equal(t, mappings.GroupMappings, second.Mappings.GroupMappings)
+	})
+}
+
+func TestApp_ReceiveEnvelope_TranslatesIntention(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: Alice shares a match at a cafe Bob knows, then plans to watch it with Jim, whom he does not
+	users := newTwoUsers(t)
+	alice, bob := users.alice, users.bob
+	aliceCafe, err := alice.locations.AddSharedLocation(ctx, "Corner Cafe", "Cafe")
+	require.NoError(t, err)
+	jim, err := alice.people.CreatePerson(ctx, "Jim")
+	require.NoError(t, err)
+	match, err := alice.intentions.AddIntention(ctx, "alice", "Match", []intentions.Target{
+		intentions.LocationTarget{LocationID: aliceCafe.ID},
+	}, time.Now(), time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	watch, err := alice.intentions.AddIntention(ctx, "alice", "Watch the match", []intentions.Target{
+		intentions.EventTarget{IntentionID: match.ID},
+		intentions.ProximityTarget{PersonIDs: []uuid.UUID{jim.ID}},
+	}, time.Now().Add(3*time.Hour), time.Now().Add(5*time.Hour))
+	require.NoError(t, err)
+	bobCafe, err := bob.locations.AddSharedLocation(ctx, "Corner Cafe", "Cafe")
+	require.NoError(t, err)
+
+	matchEnvelope := users.share(t, match.ID)
+	watchEnvelope := users.share(t, watch.ID)
+
+	// Act
+	matchResult := users.receive(t, matchEnvelope)
+	watchResult := users.receive(t, watchEnvelope)
+
+	// Assert: The copies are linked to Alice's originals and refer to Bob's records
+	bobMatch := matchResult.Intention
+	assert.Empty(t, matchResult.Unresolved)
+	assert.Equal(t, []intentions.Target{intentions.LocationTarget{LocationID: bobCafe.ID}}, bobMatch.Targets)
+	require.NotNil(t, bobMatch.Provenance)
+	assert.True(t, bobMatch.Provenance.Is("alice", match.ID))
+
+	found, err := bob.intentions.FindReceivedIntention(ctx, "alice", watch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, watchResult.Intention.ID, found.ID)
+	assert.Equal(t, []intentions.Target{
+		intentions.EventTarget{IntentionID: bobMatch.ID},
+		intentions.ProximityTarget{PersonIDs: []uuid.UUID{jim.ID}},
+	}, found.Targets)
+	assert.Equal(t, []reconciliation.UnresolvedReference{{Kind: reconciliation.EntityPerson, SenderID: jim.ID}}, watchResult.Unresolved)
+
+	t.Run("Updates the copy when shared again", func(t *testing.T) {
+		match.Action = "Cup final"
+		_, err := alice.intentions.UpdateIntention(ctx, match)
+		require.NoError(t, err)
+
+		result := users.receive(t, users.share(t, match.ID))
+
+		assert.Equal(t, bobMatch.ID, result.Intention.ID)
+		stored, err := bob.intentions.GetIntention(ctx, bobMatch.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Cup final", stored.Action)
+		assert.Equal(t, bobMatch.Version+1, stored.Version)
+	})
+}
+
+func TestApp_ReceiveEnvelope_RemembersMappings(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: Alice's cafe is only a possible match for Bob's
+	users := newTwoUsers(t)
+	alice, bob := users.alice, users.bob
+	var bobLog bytes.Buffer
+	bob.rebuild(bob.locations, zerolog.New(&bobLog).Level(zerolog.DebugLevel))
+	aliceCafe, err := alice.locations.AddSharedLocation(ctx, "Corner Cafe", "Cafe")
+	require.NoError(t, err)
+	coffee, err := alice.intentions.AddIntention(ctx, "alice", "Coffee", []intentions.Target{
+		intentions.LocationTarget{LocationID: aliceCafe.ID},
+	}, time.Now(), time.Now().Add(1*time.Hour))
+	require.NoError(t, err)
+	bobCafe, err := bob.locations.AddSharedLocation(ctx, "Corner Cafe", "Bar")
+	require.NoError(t, err)
+	bobOtherCafe, err := bob.locations.AddSharedLocation(ctx, "The Corner", "Cafe")
+	require.NoError(t, err)
+
+	receive := func(t *testing.T) *app.ReceiveResult {
+		t.Helper()
+		return users.receive(t, users.share(t, coffee.ID))
+	}
+
+	first := receive(t)
+	require.NotNil(t, first.Pending)
+	trace, ok := first.Mappings.Trace(reconciliation.EntityLocation, aliceCafe.ID)
+	require.True(t, ok)
+	assert.Equal(t, reconciliation.RuleBelowExact, trace.Rule)
+	assert.Equal(t, reconciliation.OutcomePossible, trace.Outcome)
+	assert.Equal(t, 2, trace.Considered)
+	assert.Equal(t, first.Mappings.LocationCandidates[aliceCafe.ID], trace.Candidates)
+	assert.Contains(t, trace.Summary(), `might be one of 1 of yours; best is "Corner Cafe"`)
+	assert.Contains(t, bobLog.String(), `"rule":"below_exact"`)
+	tasks, err := bob.app.ListReconciliationTasks(ctx, reconciliation.TaskPending)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	_, err = bob.app.ConfirmMatch(ctx, tasks[0].ID, bobCafe.ID)
+	require.NoError(t, err)
+
+	t.Run("Maps a confirmed match without asking again", func(t *testing.T) {
+		result := receive(t)
+
+		assert.Nil(t, result.Pending)
+		assert.Equal(t, bobCafe.ID, result.Mappings.LocationMappings[aliceCafe.ID])
+		candidates := result.Mappings.LocationCandidates[aliceCafe.ID]
+		require.Len(t, candidates, 1)
+		assert.Equal(t, "mapped before", candidates[0].Explanation)
+		trace, ok := result.Mappings.Trace(reconciliation.EntityLocation, aliceCafe.ID)
+		require.True(t, ok)
+		assert.Equal(t, reconciliation.RuleMappedBefore, trace.Rule)
+		assert.Equal(t, reconciliation.OutcomeMapped, trace.Outcome)
+		assert.Equal(t, bobCafe.ID, trace.LocalID)
+	})
+
+	t.Run("Uses a corrected mapping", func(t *testing.T) {
+		err := bob.app.CorrectMapping(ctx, "alice", reconciliation.EntityLocation, aliceCafe.ID, uuid.New())
+		assert.ErrorIs(t, err, apperr.ErrNotFound, "the local record must exist")
+		require.NoError(t, bob.app.CorrectMapping(ctx, "alice", reconciliation.EntityLocation, aliceCafe.ID, bobOtherCafe.ID))
+
+		result := receive(t)
+
+		assert.Nil(t, result.Pending)
+		assert.Equal(t, bobOtherCafe.ID, result.Mappings.LocationMappings[aliceCafe.ID])
+		assert.Equal(t, []intentions.Target{intentions.LocationTarget{LocationID: bobOtherCafe.ID}}, result.Intention.Targets)
+	})
+
+	t.Run("Matches again once the local record is deleted", func(t *testing.T) {
+		require.NoError(t, bob.locations.DeleteLocation(ctx, bobOtherCafe.ID))
+
+		result := receive(t)
+
+		require.NotNil(t, result.Pending)
+		assert.Equal(t, bobCafe.ID, result.Mappings.LocationCandidates[aliceCafe.ID][0].LocalID)
+	})
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/crypto"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/illmade-knight/action-intention/pkg/reconciliation"
+	"github.com/illmade-knight/action-intention/pkg/sharing"
+	"github.com/illmade-knight/go-secure-messaging/pkg/transport"
+)
+
+// ReceiveStage names the step of the receive pipeline that failed.
+type ReceiveStage string
+
+const (
+	StageSenderKey ReceiveStage = "sender_key" // Fetching the sender's public key.
+	StageVerify    ReceiveStage = "verify"     // Checking the envelope signature.
+	StageDecrypt   ReceiveStage = "decrypt"    // Decrypting the payload and checking the AAD.
+	StageDecode    ReceiveStage = "decode"     // Unmarshalling the payload.
+	StageReconcile ReceiveStage = "reconcile"  // Matching the payload with local data.
+	StageStore     ReceiveStage = "store"      // Saving the result locally.
+)
+
+// ErrUnsupportedPayload is returned when a decrypted payload has an unknown kind.
+var ErrUnsupportedPayload = apperr.New(apperr.ErrInvalid, "unsupported payload kind")
+
+// ReceiveError reports which stage of ReceiveEnvelope failed.
+type ReceiveError struct {
+	Stage ReceiveStage
+	Err   error
+}
+
+func (e *ReceiveError) Error() string {
+	return fmt.Sprintf("receive envelope failed at %s: %v", e.Stage, e.Err)
+}
+
+func (e *ReceiveError) Unwrap() error {
+	return e.Err
+}
+
+// ReceiveResult describes what ReceiveEnvelope did with an envelope.
+type ReceiveResult struct {
+	Kind     sharing.PayloadKind
+	SenderID string
+	// Payload is the decrypted payload for shares and invites.
+	Payload *sharing.SharedPayload
+	// Mappings holds the reconciliation of the payload with local data,
+	// including a trace of the decision made for each entity.
+	Mappings reconciliation.MappingResult
+	// Intention is the locally stored copy for shares and invites, or the
+	// owner's updated intention for an RSVP.
+	Intention intentions.Intention
+	// Unresolved lists the references of the stored copy that are still in the
+	// sender's IDs, because nothing local was mapped to them.
+	Unresolved []reconciliation.UnresolvedReference
+	// Pending is set instead of Intention when the payload had possible matches.
+	// The intention is stored once its reconciliation tasks are resolved.
+	Pending *reconciliation.PendingIntention
+}
+
+// ReceiveEnvelope is the counterpart of ShareIntention. It verifies the envelope
+// with the sender's public key, decrypts it, and then either reconciles a shared
+// intention and stores a local copy in local IDs, or records an RSVP reply.
+// Sharing the same intention again updates the local copy.
+// Errors are *ReceiveError values naming the stage that failed.
+func (a *App) ReceiveEnvelope(ctx context.Context, envelope *transport.SecureEnvelope, privateKeyPEM []byte) (*ReceiveResult, error) {
+	logger := a.Logger.With().
+		Str("sender_id", envelope.SenderID).
+		Str("recipient_id", envelope.RecipientID).
+		Logger()
+
+	plaintext, err := a.openEnvelope(ctx, envelope, privateKeyPEM)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Rejected incoming envelope")
+		return nil, err
+	}
+
+	kind, err := sharing.KindOf(plaintext)
+	if err != nil {
+		return nil, &ReceiveError{Stage: StageDecode, Err: apperr.Errorf(apperr.ErrInvalid, "malformed payload: %w", err)}
+	}
+
+	var result *ReceiveResult
+	switch kind {
+	case sharing.PayloadKindShare, sharing.PayloadKindInvite:
+		result, err = a.receiveSharedPayload(ctx, envelope, plaintext)
+	case sharing.PayloadKindRSVP:
+		result, err = a.receiveRSVP(ctx, envelope, plaintext)
+	default:
+		err = &ReceiveError{Stage: StageDecode, Err: fmt.Errorf("%w: %q", ErrUnsupportedPayload, kind)}
+	}
+	if err != nil {
+		logger.Warn().Err(err).Str("kind", string(kind)).Msg("Failed to process incoming envelope")
+		return nil, err
+	}
+
+	if result.Pending != nil {
+		logger.Info().Str("kind", string(kind)).Stringer("pending_id", result.Pending.ID).Int("tasks", len(result.Pending.TaskIDs)).Msg("Holding incoming intention until its matches are resolved")
+		return result, nil
+	}
+	logger.Info().Str("kind", string(kind)).Stringer("intention_id", result.Intention.ID).Int("unresolved", len(result.Unresolved)).Msg("Processed incoming envelope")
+	return result, nil
+}
+
+// openEnvelope verifies an envelope's signature with the sender's public key and
+// decrypts its payload, binding it to the sender and recipient through the AAD.
+func (a *App) openEnvelope(ctx context.Context, envelope *transport.SecureEnvelope, privateKeyPEM []byte) ([]byte, error) {
+	senderPubKey, err := a.KeyClient.GetKey(ctx, envelope.SenderID)
+	if err != nil {
+		return nil, &ReceiveError{Stage: StageSenderKey, Err: err}
+	}
+	if err := crypto.Verify(envelope.EncryptedData, envelope.Signature, senderPubKey); err != nil {
+		return nil, &ReceiveError{Stage: StageVerify, Err: apperr.Errorf(apperr.ErrUnauthorized, "signature verification failed: %w", err)}
+	}
+	// Rebuild the AAD from the routing header; decryption fails if it was altered.
+	aad := []byte(envelope.SenderID + ":" + envelope.RecipientID)
+	plaintext, err := crypto.Decrypt(envelope.EncryptedSymmetricKey, envelope.EncryptedData, aad, privateKeyPEM)
+	if err != nil {
+		return nil, &ReceiveError{Stage: StageDecrypt, Err: apperr.Errorf(apperr.ErrUnauthorized, "decryption failed: %w", err)}
+	}
+	return plaintext, nil
+}
+
+// receiveSharedPayload reconciles a shared intention and saves it in local IDs.
+func (a *App) receiveSharedPayload(ctx context.Context, envelope *transport.SecureEnvelope, plaintext []byte) (*ReceiveResult, error) {
+	var payload sharing.SharedPayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		return nil, &ReceiveError{Stage: StageDecode, Err: apperr.Errorf(apperr.ErrInvalid, "malformed payload: %w", err)}
+	}
+	if payload.Intention.User != envelope.SenderID {
+		return nil, &ReceiveError{Stage: StageDecode, Err: apperr.Errorf(apperr.ErrUnauthorized, "intention owned by %s was sent by %s", payload.Intention.User, envelope.SenderID)}
+	}
+
+	mappings, err := a.Reconciler.ProcessPayload(ctx, envelope.RecipientID, payload)
+	if err != nil {
+		return nil, &ReceiveError{Stage: StageReconcile, Err: err}
+	}
+
+	kind := payload.Kind
+	if kind == "" {
+		kind = sharing.PayloadKindShare
+	}
+	result := &ReceiveResult{
+		Kind:     kind,
+		SenderID: envelope.SenderID,
+		Payload:  &payload,
+		Mappings: mappings,
+	}
+
+	if len(mappings.PossibleMatches) > 0 {
+		pending, err := a.Tasks.OpenTasks(ctx, envelope.SenderID, envelope.RecipientID, payload, mappings)
+		if err != nil {
+			return nil, &ReceiveError{Stage: StageStore, Err: err}
+		}
+		result.Pending = &pending
+		return result, nil
+	}
+
+	translation, err := a.Translator.Save(ctx, payload.Intention, mappings)
+	if err != nil {
+		return nil, &ReceiveError{Stage: StageStore, Err: err}
+	}
+	result.Intention = translation.Intention
+	result.Unresolved = translation.Unresolved
+	return result, nil
+}
+
+// receiveRSVP records a participant's reply on the owner's intention.
+func (a *App) receiveRSVP(ctx context.Context, envelope *transport.SecureEnvelope, plaintext []byte) (*ReceiveResult, error) {
+	var response sharing.RSVPResponse
+	if err := json.Unmarshal(plaintext, &response); err != nil {
+		return nil, &ReceiveError{Stage: StageDecode, Err: apperr.Errorf(apperr.ErrInvalid, "malformed payload: %w", err)}
+	}
+
+	// The signature proves who sent the envelope, so the reply must be their own.
+	if response.Participant != envelope.SenderID {
+		return nil, &ReceiveError{Stage: StageDecode, Err: apperr.Errorf(apperr.ErrUnauthorized, "RSVP for %s was sent by %s", response.Participant, envelope.SenderID)}
+	}
+	intent, err := a.IntentionSvc.GetIntention(ctx, response.IntentionID)
+	if err != nil {
+		return nil, &ReceiveError{Stage: StageStore, Err: err}
+	}
+	if intent.User != envelope.RecipientID {
+		return nil, &ReceiveError{Stage: StageDecode, Err: apperr.Errorf(apperr.ErrUnauthorized, "intention %s is not owned by %s", response.IntentionID, envelope.RecipientID)}
+	}
+
+	updated, err := a.IntentionSvc.RecordRSVP(ctx, response.IntentionID, response.Participant, response.RSVP, response.RespondedAt)
+	if err != nil {
+		return nil, &ReceiveError{Stage: StageStore, Err: err}
+	}
+	return &ReceiveResult{Kind: sharing.PayloadKindRSVP, SenderID: envelope.SenderID, Intention: updated}, nil
+}
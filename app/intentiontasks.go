@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/illmade-knight/action-intention/pkg/reconciliation"
+)
+
+// UseTaskStore replaces the in-memory reconciliation task store, e.g. with a
+// Firestore-backed one so tasks survive restarts.
+func (a *App) UseTaskStore(store reconciliation.TaskStore) {
+	a.taskStore = store
+	a.buildReconciliation()
+}
+
+// UseMappingStore replaces the in-memory identity mapping store, e.g. with a
+// Firestore-backed one so senders' entities stay mapped across restarts.
+func (a *App) UseMappingStore(store reconciliation.MappingStore) {
+	a.mappingStore = store
+	a.buildReconciliation()
+}
+
+// UseReconcilerOptions rebuilds the reconciler with the given options, e.g.
+// reconciliation.WithCreateUnmatched to create local records for anything
+// received that matches nothing.
+func (a *App) UseReconcilerOptions(opts ...reconciliation.ReconcilerOption) {
+	a.reconcilerOpts = opts
+	a.buildReconciliation()
+}
+
+// buildReconciliation creates the reconciler and task service from the
+// current stores and options.
+func (a *App) buildReconciliation() {
+	opts := append([]reconciliation.ReconcilerOption{
+		reconciliation.WithMappingStore(a.mappingStore),
+		reconciliation.WithLogger(a.Logger.With().Str("component", "reconciler").Logger()),
+	}, a.reconcilerOpts...)
+	a.Reconciler = reconciliation.NewReconciler(a.LocationSvc.GetStore(), a.PersonSvc.GetStore(), opts...)
+	a.Tasks = reconciliation.NewTaskService(a.taskStore, a.mappingStore, a.LocationSvc.GetStore(), a.PersonSvc.GetStore(), a.Logger)
+}
+
+// CorrectMapping maps one of a sender's entities onto a different local
+// record, for when a share was matched to the wrong one. Later shares use the
+// new mapping; intentions already stored keep their references.
+func (a *App) CorrectMapping(ctx context.Context, senderID string, kind reconciliation.EntityKind, senderEntityID, localID uuid.UUID) error {
+	if err := a.Reconciler.CorrectMapping(ctx, senderID, kind, senderEntityID, localID); err != nil {
+		return fmt.Errorf("failed to correct mapping of %s %s: %w", kind, senderEntityID, err)
+	}
+	return nil
+}
+
+// ListReconciliationTasks returns the tasks with the given status, oldest
+// first. An empty status lists every task.
+func (a *App) ListReconciliationTasks(ctx context.Context, status reconciliation.TaskStatus) ([]reconciliation.Task, error) {
+	return a.Tasks.ListTasks(ctx, status)
+}
+
+// ConfirmMatch accepts one of a task's candidates as the local record for the
+// incoming entity. Once the last task of a received intention is resolved, the
+// intention is stored in local IDs and returned; until then it returns nil.
+func (a *App) ConfirmMatch(ctx context.Context, taskID, localID uuid.UUID) (*intentions.Intention, error) {
+	pending, complete, err := a.Tasks.ConfirmTask(ctx, taskID, localID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm task %s: %w", taskID, err)
+	}
+	return a.finalizeIfComplete(ctx, pending, complete)
+}
+
+// RejectMatch rejects every candidate of a task and creates a new local record
+// from the incoming entity. It finalizes the intention like ConfirmMatch.
+func (a *App) RejectMatch(ctx context.Context, taskID uuid.UUID) (*intentions.Intention, error) {
+	pending, complete, err := a.Tasks.RejectTask(ctx, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reject task %s: %w", taskID, err)
+	}
+	return a.finalizeIfComplete(ctx, pending, complete)
+}
+
+// MergeMatch accepts one of a task's candidates and copies the details it is
+// missing from the incoming entity. It finalizes the intention like ConfirmMatch.
+func (a *App) MergeMatch(ctx context.Context, taskID, localID uuid.UUID) (*intentions.Intention, error) {
+	pending, complete, err := a.Tasks.MergeTask(ctx, taskID, localID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge task %s: %w", taskID, err)
+	}
+	return a.finalizeIfComplete(ctx, pending, complete)
+}
+
+// finalizeIfComplete stores a pending intention once all its tasks are resolved.
+func (a *App) finalizeIfComplete(ctx context.Context, pending reconciliation.PendingIntention, complete bool) (*intentions.Intention, error) {
+	if !complete {
+		return nil, nil
+	}
+	translation, err := a.Translator.Save(ctx, pending.Intention, pending.Mappings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store reconciled intention: %w", err)
+	}
+	local := translation.Intention
+	if err := a.Tasks.ClosePending(ctx, pending.ID); err != nil {
+		return nil, fmt.Errorf("failed to close pending intention %s: %w", pending.ID, err)
+	}
+	a.Logger.Info().Stringer("pending_id", pending.ID).Stringer("intention_id", local.ID).Int("unresolved", len(translation.Unresolved)).Msg("Finalized reconciled intention")
+	return &local, nil
+}
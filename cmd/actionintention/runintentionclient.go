@@ -2,18 +2,23 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/illmade-knight/action-intention/app"
 	"github.com/illmade-knight/action-intention/internal/clients"
 	firestorestorage "github.com/illmade-knight/action-intention/internal/storage/firestore"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 	"github.com/illmade-knight/action-intention/pkg/intentions"
 	"github.com/illmade-knight/action-intention/pkg/locations"
 	"github.com/illmade-knight/action-intention/pkg/people"
+	"github.com/illmade-knight/action-intention/pkg/reconciliation"
+	"github.com/illmade-knight/go-secure-messaging/pkg/transport"
 	"github.com/rs/zerolog"
 )
 
@@ -23,6 +28,18 @@ type Config struct {
 	GCPProjectID      string
 	KeyServiceURL     string
 	RoutingServiceURL string
+	// UserID and PrivateKeyPath identify the local user whose inbox is polled.
+	// The inbox poller only runs when both are set.
+	UserID            string
+	PrivateKeyPath    string
+	InboxPollInterval time.Duration
+	// RoutingWebSocketURL enables real-time delivery alongside the poller;
+	// RoutingToken is presented when the connection authenticates.
+	RoutingWebSocketURL string
+	RoutingToken        string
+	// CreateUnmatched creates local locations, people and groups for received
+	// ones that match nothing, instead of leaving them in the sender's IDs.
+	CreateUnmatched bool
 }
 
 func main() {
@@ -35,10 +52,24 @@ func main() {
 		GCPProjectID:      os.Getenv("GCP_PROJECT_ID"),
 		KeyServiceURL:     "http://localhost:8081", // Example URL
 		RoutingServiceURL: "http://localhost:8080", // Example URL
+		UserID:            os.Getenv("USER_ID"),
+		PrivateKeyPath:    os.Getenv("PRIVATE_KEY_PATH"),
+		InboxPollInterval: 10 * time.Second,
+
+		RoutingWebSocketURL: os.Getenv("ROUTING_WEBSOCKET_URL"),
+		RoutingToken:        os.Getenv("ROUTING_TOKEN"),
+		CreateUnmatched:     os.Getenv("CREATE_UNMATCHED") == "true",
 	}
 	if cfg.GCPProjectID == "" {
 		logger.Fatal().Msg("GCP_PROJECT_ID environment variable must be set.")
 	}
+	if v := os.Getenv("INBOX_POLL_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("INBOX_POLL_INTERVAL must be a duration such as 30s.")
+		}
+		cfg.InboxPollInterval = interval
+	}
 
 	// 2. Initialize External Clients (e.g., Firestore)
 	fsClient, err := firestore.NewClient(ctx, cfg.GCPProjectID)
@@ -66,8 +97,39 @@ func main() {
 
 	// 6. Instantiate the Main Application Orchestrator
 	application := app.New(intentionSvc, locationSvc, personSvc, keyClient, routeClient, logger)
+	application.UseTaskStore(firestorestorage.NewTasksStore(fsClient))
+	application.UseMappingStore(firestorestorage.NewMappingsStore(fsClient))
+	if cfg.CreateUnmatched {
+		application.UseReconcilerOptions(reconciliation.WithCreateUnmatched())
+	}
 	logger.Info().Str("app_address", fmt.Sprintf("%p", application)).Msg("Application orchestrator created")
 
+	// 7. Start pulling incoming envelopes from the routing service
+	if cfg.UserID != "" && cfg.PrivateKeyPath != "" {
+		privateKeyPEM, err := os.ReadFile(cfg.PrivateKeyPath)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to read private key")
+		}
+		handler := func(ctx context.Context, envelope *transport.SecureEnvelope) error {
+			return receiveEnvelope(ctx, application, envelope, privateKeyPEM, logger)
+		}
+		pollerCfg := clients.DefaultInboxPollerConfig()
+		pollerCfg.Interval = cfg.InboxPollInterval
+		poller := clients.NewInboxPoller(routeClient, cfg.UserID, handler, pollerCfg, logger)
+		go poller.Run(ctx)
+
+		if cfg.RoutingWebSocketURL != "" {
+			wsCfg := clients.WebSocketReceiverConfig{
+				URL:    cfg.RoutingWebSocketURL,
+				UserID: cfg.UserID,
+				Token:  func(context.Context) (string, error) { return cfg.RoutingToken, nil },
+			}
+			go clients.NewWebSocketReceiver(wsCfg, handler, logger).Run(ctx)
+		}
+	} else {
+		logger.Warn().Msg("USER_ID or PRIVATE_KEY_PATH not set; incoming messages will not be pulled.")
+	}
+
 	// --- Application is now fully assembled and ready ---
 	logger.Info().Msg("Action-Intention service initialized. Waiting for shutdown signal...")
 	// In Phase 2, this is where we would start the HTTP API server.
@@ -76,3 +138,28 @@ func main() {
 	<-ctx.Done()
 	logger.Info().Msg("Shutdown signal received. Exiting.")
 }
+
+// receiveEnvelope passes an envelope to the application. Envelopes that can
+// never be processed (bad signature, wrong key, malformed payload) are logged
+// and dropped so they are acknowledged; failures that may clear up on a retry
+// are returned so the envelope stays in the inbox. A failure in a retryable
+// stage is still dropped if it is apperr.ErrInvalid or apperr.ErrUnauthorized,
+// since a retry would be rejected the same way.
+func receiveEnvelope(ctx context.Context, application *app.App, envelope *transport.SecureEnvelope, privateKeyPEM []byte, logger zerolog.Logger) error {
+	result, err := application.ReceiveEnvelope(ctx, envelope, privateKeyPEM)
+	if err == nil {
+		logger.Info().Str("sender_id", result.SenderID).Str("kind", string(result.Kind)).Msg("Received envelope")
+		return nil
+	}
+
+	var receiveErr *app.ReceiveError
+	permanent := errors.Is(err, apperr.ErrInvalid) || errors.Is(err, apperr.ErrUnauthorized)
+	if errors.As(err, &receiveErr) && !permanent {
+		switch receiveErr.Stage {
+		case app.StageSenderKey, app.StageReconcile, app.StageStore:
+			return err
+		}
+	}
+	logger.Error().Err(err).Str("sender_id", envelope.SenderID).Msg("Dropping envelope that cannot be processed")
+	return nil
+}
@@ -77,7 +77,7 @@ func main() {
 
 	// The Reconciler is initialized with LUCAS'S local data stores.
 	reconciler := reconciliation.NewReconciler(lucasLocationStore, lucasPeopleStore)
-	mappingResult, err := reconciler.ProcessPayload(ctx, payload)
+	mappingResult, err := reconciler.ProcessPayload(ctx, "Lucas", payload)
 	if err != nil {
 		log.Fatalf("Reconciliation failed: %v", err)
 	}
@@ -184,7 +184,7 @@ func main() {
 	json.Unmarshal(decryptedPayloadBytes, &receivedPayload)
 
 	reconciler := reconciliation.NewReconciler(bobLocationStore, bobPeopleStore)
-	mappingResult, _ := reconciler.ProcessPayload(ctx, receivedPayload)
+	mappingResult, _ := reconciler.ProcessPayload(ctx, receivedEnvelope.RecipientID, receivedPayload)
 	log.Println("5. Payload reconciled with Bob's local data.")
 
 	// --- Phase 6: Final Result Verification ---
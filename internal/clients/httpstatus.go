@@ -0,0 +1,28 @@
+package clients
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+)
+
+// statusError reports an unexpected HTTP status from a service. Statuses that
+// say something about the request rather than the service are categorized so
+// callers can tell them from outages with errors.Is.
+func statusError(service string, code int) error {
+	var kind error
+	switch code {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		kind = apperr.ErrInvalid
+	case http.StatusUnauthorized, http.StatusForbidden:
+		kind = apperr.ErrUnauthorized
+	case http.StatusNotFound:
+		kind = apperr.ErrNotFound
+	case http.StatusConflict:
+		kind = apperr.ErrConflict
+	default:
+		return fmt.Errorf("%s returned unexpected status code: %d", service, code)
+	}
+	return apperr.Errorf(kind, "%s returned unexpected status code: %d", service, code)
+}
@@ -0,0 +1,180 @@
+package clients
+
+import (
+	"context"
+	"time"
+
+	"github.com/illmade-knight/go-secure-messaging/pkg/transport"
+	"github.com/rs/zerolog"
+)
+
+// EnvelopeHandler processes a single incoming envelope, typically by passing it
+// to App.ReceiveEnvelope. Returning nil means the envelope has been persisted
+// locally and may be acknowledged.
+type EnvelopeHandler func(ctx context.Context, envelope *transport.SecureEnvelope) error
+
+// Inbox is the part of the routing service client used by the InboxPoller.
+type Inbox interface {
+	Receive(ctx context.Context, userID string, limit int) ([]InboxMessage, error)
+	Ack(ctx context.Context, userID string, messageIDs []string) error
+}
+
+// DeadLetterHandler receives an envelope the poller has given up on, together
+// with the last error its handler returned. The envelope is acknowledged
+// afterwards, so this is the last chance to keep a copy of it.
+type DeadLetterHandler func(ctx context.Context, msg InboxMessage, err error)
+
+// InboxPollerConfig controls how often the inbox is polled.
+type InboxPollerConfig struct {
+	// Interval is the wait between polls when the routing service is healthy.
+	Interval time.Duration
+	// MaxBackoff caps the wait after repeated failures to reach the routing service.
+	MaxBackoff time.Duration
+	// BatchSize is the maximum number of messages fetched per poll.
+	BatchSize int
+	// MaxAttempts is the number of times an envelope's handler may fail before
+	// the envelope is dead-lettered and acknowledged.
+	MaxAttempts int
+	// DeadLetter, if set, receives envelopes that reached MaxAttempts. When nil
+	// they are only logged.
+	DeadLetter DeadLetterHandler
+}
+
+// DefaultInboxPollerConfig returns the settings used when none are given.
+func DefaultInboxPollerConfig() InboxPollerConfig {
+	return InboxPollerConfig{
+		Interval:    10 * time.Second,
+		MaxBackoff:  5 * time.Minute,
+		BatchSize:   25,
+		MaxAttempts: 5,
+	}
+}
+
+// InboxPoller periodically pulls a user's messages from the routing service,
+// hands each envelope to a handler and acknowledges the ones that succeed.
+// Envelopes whose handler fails are left in the inbox and retried on a later
+// poll, until they have failed MaxAttempts times; they are then dead-lettered
+// and acknowledged so they cannot fill every batch and block newer messages.
+//
+// Attempts are counted in memory, so a restart gives every envelope a fresh
+// set of retries. An InboxPoller must not be polled concurrently.
+type InboxPoller struct {
+	inbox    Inbox
+	userID   string
+	handler  EnvelopeHandler
+	cfg      InboxPollerConfig
+	logger   zerolog.Logger
+	attempts map[string]int // Failed handler calls by message ID.
+}
+
+// NewInboxPoller creates a poller for the given user's inbox. Zero values in
+// cfg fall back to DefaultInboxPollerConfig.
+func NewInboxPoller(inbox Inbox, userID string, handler EnvelopeHandler, cfg InboxPollerConfig, logger zerolog.Logger) *InboxPoller {
+	defaults := DefaultInboxPollerConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaults.Interval
+	}
+	if cfg.MaxBackoff < cfg.Interval {
+		cfg.MaxBackoff = max(defaults.MaxBackoff, cfg.Interval)
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaults.BatchSize
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaults.MaxAttempts
+	}
+	return &InboxPoller{
+		inbox:    inbox,
+		userID:   userID,
+		handler:  handler,
+		cfg:      cfg,
+		logger:   logger.With().Str("component", "inbox-poller").Str("user_id", userID).Logger(),
+		attempts: make(map[string]int),
+	}
+}
+
+// Run polls until the context is cancelled. Failures to reach the routing
+// service double the wait between polls, up to MaxBackoff; a successful poll
+// resets it. A fully processed batch is followed immediately by another poll.
+func (p *InboxPoller) Run(ctx context.Context) {
+	p.logger.Info().Dur("interval", p.cfg.Interval).Msg("Inbox poller started")
+	wait := time.Duration(0)
+	for {
+		select {
+		case <-ctx.Done():
+			p.logger.Info().Msg("Inbox poller stopped")
+			return
+		case <-time.After(wait):
+		}
+
+		processed, err := p.Poll(ctx)
+		switch {
+		case err != nil:
+			wait = min(max(wait*2, p.cfg.Interval), p.cfg.MaxBackoff)
+			p.logger.Warn().Err(err).Dur("retry_in", wait).Msg("Failed to poll inbox")
+		case processed == p.cfg.BatchSize:
+			wait = 0
+		default:
+			wait = p.cfg.Interval
+		}
+	}
+}
+
+// Poll fetches one batch of messages, handles them and acknowledges those that
+// were processed or dead-lettered. It returns the number of messages acknowledged.
+func (p *InboxPoller) Poll(ctx context.Context) (int, error) {
+	messages, err := p.inbox.Receive(ctx, p.userID, p.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var acked []string
+	for _, msg := range messages {
+		envelope := msg.Envelope
+		err := p.handler(ctx, &envelope)
+		if err == nil {
+			acked = append(acked, msg.ID)
+			continue
+		}
+
+		p.attempts[msg.ID]++
+		if p.attempts[msg.ID] < p.cfg.MaxAttempts {
+			p.logger.Warn().Err(err).Str("message_id", msg.ID).Str("sender_id", envelope.SenderID).Int("attempt", p.attempts[msg.ID]).Msg("Failed to process envelope, leaving it in the inbox")
+			continue
+		}
+		p.logger.Error().Err(err).Str("message_id", msg.ID).Str("sender_id", envelope.SenderID).Int("attempt", p.attempts[msg.ID]).Msg("Giving up on envelope, dead-lettering it")
+		if p.cfg.DeadLetter != nil {
+			p.cfg.DeadLetter(ctx, msg, err)
+		}
+		acked = append(acked, msg.ID)
+	}
+
+	// A short batch means the whole inbox was seen, so any other counts belong
+	// to messages that have since been removed.
+	if len(messages) < p.cfg.BatchSize {
+		p.pruneAttempts(messages)
+	}
+
+	if err := p.inbox.Ack(ctx, p.userID, acked); err != nil {
+		return 0, err
+	}
+	// Counts are only dropped once the ack lands, so an envelope whose ack
+	// failed is dead-lettered again on the next poll rather than retried afresh.
+	for _, id := range acked {
+		delete(p.attempts, id)
+	}
+	return len(acked), nil
+}
+
+// pruneAttempts forgets the attempt counts of messages no longer in the inbox.
+func (p *InboxPoller) pruneAttempts(inbox []InboxMessage) {
+	present := make(map[string]bool, len(inbox))
+	for _, msg := range inbox {
+		present[msg.ID] = true
+	}
+	for id := range p.attempts {
+		if !present[id] {
+			delete(p.attempts, id)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package clients_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/action-intention/internal/clients"
+	"github.com/illmade-knight/go-secure-messaging/pkg/transport"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRoutingInbox is an httptest stand-in for the routing service inbox API.
+type fakeRoutingInbox struct {
+	mu       sync.Mutex
+	messages []clients.InboxMessage
+	acked    []string
+	failures int // Number of receive requests to fail before succeeding.
+	receives int
+}
+
+func (f *fakeRoutingInbox) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/messages/user-bob":
+		f.receives++
+		if f.failures > 0 {
+			f.failures--
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if len(f.messages) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		batch := f.messages
+		if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit < len(batch) {
+			batch = batch[:limit]
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"messages": batch})
+	case r.Method == http.MethodPost && r.URL.Path == "/messages/user-bob/ack":
+		var body struct {
+			MessageIDs []string `json:"message_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		f.acked = append(f.acked, body.MessageIDs...)
+		remaining := f.messages[:0]
+		for _, msg := range f.messages {
+			if !contains(body.MessageIDs, msg.ID) {
+				remaining = append(remaining, msg)
+			}
+		}
+		f.messages = remaining
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeRoutingInbox) snapshot() (acked []string, pending, receives int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.acked...), len(f.messages), f.receives
+}
+
+func contains(ids []string, id string) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRoutingServiceClient_ReceiveAndAck(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeRoutingInbox{messages: []clients.InboxMessage{
+		{ID: "m1", Envelope: transport.SecureEnvelope{SenderID: "user-alice", RecipientID: "user-bob"}},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	client := clients.NewRoutingServiceClient(server.URL, zerolog.Nop())
+
+	// Act
+	messages, err := client.Receive(ctx, "user-bob", 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "user-alice", messages[0].Envelope.SenderID)
+	require.NoError(t, client.Ack(ctx, "user-bob", []string{"m1"}))
+
+	// Assert: The inbox is now empty
+	messages, err = client.Receive(ctx, "user-bob", 10)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+}
+
+func TestInboxPoller_AcksOnlyProcessedEnvelopes(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: Two messages, one of which the handler cannot persist
+	fake := &fakeRoutingInbox{messages: []clients.InboxMessage{
+		{ID: "good", Envelope: transport.SecureEnvelope{SenderID: "user-alice", RecipientID: "user-bob"}},
+		{ID: "bad", Envelope: transport.SecureEnvelope{SenderID: "user-mallory", RecipientID: "user-bob"}},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	handler := func(ctx context.Context, envelope *transport.SecureEnvelope) error {
+		if envelope.SenderID == "user-mallory" {
+			return errors.New("storage unavailable")
+		}
+		return nil
+	}
+	client := clients.NewRoutingServiceClient(server.URL, zerolog.Nop())
+	poller := clients.NewInboxPoller(client, "user-bob", handler, clients.InboxPollerConfig{}, zerolog.Nop())
+
+	// Act
+	processed, err := poller.Poll(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	acked, pending, _ := fake.snapshot()
+	assert.Equal(t, []string{"good"}, acked)
+	assert.Equal(t, 1, pending)
+}
+
+func TestInboxPoller_DeadLettersEnvelopesThatKeepFailing(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: A full batch of envelopes that always fail, ahead of a newer good one
+	fake := &fakeRoutingInbox{messages: []clients.InboxMessage{
+		{ID: "bad-1", Envelope: transport.SecureEnvelope{SenderID: "user-mallory", RecipientID: "user-bob"}},
+		{ID: "bad-2", Envelope: transport.SecureEnvelope{SenderID: "user-mallory", RecipientID: "user-bob"}},
+		{ID: "good", Envelope: transport.SecureEnvelope{SenderID: "user-alice", RecipientID: "user-bob"}},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	failures := make(map[string]int)
+	var handled []string
+	handler := func(ctx context.Context, envelope *transport.SecureEnvelope) error {
+		if envelope.SenderID == "user-mallory" {
+			failures[envelope.SenderID]++
+			return errors.New("cannot decrypt envelope")
+		}
+		handled = append(handled, envelope.SenderID)
+		return nil
+	}
+	var deadLettered []string
+	const maxAttempts = 3
+	cfg := clients.InboxPollerConfig{
+		BatchSize:   2,
+		MaxAttempts: maxAttempts,
+		DeadLetter: func(ctx context.Context, msg clients.InboxMessage, err error) {
+			deadLettered = append(deadLettered, msg.ID)
+		},
+	}
+	client := clients.NewRoutingServiceClient(server.URL, zerolog.Nop())
+	poller := clients.NewInboxPoller(client, "user-bob", handler, cfg, zerolog.Nop())
+
+	// Act: Poll once more than the envelopes are allowed to fail
+	for range maxAttempts + 1 {
+		_, err := poller.Poll(ctx)
+		require.NoError(t, err)
+	}
+
+	// Assert: The failing envelopes were given up on and the newer one got through
+	assert.Equal(t, 2*maxAttempts, failures["user-mallory"], "each failing envelope is tried MaxAttempts times")
+	assert.Equal(t, []string{"bad-1", "bad-2"}, deadLettered)
+	assert.Equal(t, []string{"user-alice"}, handled)
+	acked, pending, _ := fake.snapshot()
+	assert.Equal(t, []string{"bad-1", "bad-2", "good"}, acked)
+	assert.Zero(t, pending)
+}
+
+func TestInboxPoller_RunBacksOffAndRecovers(t *testing.T) {
+	// Arrange: The routing service fails twice before delivering a message
+	fake := &fakeRoutingInbox{
+		failures: 2,
+		messages: []clients.InboxMessage{
+			{ID: "m1", Envelope: transport.SecureEnvelope{SenderID: "user-alice", RecipientID: "user-bob"}},
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	handled := make(chan string, 1)
+	handler := func(ctx context.Context, envelope *transport.SecureEnvelope) error {
+		handled <- envelope.SenderID
+		return nil
+	}
+	client := clients.NewRoutingServiceClient(server.URL, zerolog.Nop())
+	cfg := clients.InboxPollerConfig{Interval: 10 * time.Millisecond, MaxBackoff: 40 * time.Millisecond, BatchSize: 5}
+	poller := clients.NewInboxPoller(client, "user-bob", handler, cfg, zerolog.Nop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		poller.Run(ctx)
+		close(done)
+	}()
+
+	// Act & Assert: The envelope is eventually handled and acknowledged
+	select {
+	case sender := <-handled:
+		assert.Equal(t, "user-alice", sender)
+	case <-time.After(2 * time.Second):
+		t.Fatal("envelope was not handled")
+	}
+	require.Eventually(t, func() bool {
+		acked, _, _ := fake.snapshot()
+		return len(acked) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+	_, _, receives := fake.snapshot()
+	assert.GreaterOrEqual(t, receives, 3)
+}
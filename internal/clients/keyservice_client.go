@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 	"github.com/rs/zerolog"
 )
 
@@ -30,7 +31,9 @@ func NewKeyServiceClient(baseURL string, logger zerolog.Logger) *KeyServiceClien
 	}
 }
 
-// GetKey fetches a user's public key.
+// GetKey fetches a user's public key. A user without a key is reported as
+// apperr.ErrNotFound and a rejected request as apperr.ErrUnauthorized, so
+// callers can tell both from the key service being unavailable.
 func (c *KeyServiceClient) GetKey(ctx context.Context, userID string) ([]byte, error) {
 	url := fmt.Sprintf("%s/keys/%s", c.baseURL, userID)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
@@ -45,10 +48,10 @@ func (c *KeyServiceClient) GetKey(ctx context.Context, userID string) ([]byte, e
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("key for user %s not found", userID)
+		return nil, apperr.Errorf(apperr.ErrNotFound, "key for user %s not found", userID)
 	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("key service returned unexpected status code: %d", resp.StatusCode)
+		return nil, statusError("key service", resp.StatusCode)
 	}
 
 	key, err := io.ReadAll(resp.Body)
@@ -76,7 +79,7 @@ func (c *KeyServiceClient) StoreKey(ctx context.Context, userID string, key []by
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("key service returned unexpected status code for store: %d", resp.StatusCode)
+		return statusError("key service", resp.StatusCode)
 	}
 
 	c.logger.Info().Str("user_id", userID).Msg("Successfully stored public key")
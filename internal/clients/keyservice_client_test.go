@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/illmade-knight/action-intention/internal/clients"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -15,10 +16,15 @@ import (
 func TestKeyServiceClient(t *testing.T) {
 	const testUserID = "user-123"
 	const testKey = "my-public-key"
+	const forbiddenUserID = "user-forbidden"
 	ctx := context.Background()
 
 	// Arrange: Create a mock HTTP server to act as the key service
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/keys/"+forbiddenUserID {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
 		expectedPath := "/keys/" + testUserID
 		if r.URL.Path != expectedPath {
 			http.NotFound(w, r)
@@ -57,6 +63,16 @@ func TestKeyServiceClient(t *testing.T) {
 		// Assert
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "not found")
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+	})
+
+	t.Run("Errors are categorized by status", func(t *testing.T) {
+		_, err := client.GetKey(ctx, forbiddenUserID)
+		assert.ErrorIs(t, err, apperr.ErrUnauthorized)
+
+		err = client.StoreKey(ctx, forbiddenUserID, []byte(testKey))
+		assert.ErrorIs(t, err, apperr.ErrUnauthorized)
+		assert.NotErrorIs(t, err, apperr.ErrNotFound)
 	})
 
 	t.Run("StoreKey - Success", func(t *testing.T) {
@@ -7,13 +7,28 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/illmade-knight/go-secure-messaging/pkg/transport"
 	"github.com/rs/zerolog"
 )
 
+// InboxMessage is an envelope waiting in a user's inbox on the routing service.
+// The ID is used to acknowledge the message once it has been processed.
+type InboxMessage struct {
+	ID       string                   `json:"id"`
+	Envelope transport.SecureEnvelope `json:"envelope"`
+}
+
 // RoutingServiceClient is responsible for all communication with the go-routing-service.
+//
+// Only Send is backed by the routing service pinned in go.mod: v0.0.2-beta
+// registers a single route, POST /send, in routingservice/routingservice.go.
+// Receive and Ack target an inbox API that release does not serve yet
+// (GET /messages/{user} and POST /messages/{user}/ack); until it does, they
+// fail with the routing service's 404 and the InboxPoller backs off.
 type RoutingServiceClient struct {
 	baseURL    string
 	httpClient *http.Client
@@ -53,9 +68,83 @@ func (c *RoutingServiceClient) Send(ctx context.Context, envelope *transport.Sec
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("routing service returned unexpected status code: %d", resp.StatusCode)
+		return statusError("routing service", resp.StatusCode)
 	}
 
 	c.logger.Info().Str("sender_id", envelope.SenderID).Str("recipient_id", envelope.RecipientID).Msg("Successfully sent envelope to routing service")
 	return nil
 }
+
+// Receive fetches up to limit pending messages from the user's inbox with
+// GET /messages/{user}?limit=N. The response is 200 with {"messages": [...]},
+// or 204 when the inbox is empty. Messages stay in the inbox until they are
+// acknowledged with Ack.
+func (c *RoutingServiceClient) Receive(ctx context.Context, userID string, limit int) ([]InboxMessage, error) {
+	endpoint := fmt.Sprintf("%s/messages/%s", c.baseURL, url.PathEscape(userID))
+	if limit > 0 {
+		endpoint += "?limit=" + strconv.Itoa(limit)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create receive request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute receive request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("routing service", resp.StatusCode)
+	}
+
+	var body struct {
+		Messages []InboxMessage `json:"messages"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode inbox messages: %w", err)
+	}
+
+	c.logger.Debug().Str("user_id", userID).Int("count", len(body.Messages)).Msg("Fetched inbox messages")
+	return body.Messages, nil
+}
+
+// Ack removes processed messages from the user's inbox with
+// POST /messages/{user}/ack and a {"message_ids": [...]} body.
+func (c *RoutingServiceClient) Ack(ctx context.Context, userID string, messageIDs []string) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+	endpoint := fmt.Sprintf("%s/messages/%s/ack", c.baseURL, url.PathEscape(userID))
+
+	payload, err := json.Marshal(struct {
+		MessageIDs []string `json:"message_ids"`
+	}{MessageIDs: messageIDs})
+	if err != nil {
+		return fmt.Errorf("failed to marshal ack request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create ack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute ack request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return statusError("routing service", resp.StatusCode)
+	}
+
+	c.logger.Debug().Str("user_id", userID).Int("count", len(messageIDs)).Msg("Acknowledged inbox messages")
+	return nil
+}
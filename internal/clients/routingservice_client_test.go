@@ -3,11 +3,13 @@ package clients_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/illmade-knight/action-intention/internal/clients"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 	"github.com/illmade-knight/go-secure-messaging/pkg/transport"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
@@ -45,3 +47,23 @@ func TestRoutingServiceClient_Send(t *testing.T) {
 	// Assert
 	require.NoError(t, err)
 }
+
+func TestRoutingServiceClient_ReceiveWithoutInboxAPI(t *testing.T) {
+	ctx := context.Background()
+
+	// Arrange: Mirror the routes of routing-service v0.0.2-beta, which only serves /send
+	mux := http.NewServeMux()
+	mux.HandleFunc("/send", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mockServer := httptest.NewServer(mux)
+	defer mockServer.Close()
+	client := clients.NewRoutingServiceClient(mockServer.URL, zerolog.Nop())
+
+	// Act & Assert: Sending works, but the inbox is reported as not found
+	require.NoError(t, client.Send(ctx, &transport.SecureEnvelope{SenderID: "user-alice", RecipientID: "user-bob"}))
+	_, err := client.Receive(ctx, "user-bob", 10)
+	assert.True(t, errors.Is(err, apperr.ErrNotFound))
+	err = client.Ack(ctx, "user-bob", []string{"m1"})
+	assert.True(t, errors.Is(err, apperr.ErrNotFound))
+}
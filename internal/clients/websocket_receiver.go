@@ -0,0 +1,216 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/go-secure-messaging/pkg/transport"
+	"github.com/rs/zerolog"
+)
+
+// WebSocket frame types exchanged with the routing service's online delivery path.
+// The client authenticates, subscribes for its user, then receives envelope
+// frames and acknowledges each one it has processed.
+const (
+	FrameAuth       = "auth"       // Client -> server: user ID and token.
+	FrameAuthOK     = "auth_ok"    // Server -> client: token accepted.
+	FrameSubscribe  = "subscribe"  // Client -> server: start delivering for the user.
+	FrameSubscribed = "subscribed" // Server -> client: subscription active.
+	FrameEnvelope   = "envelope"   // Server -> client: one envelope to process.
+	FrameAck        = "ack"        // Client -> server: envelope processed.
+	FrameError      = "error"      // Server -> client: request rejected.
+)
+
+// WebSocketFrame is the JSON message sent in both directions over the connection.
+type WebSocketFrame struct {
+	Type     string                    `json:"type"`
+	UserID   string                    `json:"user_id,omitempty"`
+	Token    string                    `json:"token,omitempty"`
+	ID       string                    `json:"id,omitempty"`
+	Envelope *transport.SecureEnvelope `json:"envelope,omitempty"`
+	Error    string                    `json:"error,omitempty"`
+}
+
+// TokenSource returns the credential presented when authenticating. It is
+// called on every connection attempt so short-lived tokens can be refreshed.
+type TokenSource func(ctx context.Context) (string, error)
+
+// WebSocketReceiverConfig configures a WebSocketReceiver.
+type WebSocketReceiverConfig struct {
+	// URL is the routing service's WebSocket endpoint, e.g. ws://localhost:8080/connect.
+	URL    string
+	UserID string
+	Token  TokenSource
+	// MinBackoff and MaxBackoff bound the jittered wait between reconnects;
+	// zero means 1 second and 1 minute.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// HandshakeTimeout bounds dialling, authenticating and subscribing; zero means 10 seconds.
+	HandshakeTimeout time.Duration
+}
+
+// WebSocketReceiver holds a live connection to the routing service and hands
+// each delivered envelope to a handler. Envelopes whose handler fails are not
+// acknowledged, so they remain in the inbox for the InboxPoller or a later
+// delivery.
+type WebSocketReceiver struct {
+	cfg     WebSocketReceiverConfig
+	handler EnvelopeHandler
+	dialer  *websocket.Dialer
+	logger  zerolog.Logger
+}
+
+// NewWebSocketReceiver creates a receiver for cfg.UserID. Call Run to connect.
+func NewWebSocketReceiver(cfg WebSocketReceiverConfig, handler EnvelopeHandler, logger zerolog.Logger) *WebSocketReceiver {
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = time.Second
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = max(time.Minute, cfg.MinBackoff)
+	}
+	if cfg.HandshakeTimeout <= 0 {
+		cfg.HandshakeTimeout = 10 * time.Second
+	}
+	if cfg.Token == nil {
+		cfg.Token = func(context.Context) (string, error) { return "", nil }
+	}
+	return &WebSocketReceiver{
+		cfg:     cfg,
+		handler: handler,
+		dialer:  &websocket.Dialer{HandshakeTimeout: cfg.HandshakeTimeout},
+		logger:  logger.With().Str("component", "websocket-receiver").Str("user_id", cfg.UserID).Logger(),
+	}
+}
+
+// Run connects and receives envelopes until the context is cancelled. Whenever
+// the connection fails or drops it reconnects after a jittered, exponentially
+// growing wait; the wait resets once a subscription succeeds.
+func (r *WebSocketReceiver) Run(ctx context.Context) {
+	backoff := r.cfg.MinBackoff
+	for {
+		subscribed, err := r.session(ctx)
+		if ctx.Err() != nil {
+			r.logger.Info().Msg("WebSocket receiver stopped")
+			return
+		}
+		if subscribed {
+			backoff = r.cfg.MinBackoff
+		}
+
+		wait := jitter(backoff)
+		r.logger.Warn().Err(err).Dur("retry_in", wait).Msg("WebSocket connection lost, reconnecting")
+		select {
+		case <-ctx.Done():
+			r.logger.Info().Msg("WebSocket receiver stopped")
+			return
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, r.cfg.MaxBackoff)
+	}
+}
+
+// jitter returns a wait between half and all of d, so that many clients
+// reconnecting after an outage do not all retry at once.
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// session runs a single connection from dial to disconnect. It reports whether
+// the subscription was established before the connection ended.
+func (r *WebSocketReceiver) session(ctx context.Context) (bool, error) {
+	handshakeCtx, cancel := context.WithTimeout(ctx, r.cfg.HandshakeTimeout)
+	defer cancel()
+
+	token, err := r.cfg.Token(handshakeCtx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get auth token: %w", err)
+	}
+	conn, resp, err := r.dialer.DialContext(handshakeCtx, r.cfg.URL, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to dial routing service websocket: %w", err)
+	}
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	defer conn.Close()
+
+	// Closing the connection is the only way to interrupt a blocked read.
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		_ = conn.Close()
+	})
+	defer stop()
+
+	_ = conn.SetReadDeadline(time.Now().Add(r.cfg.HandshakeTimeout))
+	if err := r.request(conn, WebSocketFrame{Type: FrameAuth, UserID: r.cfg.UserID, Token: token}, FrameAuthOK); err != nil {
+		var rejected *frameError
+		if errors.As(err, &rejected) {
+			return false, apperr.Errorf(apperr.ErrUnauthorized, "failed to authenticate: %w", err)
+		}
+		return false, fmt.Errorf("failed to authenticate: %w", err)
+	}
+	if err := r.request(conn, WebSocketFrame{Type: FrameSubscribe, UserID: r.cfg.UserID}, FrameSubscribed); err != nil {
+		return false, fmt.Errorf("failed to subscribe: %w", err)
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	r.logger.Info().Str("url", r.cfg.URL).Msg("Subscribed for real-time delivery")
+
+	for {
+		var frame WebSocketFrame
+		if err := conn.ReadJSON(&frame); err != nil {
+			return true, fmt.Errorf("failed to read frame: %w", err)
+		}
+		switch frame.Type {
+		case FrameEnvelope:
+			if frame.Envelope == nil {
+				r.logger.Warn().Str("message_id", frame.ID).Msg("Ignoring envelope frame without an envelope")
+				continue
+			}
+			if err := r.handler(ctx, frame.Envelope); err != nil {
+				r.logger.Warn().Err(err).Str("message_id", frame.ID).Str("sender_id", frame.Envelope.SenderID).Msg("Failed to process envelope, leaving it unacknowledged")
+				continue
+			}
+			if err := conn.WriteJSON(WebSocketFrame{Type: FrameAck, ID: frame.ID}); err != nil {
+				return true, fmt.Errorf("failed to acknowledge envelope %s: %w", frame.ID, err)
+			}
+		case FrameError:
+			return true, fmt.Errorf("routing service reported an error: %s", frame.Error)
+		default:
+			r.logger.Debug().Str("type", frame.Type).Msg("Ignoring unexpected frame")
+		}
+	}
+}
+
+// request writes a frame and waits for the expected reply.
+func (r *WebSocketReceiver) request(conn *websocket.Conn, frame WebSocketFrame, want string) error {
+	if err := conn.WriteJSON(frame); err != nil {
+		return err
+	}
+	var reply WebSocketFrame
+	if err := conn.ReadJSON(&reply); err != nil {
+		return err
+	}
+	switch reply.Type {
+	case want:
+		return nil
+	case FrameError:
+		return &frameError{message: reply.Error}
+	default:
+		return fmt.Errorf("expected %q frame, got %q", want, reply.Type)
+	}
+}
+
+// frameError is an error frame sent by the routing service in reply to a request.
+type frameError struct {
+	message string
+}
+
+func (e *frameError) Error() string {
+	return e.message
+}
@@ -0,0 +1,208 @@
+package clients_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/illmade-knight/action-intention/internal/clients"
+	"github.com/illmade-knight/go-secure-messaging/pkg/transport"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wsStandIn is a local stand-in for the routing service's WebSocket delivery
+// path. Each connection must authenticate and subscribe, after which every
+// pending envelope is delivered. If dropAfterDelivery is set, the first
+// connection is closed straight after its deliveries to force a reconnect.
+type wsStandIn struct {
+	token             string
+	dropAfterDelivery bool
+
+	mu          sync.Mutex
+	pending     map[string]transport.SecureEnvelope
+	acked       []string
+	connections int
+}
+
+func (s *wsStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	s.mu.Lock()
+	s.connections++
+	connection := s.connections
+	s.mu.Unlock()
+
+	var auth clients.WebSocketFrame
+	if err := conn.ReadJSON(&auth); err != nil || auth.Type != clients.FrameAuth {
+		return
+	}
+	if auth.Token != s.token {
+		_ = conn.WriteJSON(clients.WebSocketFrame{Type: clients.FrameError, Error: "invalid token"})
+		return
+	}
+	_ = conn.WriteJSON(clients.WebSocketFrame{Type: clients.FrameAuthOK})
+
+	var subscribe clients.WebSocketFrame
+	if err := conn.ReadJSON(&subscribe); err != nil || subscribe.Type != clients.FrameSubscribe || subscribe.UserID != auth.UserID {
+		return
+	}
+	_ = conn.WriteJSON(clients.WebSocketFrame{Type: clients.FrameSubscribed})
+
+	s.mu.Lock()
+	var frames []clients.WebSocketFrame
+	for id, envelope := range s.pending {
+		frames = append(frames, clients.WebSocketFrame{Type: clients.FrameEnvelope, ID: id, Envelope: &envelope})
+	}
+	s.mu.Unlock()
+	for _, frame := range frames {
+		_ = conn.WriteJSON(frame)
+	}
+	if s.dropAfterDelivery && connection == 1 {
+		return
+	}
+
+	for {
+		var frame clients.WebSocketFrame
+		if err := conn.ReadJSON(&frame); err != nil {
+			return
+		}
+		if frame.Type == clients.FrameAck {
+			s.mu.Lock()
+			s.acked = append(s.acked, frame.ID)
+			delete(s.pending, frame.ID)
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *wsStandIn) snapshot() (acked []string, connections int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.acked...), s.connections
+}
+
+func startReceiver(t *testing.T, server *httptest.Server, token string, handler clients.EnvelopeHandler) context.CancelFunc {
+	t.Helper()
+	cfg := clients.WebSocketReceiverConfig{
+		URL:        "ws" + strings.TrimPrefix(server.URL, "http"),
+		UserID:     "user-bob",
+		Token:      func(context.Context) (string, error) { return token, nil },
+		MinBackoff: 10 * time.Millisecond,
+		MaxBackoff: 40 * time.Millisecond,
+	}
+	receiver := clients.NewWebSocketReceiver(cfg, handler, zerolog.Nop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		receiver.Run(ctx)
+		close(done)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestWebSocketReceiver_DeliversAndAcks(t *testing.T) {
+	// Arrange
+	standIn := &wsStandIn{token: "secret", pending: map[string]transport.SecureEnvelope{
+		"m1": {SenderID: "user-alice", RecipientID: "user-bob"},
+	}}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	handled := make(chan string, 1)
+	handler := func(ctx context.Context, envelope *transport.SecureEnvelope) error {
+		handled <- envelope.SenderID
+		return nil
+	}
+
+	// Act
+	stop := startReceiver(t, server, "secret", handler)
+	defer stop()
+
+	// Assert
+	select {
+	case sender := <-handled:
+		assert.Equal(t, "user-alice", sender)
+	case <-time.After(2 * time.Second):
+		t.Fatal("envelope was not delivered")
+	}
+	require.Eventually(t, func() bool {
+		acked, _ := standIn.snapshot()
+		return len(acked) == 1 && acked[0] == "m1"
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestWebSocketReceiver_ReconnectsAndRedeliversUnacked(t *testing.T) {
+	// Arrange: The first connection drops before the failed envelope can be retried
+	standIn := &wsStandIn{token: "secret", dropAfterDelivery: true, pending: map[string]transport.SecureEnvelope{
+		"m1": {SenderID: "user-alice", RecipientID: "user-bob"},
+	}}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	var mu sync.Mutex
+	attempts := 0
+	handler := func(ctx context.Context, envelope *transport.SecureEnvelope) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			return errors.New("storage unavailable")
+		}
+		return nil
+	}
+
+	// Act
+	stop := startReceiver(t, server, "secret", handler)
+	defer stop()
+
+	// Assert: The envelope is acknowledged on the second connection
+	require.Eventually(t, func() bool {
+		acked, _ := standIn.snapshot()
+		return len(acked) == 1
+	}, 2*time.Second, 5*time.Millisecond)
+	_, connections := standIn.snapshot()
+	assert.GreaterOrEqual(t, connections, 2)
+}
+
+func TestWebSocketReceiver_RetriesRejectedAuth(t *testing.T) {
+	// Arrange
+	standIn := &wsStandIn{token: "secret", pending: map[string]transport.SecureEnvelope{
+		"m1": {SenderID: "user-alice", RecipientID: "user-bob"},
+	}}
+	server := httptest.NewServer(standIn)
+	defer server.Close()
+
+	handler := func(ctx context.Context, envelope *transport.SecureEnvelope) error {
+		t.Error("envelope delivered without authentication")
+		return nil
+	}
+
+	// Act
+	stop := startReceiver(t, server, "wrong", handler)
+	require.Eventually(t, func() bool {
+		_, connections := standIn.snapshot()
+		return connections >= 3
+	}, 2*time.Second, 5*time.Millisecond)
+	stop()
+
+	// Assert
+	acked, _ := standIn.snapshot()
+	assert.Empty(t, acked)
+}
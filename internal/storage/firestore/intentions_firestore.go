@@ -3,18 +3,29 @@ package firestore
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 	"github.com/illmade-knight/action-intention/pkg/intentions"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // targetDocument is a private struct for Firestore marshalling that handles the Target interface.
+// Data holds the target's fields as produced by the intentions target registry, stored as a
+// native map so the documents remain readable in the Firestore console.
+//
+// LocationID, PersonIDs and GroupIDs are the fields targets were stored with before Data was
+// introduced. They are only read, so documents written in that shape still load.
 type targetDocument struct {
-	Type       string      `firestore:"type"`
+	Type string                 `firestore:"type"`
+	Data map[string]interface{} `firestore:"data"`
+
 	LocationID *uuid.UUID  `firestore:"locationId,omitempty"`
 	PersonIDs  []uuid.UUID `firestore:"personIds,omitempty"`
 	GroupIDs   []uuid.UUID `firestore:"groupIds,omitempty"`
@@ -29,6 +40,110 @@ type intentionDocument struct {
 	StartTime    time.Time        `firestore:"startTime"`
 	EndTime      time.Time        `firestore:"endTime"`
 	CreatedAt    time.Time        `firestore:"createdAt"`
+	Status       string           `firestore:"status"`
+	ConfirmedAt  *time.Time       `firestore:"confirmedAt,omitempty"`
+	StartedAt    *time.Time       `firestore:"startedAt,omitempty"`
+	CompletedAt  *time.Time       `firestore:"completedAt,omitempty"`
+	CancelledAt  *time.Time       `firestore:"cancelledAt,omitempty"`
+	Version      int              `firestore:"version"`
+	// Invitations holds the RSVP state of each invited participant.
+	Invitations []invitationDocument `firestore:"invitations,omitempty"`
+	// Refs denormalizes the IDs referenced by the targets (e.g. "location:<id>")
+	// so reference filters can use an array-contains query.
+	Refs       []string            `firestore:"refs"`
+	Recurrence *recurrenceDocument `firestore:"recurrence,omitempty"`
+	// Recurring and SeriesEnd let time-range queries find recurring intentions
+	// whose first occurrence is outside the range.
+	Recurring bool      `firestore:"recurring"`
+	SeriesEnd time.Time `firestore:"seriesEnd"`
+	// Provenance is set on received copies, for FindByProvenance.
+	Provenance *sourceDocument `firestore:"provenance,omitempty"`
+}
+
+// invitationDocument is the stored form of intentions.Invitation.
+type invitationDocument struct {
+	Participant string     `firestore:"participant"`
+	RSVP        string     `firestore:"rsvp"`
+	InvitedAt   time.Time  `firestore:"invitedAt"`
+	RespondedAt *time.Time `firestore:"respondedAt,omitempty"`
+}
+
+func toInvitationDocuments(invitations []intentions.Invitation) []invitationDocument {
+	docs := make([]invitationDocument, len(invitations))
+	for i, inv := range invitations {
+		docs[i] = invitationDocument{
+			Participant: inv.Participant,
+			RSVP:        string(inv.RSVP),
+			InvitedAt:   inv.InvitedAt,
+			RespondedAt: inv.RespondedAt,
+		}
+	}
+	return docs
+}
+
+func toInvitations(docs []invitationDocument) []intentions.Invitation {
+	if len(docs) == 0 {
+		return nil
+	}
+	invitations := make([]intentions.Invitation, len(docs))
+	for i, doc := range docs {
+		invitations[i] = intentions.Invitation{
+			Participant: doc.Participant,
+			RSVP:        intentions.RSVP(doc.RSVP),
+			InvitedAt:   doc.InvitedAt,
+			RespondedAt: doc.RespondedAt,
+		}
+	}
+	return invitations
+}
+
+// recurrenceDocument is the stored form of intentions.Recurrence.
+type recurrenceDocument struct {
+	Frequency  string      `firestore:"frequency"`
+	Interval   int         `firestore:"interval"`
+	ByDay      []int       `firestore:"byDay,omitempty"`
+	Until      *time.Time  `firestore:"until,omitempty"`
+	Count      int         `firestore:"count"`
+	Exceptions []time.Time `firestore:"exceptions,omitempty"`
+}
+
+// openSeriesEnd is stored as the SeriesEnd of intentions that recur without end.
+var openSeriesEnd = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+func toRecurrenceDocument(r *intentions.Recurrence) *recurrenceDocument {
+	if r == nil {
+		return nil
+	}
+	byDay := make([]int, len(r.ByDay))
+	for i, day := range r.ByDay {
+		byDay[i] = int(day)
+	}
+	return &recurrenceDocument{
+		Frequency:  string(r.Frequency),
+		Interval:   r.Interval,
+		ByDay:      byDay,
+		Until:      r.Until,
+		Count:      r.Count,
+		Exceptions: r.Exceptions,
+	}
+}
+
+func toRecurrence(doc *recurrenceDocument) *intentions.Recurrence {
+	if doc == nil {
+		return nil
+	}
+	var byDay []time.Weekday
+	for _, day := range doc.ByDay {
+		byDay = append(byDay, time.Weekday(day))
+	}
+	return &intentions.Recurrence{
+		Frequency:  intentions.Frequency(doc.Frequency),
+		Interval:   doc.Interval,
+		ByDay:      byDay,
+		Until:      doc.Until,
+		Count:      doc.Count,
+		Exceptions: doc.Exceptions,
+	}
 }
 
 // IntentionStore is a concrete implementation of the intentions.Store interface using Firestore.
@@ -45,71 +160,358 @@ func NewIntentionsStore(client *firestore.Client) *IntentionStore {
 	}
 }
 
-// toTargetDocument converts a Target interface into its serializable document form.
+// toTargetDocument converts a Target interface into its serializable document form
+// using the same registry that drives the JSON codec.
 func toTargetDocument(target intentions.Target) (targetDocument, error) {
-	switch t := target.(type) {
-	case intentions.LocationTarget:
-		return targetDocument{Type: "Location", LocationID: &t.LocationID}, nil
-	case intentions.ProximityTarget:
-		return targetDocument{Type: "Proximity", PersonIDs: t.PersonIDs, GroupIDs: t.GroupIDs}, nil
-	default:
-		return targetDocument{}, fmt.Errorf("unknown target type: %s", t.Type())
+	record, err := intentions.EncodeTarget(target)
+	if err != nil {
+		return targetDocument{}, err
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(record.Data, &data); err != nil {
+		return targetDocument{}, fmt.Errorf("failed to convert %s target to document: %w", record.Type, err)
 	}
+	return targetDocument{Type: record.Type, Data: data}, nil
 }
 
 // toTarget converts a targetDocument back into a Target interface.
 func toTarget(doc targetDocument) (intentions.Target, error) {
+	if doc.Data == nil {
+		return toLegacyTarget(doc)
+	}
+	data, err := json.Marshal(doc.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s target document: %w", doc.Type, err)
+	}
+	return intentions.DecodeTarget(intentions.TargetRecord{Type: doc.Type, Data: data})
+}
+
+// toLegacyTarget reads a target stored before the registry-driven Data field.
+// Only location and proximity targets existed then.
+func toLegacyTarget(doc targetDocument) (intentions.Target, error) {
 	switch doc.Type {
-	case "Location":
+	case intentions.LocationTarget{}.Type():
 		if doc.LocationID == nil {
 			return nil, fmt.Errorf("location target document has nil LocationID")
 		}
 		return intentions.LocationTarget{LocationID: *doc.LocationID}, nil
-	case "Proximity":
+	case intentions.ProximityTarget{}.Type():
 		return intentions.ProximityTarget{PersonIDs: doc.PersonIDs, GroupIDs: doc.GroupIDs}, nil
 	default:
 		return nil, fmt.Errorf("unknown target type in document: %s", doc.Type)
 	}
 }
 
-// Add saves a new intention to the store.
-func (s *IntentionStore) Add(ctx context.Context, intent intentions.Intention) error {
+// toIntentionDocument converts an Intention into the form stored in Firestore.
+func toIntentionDocument(intent intentions.Intention) (intentionDocument, error) {
 	targetDocs := make([]targetDocument, len(intent.Targets))
 	for i, target := range intent.Targets {
 		doc, err := toTargetDocument(target)
 		if err != nil {
-			return err
+			return intentionDocument{}, err
 		}
 		targetDocs[i] = doc
 	}
 
-	doc := intentionDocument{
+	seriesEnd, bounded := intent.SeriesEnd()
+	if !bounded {
+		seriesEnd = openSeriesEnd
+	}
+
+	return intentionDocument{
 		User:         intent.User,
 		Participants: intent.Participants,
+		Invitations:  toInvitationDocuments(intent.Invitations),
 		Action:       intent.Action,
 		Targets:      targetDocs,
 		StartTime:    intent.StartTime,
 		EndTime:      intent.EndTime,
 		CreatedAt:    intent.CreatedAt,
+		Status:       string(intent.Status),
+		ConfirmedAt:  intent.ConfirmedAt,
+		StartedAt:    intent.StartedAt,
+		CompletedAt:  intent.CompletedAt,
+		CancelledAt:  intent.CancelledAt,
+		Version:      intent.Version,
+		Refs:         toRefs(intentions.IntentionReferences(intent)),
+		Recurrence:   toRecurrenceDocument(intent.Recurrence),
+		Recurring:    intent.Recurrence != nil,
+		SeriesEnd:    seriesEnd,
+		Provenance:   toSourceDocument(intent.Provenance),
+	}, nil
+}
+
+// refKey builds an entry of the intentionDocument Refs field.
+func refKey(kind string, id uuid.UUID) string {
+	return kind + ":" + id.String()
+}
+
+// toRefs flattens target references into the values stored in the Refs field.
+func toRefs(refs intentions.TargetRefs) []string {
+	var keys []string
+	for _, id := range refs.LocationIDs {
+		keys = append(keys, refKey("location", id))
+	}
+	for _, id := range refs.PersonIDs {
+		keys = append(keys, refKey("person", id))
+	}
+	for _, id := range refs.GroupIDs {
+		keys = append(keys, refKey("group", id))
+	}
+	for _, id := range refs.IntentionIDs {
+		keys = append(keys, refKey("intention", id))
+	}
+	return keys
+}
+
+// toIntention converts a stored document back into an Intention.
+func toIntention(snap *firestore.DocumentSnapshot) (intentions.Intention, error) {
+	var idoc intentionDocument
+	if err := snap.DataTo(&idoc); err != nil {
+		return intentions.Intention{}, err
+	}
+
+	targets := make([]intentions.Target, len(idoc.Targets))
+	for i, tDoc := range idoc.Targets {
+		target, err := toTarget(tDoc)
+		if err != nil {
+			return intentions.Intention{}, err
+		}
+		targets[i] = target
+	}
+
+	docID, err := uuid.Parse(snap.Ref.ID)
+	if err != nil {
+		return intentions.Intention{}, err
 	}
 
-	_, err := s.collection.Doc(intent.ID.String()).Set(ctx, doc)
+	return intentions.Intention{
+		ID:           docID,
+		User:         idoc.User,
+		Participants: idoc.Participants,
+		Invitations:  toInvitations(idoc.Invitations),
+		Action:       idoc.Action,
+		Targets:      targets,
+		StartTime:    idoc.StartTime,
+		EndTime:      idoc.EndTime,
+		CreatedAt:    idoc.CreatedAt,
+		Recurrence:   toRecurrence(idoc.Recurrence),
+		Status:       intentions.Status(idoc.Status),
+		ConfirmedAt:  idoc.ConfirmedAt,
+		StartedAt:    idoc.StartedAt,
+		CompletedAt:  idoc.CompletedAt,
+		CancelledAt:  idoc.CancelledAt,
+		Version:      idoc.Version,
+		Provenance:   toSource(idoc.Provenance),
+	}, nil
+}
+
+// Add saves a new intention to the store.
+func (s *IntentionStore) Add(ctx context.Context, intent intentions.Intention) error {
+	doc, err := toIntentionDocument(intent)
+	if err != nil {
+		return err
+	}
+	_, err = s.collection.Doc(intent.ID.String()).Set(ctx, doc)
 	return err
 }
 
-// Query retrieves intentions based on the provided specification.
+// Get retrieves a single intention by its ID.
+func (s *IntentionStore) Get(ctx context.Context, id uuid.UUID) (intentions.Intention, error) {
+	snap, err := s.collection.Doc(id.String()).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return intentions.Intention{}, apperr.Errorf(apperr.ErrNotFound, "intention with ID %s not found", id)
+		}
+		return intentions.Intention{}, err
+	}
+	return toIntention(snap)
+}
+
+// Update replaces a stored intention if its version matches the stored one.
+// The read and write happen in a transaction so concurrent updates cannot both succeed.
+func (s *IntentionStore) Update(ctx context.Context, intent intentions.Intention) error {
+	ref := s.collection.Doc(intent.ID.String())
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return apperr.Errorf(apperr.ErrNotFound, "intention with ID %s not found", intent.ID)
+			}
+			return err
+		}
+		var existing intentionDocument
+		if err := snap.DataTo(&existing); err != nil {
+			return err
+		}
+		if existing.Version != intent.Version {
+			return fmt.Errorf("update intention %s at version %d (stored %d): %w", intent.ID, intent.Version, existing.Version, intentions.ErrVersionConflict)
+		}
+
+		intent.Version++
+		doc, err := toIntentionDocument(intent)
+		if err != nil {
+			return err
+		}
+		return tx.Set(ref, doc)
+	})
+}
+
+// Cancel marks an intention as cancelled without removing it. The status is
+// checked and changed in a transaction so a concurrent transition cannot be
+// overwritten.
+func (s *IntentionStore) Cancel(ctx context.Context, id uuid.UUID) error {
+	ref := s.collection.Doc(id.String())
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(ref)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return apperr.Errorf(apperr.ErrNotFound, "intention with ID %s not found", id)
+			}
+			return err
+		}
+		var existing intentionDocument
+		if err := snap.DataTo(&existing); err != nil {
+			return err
+		}
+		from := intentions.Status(existing.Status)
+		if !intentions.CanTransition(from, intentions.StatusCancelled) {
+			return apperr.Errorf(apperr.ErrConflict, "cannot change intention %s from %s to %s", id, from, intentions.StatusCancelled)
+		}
+
+		return tx.Update(ref, []firestore.Update{
+			{Path: "status", Value: string(intentions.StatusCancelled)},
+			{Path: "cancelledAt", Value: firestore.ServerTimestamp},
+			{Path: "version", Value: firestore.Increment(1)},
+		})
+	})
+}
+
+// Delete permanently removes an intention.
+func (s *IntentionStore) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := s.collection.Doc(id.String()).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return apperr.Errorf(apperr.ErrNotFound, "intention with ID %s not found", id)
+	}
+	return err
+}
+
+// FindByProvenance retrieves the local copy of another user's intention.
+func (s *IntentionStore) FindByProvenance(ctx context.Context, senderID string, senderIntentionID uuid.UUID) (intentions.Intention, error) {
+	iter := s.collection.
+		Where("provenance.senderId", "==", senderID).
+		Where("provenance.senderEntityId", "==", senderIntentionID.String()).
+		Limit(1).
+		Documents(ctx)
+	defer iter.Stop()
+	snap, err := iter.Next()
+	if err == iterator.Done {
+		return intentions.Intention{}, apperr.Errorf(apperr.ErrNotFound, "no copy of intention %s from %s", senderIntentionID, senderID)
+	}
+	if err != nil {
+		return intentions.Intention{}, err
+	}
+	return toIntention(snap)
+}
+
+// Query retrieves intentions based on the provided specification, ordered by start time.
+//
+// Firestore allows a single array-contains filter per query and has no substring
+// matching, so only the user, the time range and one of the reference or
+// participant filters are applied on the server. The remaining filters are
+// applied to the results as they are read, before the limit.
+//
+// When the spec has a time range, recurring intentions are read by a second
+// query on their series bounds and expanded into occurrences in memory, then
+// merged with the one-off intentions.
+//
+// The server-side filters combined with the start-time ordering need composite
+// indexes on the intentions collection:
+//
+//	user ASC, startTime ASC, endTime ASC, __name__ ASC
+//	refs CONTAINS, startTime ASC, endTime ASC, __name__ ASC
+//	participants CONTAINS, startTime ASC, endTime ASC, __name__ ASC
+//	user ASC, refs CONTAINS, startTime ASC, endTime ASC, __name__ ASC
+//	user ASC, participants CONTAINS, startTime ASC, endTime ASC, __name__ ASC
+//
+// and, for recurring intentions, each of the above prefixes followed by
+// recurring ASC, startTime ASC, seriesEnd ASC.
 func (s *IntentionStore) Query(ctx context.Context, spec intentions.QuerySpec) ([]intentions.Intention, error) {
+	var cursor *intentions.QueryCursor
+	if spec.After != "" {
+		c, err := intentions.DecodeCursor(spec.After)
+		if err != nil {
+			return nil, err
+		}
+		cursor = &c
+	}
+
+	from, to := spec.TimeBounds()
+	ranged := from != nil || to != nil
+
+	results, err := s.queryOneOff(ctx, spec, cursor, ranged)
+	if err != nil || !ranged {
+		return results, err
+	}
+
+	occurrences, err := s.queryOccurrences(ctx, spec, cursor)
+	if err != nil {
+		return nil, err
+	}
+	if len(occurrences) == 0 {
+		return results, nil
+	}
+	results = append(results, occurrences...)
+	intentions.SortIntentions(results)
+	if spec.Limit > 0 && len(results) > spec.Limit {
+		results = results[:spec.Limit]
+	}
+	return results, nil
+}
+
+// filteredQuery applies the user filter and at most one array-contains filter.
+func (s *IntentionStore) filteredQuery(spec intentions.QuerySpec) firestore.Query {
 	q := s.collection.Query
 	if spec.User != nil {
 		q = q.Where("user", "==", *spec.User)
 	}
-	if spec.ActiveAt != nil {
-		q = q.Where("startTime", "<=", *spec.ActiveAt).Where("endTime", ">=", *spec.ActiveAt)
+	switch {
+	case spec.LocationID != nil:
+		q = q.Where("refs", "array-contains", refKey("location", *spec.LocationID))
+	case spec.PersonID != nil:
+		q = q.Where("refs", "array-contains", refKey("person", *spec.PersonID))
+	case spec.GroupID != nil:
+		q = q.Where("refs", "array-contains", refKey("group", *spec.GroupID))
+	case spec.Participant != nil:
+		q = q.Where("participants", "array-contains", *spec.Participant)
+	}
+	return q
+}
+
+// queryOneOff reads intentions in start-time order with the cursor and limit
+// applied on the server. If skipRecurring is set, recurring intentions are left
+// for queryOccurrences.
+func (s *IntentionStore) queryOneOff(ctx context.Context, spec intentions.QuerySpec, cursor *intentions.QueryCursor, skipRecurring bool) ([]intentions.Intention, error) {
+	q := s.filteredQuery(spec)
+	from, to := spec.TimeBounds()
+	if to != nil {
+		q = q.Where("startTime", "<=", *to)
+	}
+	if from != nil {
+		q = q.Where("endTime", ">=", *from)
+	}
+
+	q = q.OrderBy("startTime", firestore.Asc).
+		OrderBy("endTime", firestore.Asc).
+		OrderBy(firestore.DocumentID, firestore.Asc)
+	if cursor != nil {
+		q = q.StartAfter(cursor.StartTime, cursor.EndTime, s.collection.Doc(cursor.ID.String()))
 	}
 
 	iter := q.Documents(ctx)
+	defer iter.Stop()
 	var results []intentions.Intention
-	for {
+	for spec.Limit <= 0 || len(results) < spec.Limit {
 		doc, err := iter.Next()
 		if err == iterator.Done {
 			break
@@ -118,35 +520,55 @@ func (s *IntentionStore) Query(ctx context.Context, spec intentions.QuerySpec) (
 			return nil, err
 		}
 
-		var idoc intentionDocument
-		if err := doc.DataTo(&idoc); err != nil {
+		intent, err := toIntention(doc)
+		if err != nil {
 			return nil, err
 		}
-
-		targets := make([]intentions.Target, len(idoc.Targets))
-		for i, tDoc := range idoc.Targets {
-			target, err := toTarget(tDoc)
-			if err != nil {
-				return nil, err
-			}
-			targets[i] = target
+		if skipRecurring && intent.Recurrence != nil {
+			continue
+		}
+		if !spec.Matches(intent) {
+			continue
 		}
+		results = append(results, intent)
+	}
+	return results, nil
+}
 
-		docID, err := uuid.Parse(doc.Ref.ID)
+// queryOccurrences reads the recurring intentions whose series overlaps the
+// spec's time range and expands them into matching occurrences after the cursor.
+func (s *IntentionStore) queryOccurrences(ctx context.Context, spec intentions.QuerySpec, cursor *intentions.QueryCursor) ([]intentions.Intention, error) {
+	q := s.filteredQuery(spec).Where("recurring", "==", true)
+	from, to := spec.TimeBounds()
+	if to != nil {
+		q = q.Where("startTime", "<=", *to)
+	}
+	if from != nil {
+		q = q.Where("seriesEnd", ">=", *from)
+	}
+
+	iter := q.Documents(ctx)
+	defer iter.Stop()
+	var results []intentions.Intention
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
 		if err != nil {
 			return nil, err
 		}
 
-		results = append(results, intentions.Intention{
-			ID:           docID,
-			User:         idoc.User,
-			Participants: idoc.Participants,
-			Action:       idoc.Action,
-			Targets:      targets,
-			StartTime:    idoc.StartTime,
-			EndTime:      idoc.EndTime,
-			CreatedAt:    idoc.CreatedAt,
-		})
+		intent, err := toIntention(doc)
+		if err != nil {
+			return nil, err
+		}
+		for _, occurrence := range spec.Expand(intent) {
+			if cursor != nil && cursor.Precedes(occurrence) {
+				continue
+			}
+			results = append(results, occurrence)
+		}
 	}
 	return results, nil
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	fst "github.com/illmade-knight/action-intention/internal/storage/firestore"
 	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/illmade-knight/action-intention/pkg/storetest"
 	"github.com/illmade-knight/go-test/emulators"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -104,3 +105,240 @@ func TestIntentionsStore(t *testing.T) {
 		assert.Len(t, results, 0)
 	})
 }
+
+func TestIntentionsStore_RegisteredTargets(t *testing.T) {
+	ctx, _, store := setupIntentionsTest(t)
+	now := time.Now()
+
+	// Arrange: An intention using each of the newer target kinds
+	intent := intentions.Intention{
+		ID:     uuid.New(),
+		User:   "user-alice",
+		Action: "Watch the match",
+		Targets: []intentions.Target{
+			intentions.OnlineTarget{Platform: "Discord", URL: "https://discord.example/room"},
+			intentions.RouteTarget{LocationIDs: []uuid.UUID{uuid.New(), uuid.New()}},
+			intentions.EventTarget{IntentionID: uuid.New()},
+			intentions.TimeWindowTarget{Start: now.UTC().Truncate(time.Second), End: now.UTC().Truncate(time.Second).Add(3 * time.Hour)},
+		},
+		StartTime: now.Add(-1 * time.Hour),
+		EndTime:   now.Add(1 * time.Hour),
+	}
+
+	// Act
+	require.NoError(t, store.Add(ctx, intent))
+	user := "user-alice"
+	results, err := store.Query(ctx, intentions.QuerySpec{User: &user})
+
+	// Assert: Each target is rebuilt as its concrete type
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, intent.Targets, results[0].Targets)
+}
+
+func TestIntentionsStore_LegacyTargets(t *testing.T) {
+	ctx, client, store := setupIntentionsTest(t)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	// Arrange: A document written before targets were stored as type and data
+	id := uuid.New()
+	locationID := uuid.New()
+	personIDs := []uuid.UUID{uuid.New()}
+	groupIDs := []uuid.UUID{uuid.New()}
+	_, err := client.Collection("intentions").Doc(id.String()).Set(ctx, map[string]interface{}{
+		"user":         "user-alice",
+		"participants": []string{"user-bob"},
+		"action":       "Climb",
+		"targets": []map[string]interface{}{
+			{"type": "Location", "locationId": locationID},
+			{"type": "Proximity", "personIds": personIDs, "groupIds": groupIDs},
+		},
+		"startTime": now,
+		"endTime":   now.Add(time.Hour),
+		"createdAt": now,
+	})
+	require.NoError(t, err)
+
+	// Act
+	intent, err := store.Get(ctx, id)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []intentions.Target{
+		intentions.LocationTarget{LocationID: locationID},
+		intentions.ProximityTarget{PersonIDs: personIDs, GroupIDs: groupIDs},
+	}, intent.Targets)
+	assert.True(t, intentions.CanTransition(intent.Status, intentions.StatusConfirmed), "a legacy intention is planned")
+}
+
+func TestIntentionsStore_CRUD(t *testing.T) {
+	ctx, _, store := setupIntentionsTest(t)
+	now := time.Now()
+
+	// Arrange
+	intent := intentions.Intention{
+		ID:        uuid.New(),
+		User:      "user-alice",
+		Action:    "Work",
+		Targets:   []intentions.Target{intentions.LocationTarget{LocationID: uuid.New()}},
+		StartTime: now,
+		EndTime:   now.Add(1 * time.Hour),
+		Status:    intentions.StatusPlanned,
+		Version:   1,
+	}
+	require.NoError(t, store.Add(ctx, intent))
+
+	t.Run("Get", func(t *testing.T) {
+		got, err := store.Get(ctx, intent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, intent.Action, got.Action)
+		assert.Equal(t, 1, got.Version)
+	})
+
+	t.Run("Update with current version", func(t *testing.T) {
+		changed := intent
+		changed.Action = "Work from home"
+		require.NoError(t, store.Update(ctx, changed))
+
+		got, err := store.Get(ctx, intent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Work from home", got.Action)
+		assert.Equal(t, 2, got.Version)
+	})
+
+	t.Run("Update with stale version", func(t *testing.T) {
+		err := store.Update(ctx, intent)
+		assert.ErrorIs(t, err, intentions.ErrVersionConflict)
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		require.NoError(t, store.Cancel(ctx, intent.ID))
+
+		got, err := store.Get(ctx, intent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, intentions.StatusCancelled, got.Status)
+		assert.NotNil(t, got.CancelledAt)
+		assert.Equal(t, 3, got.Version)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, store.Delete(ctx, intent.ID))
+
+		_, err := store.Get(ctx, intent.ID)
+		assert.Error(t, err)
+		assert.Error(t, store.Delete(ctx, intent.ID))
+	})
+}
+
+func TestIntentionsStore_RichQuery(t *testing.T) {
+	ctx, _, store := setupIntentionsTest(t)
+	now := time.Now().Truncate(time.Millisecond)
+
+	// Arrange: Three intentions at different times sharing one location
+	park := uuid.New()
+	friend := uuid.New()
+	morning := intentions.Intention{
+		ID: uuid.New(), User: "user-alice", Action: "Morning run",
+		Targets:   []intentions.Target{intentions.LocationTarget{LocationID: park}},
+		StartTime: now.Add(1 * time.Hour), EndTime: now.Add(2 * time.Hour),
+	}
+	lunch := intentions.Intention{
+		ID: uuid.New(), User: "user-alice", Action: "Lunch", Participants: []string{"user-bob"},
+		Targets:   []intentions.Target{intentions.ProximityTarget{PersonIDs: []uuid.UUID{friend}}},
+		StartTime: now.Add(3 * time.Hour), EndTime: now.Add(4 * time.Hour),
+	}
+	evening := intentions.Intention{
+		ID: uuid.New(), User: "user-alice", Action: "Evening run",
+		Targets:   []intentions.Target{intentions.LocationTarget{LocationID: park}},
+		StartTime: now.Add(5 * time.Hour), EndTime: now.Add(6 * time.Hour),
+	}
+	for _, intent := range []intentions.Intention{evening, morning, lunch} {
+		require.NoError(t, store.Add(ctx, intent))
+	}
+
+	t.Run("Overlapping range", func(t *testing.T) {
+		from, to := now.Add(90*time.Minute), now.Add(210*time.Minute)
+		results, err := store.Query(ctx, intentions.QuerySpec{From: &from, To: &to})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		assert.Equal(t, morning.ID, results[0].ID)
+		assert.Equal(t, lunch.ID, results[1].ID)
+	})
+
+	t.Run("By location and action", func(t *testing.T) {
+		action := "RUN"
+		results, err := store.Query(ctx, intentions.QuerySpec{LocationID: &park, ActionContains: &action})
+		require.NoError(t, err)
+		assert.Len(t, results, 2)
+	})
+
+	t.Run("By person and participant", func(t *testing.T) {
+		participant := "user-bob"
+		results, err := store.Query(ctx, intentions.QuerySpec{PersonID: &friend, Participant: &participant})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, lunch.ID, results[0].ID)
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		user := "user-alice"
+		page1, err := store.Query(ctx, intentions.QuerySpec{User: &user, Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, page1, 2)
+		assert.Equal(t, morning.ID, page1[0].ID)
+
+		page2, err := store.Query(ctx, intentions.QuerySpec{User: &user, Limit: 2, After: intentions.CursorAfter(page1[1])})
+		require.NoError(t, err)
+		require.Len(t, page2, 1)
+		assert.Equal(t, evening.ID, page2[0].ID)
+	})
+}
+
+func TestIntentionsStore_Recurring(t *testing.T) {
+	ctx, _, store := setupIntentionsTest(t)
+
+	// Arrange: Football every Tuesday 16:00-17:00, starting three weeks ago
+	now := time.Now().UTC().Truncate(time.Second)
+	first := time.Date(now.Year(), now.Month(), now.Day(), 16, 0, 0, 0, time.UTC).AddDate(0, 0, -21)
+	football := intentions.Intention{
+		ID:         uuid.New(),
+		User:       "user-alice",
+		Action:     "Football",
+		Targets:    []intentions.Target{intentions.LocationTarget{LocationID: uuid.New()}},
+		StartTime:  first,
+		EndTime:    first.Add(1 * time.Hour),
+		Recurrence: &intentions.Recurrence{Frequency: intentions.FrequencyWeekly},
+	}
+	require.NoError(t, store.Add(ctx, football))
+
+	t.Run("Active during a later occurrence", func(t *testing.T) {
+		at := first.AddDate(0, 0, 14).Add(30 * time.Minute)
+		results, err := store.Query(ctx, intentions.QuerySpec{ActiveAt: &at})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, football.ID, results[0].ID)
+		assert.True(t, first.AddDate(0, 0, 14).Equal(results[0].StartTime))
+	})
+
+	t.Run("Range covering several occurrences", func(t *testing.T) {
+		from, to := first, first.AddDate(0, 0, 15)
+		results, err := store.Query(ctx, intentions.QuerySpec{From: &from, To: &to})
+		require.NoError(t, err)
+		assert.Len(t, results, 3)
+	})
+
+	t.Run("Between occurrences", func(t *testing.T) {
+		at := first.AddDate(0, 0, 1)
+		results, err := store.Query(ctx, intentions.QuerySpec{ActiveAt: &at})
+		require.NoError(t, err)
+		assert.Len(t, results, 0)
+	})
+}
+
+func TestIntentionsStore_Conformance(t *testing.T) {
+	ctx, fsClient, store := setupIntentionsTest(t)
+	storetest.RunIntentionStoreTests(t, func(t *testing.T) intentions.Store {
+		clearCollection(t, ctx, fsClient, "intentions")
+		return store
+	})
+}
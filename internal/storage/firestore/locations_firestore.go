@@ -3,11 +3,11 @@ package firestore
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 	"github.com/illmade-knight/action-intention/pkg/locations"
 	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
@@ -17,13 +17,23 @@ import (
 // locationDocument is the private struct used for Firestore marshalling. This keeps
 // the public domain model in `pkg/locations` clean from persistence-specific tags.
 type locationDocument struct {
-	Name      string                    `firestore:"name"`
-	Category  string                    `firestore:"category"`
-	GlobalID  *string                   `firestore:"globalId,omitempty"`
-	Matcher   locations.LocationMatcher `firestore:"matcher"`
-	Type      locations.LocationType    `firestore:"type"`
-	UserID    *string                   `firestore:"userId,omitempty"`
-	CreatedAt time.Time                 `firestore:"createdAt"`
+	Name         string                    `firestore:"name"`
+	Category     string                    `firestore:"category"`
+	Coordinates  *locations.Coordinates    `firestore:"coordinates,omitempty"`
+	Address      *locations.Address        `firestore:"address,omitempty"`
+	RadiusMeters *float64                  `firestore:"radiusMeters,omitempty"`
+	GlobalID     *string                   `firestore:"globalId,omitempty"`
+	Matcher      locations.LocationMatcher `firestore:"matcher"`
+	Type         locations.LocationType    `firestore:"type"`
+	UserID       *string                   `firestore:"userId,omitempty"`
+	Provenance   *sourceDocument           `firestore:"provenance,omitempty"`
+	CreatedAt    time.Time                 `firestore:"createdAt"`
+	// Geohash is set for locations with coordinates so NearbyForMatching can
+	// find them with prefix range queries. It is stored empty for the others,
+	// so ListWithoutCoordinatesForMatching can query for them.
+	Geohash string `firestore:"geohash"`
+	// SearchName is the name's locations.SearchKey, for Search prefix queries.
+	SearchName string `firestore:"searchName"`
 }
 
 // LocationsStore is a concrete implementation of the locations.Store interface using Firestore.
@@ -41,31 +51,44 @@ func NewLocationsStore(client *firestore.Client) *LocationsStore {
 }
 
 func toLocationDocument(loc locations.Location) locationDocument {
-	return locationDocument{
-		Name:      loc.Name,
-		Category:  loc.Category,
-		GlobalID:  loc.GlobalID,
-		Matcher:   loc.Matcher,
-		Type:      loc.Type,
-		UserID:    loc.UserID,
-		CreatedAt: loc.CreatedAt,
+	doc := locationDocument{
+		Name:         loc.Name,
+		Category:     loc.Category,
+		Coordinates:  loc.Coordinates,
+		Address:      loc.Address,
+		RadiusMeters: loc.RadiusMeters,
+		GlobalID:     loc.GlobalID,
+		Matcher:      loc.Matcher,
+		Type:         loc.Type,
+		UserID:       loc.UserID,
+		Provenance:   toSourceDocument(loc.Provenance),
+		CreatedAt:    loc.CreatedAt,
+		SearchName:   locations.SearchKey(loc.Name),
 	}
+	if hash, ok := locations.LocationGeohash(loc); ok {
+		doc.Geohash = hash
+	}
+	return doc
 }
 
 func toLocation(docID uuid.UUID, doc locationDocument) locations.Location {
 	return locations.Location{
-		ID:        docID,
-		Name:      doc.Name,
-		Category:  doc.Category,
-		GlobalID:  doc.GlobalID,
-		Matcher:   doc.Matcher,
-		Type:      doc.Type,
-		UserID:    doc.UserID,
-		CreatedAt: doc.CreatedAt,
+		ID:           docID,
+		Name:         doc.Name,
+		Category:     doc.Category,
+		Coordinates:  doc.Coordinates,
+		Address:      doc.Address,
+		RadiusMeters: doc.RadiusMeters,
+		GlobalID:     doc.GlobalID,
+		Matcher:      doc.Matcher,
+		Type:         doc.Type,
+		UserID:       doc.UserID,
+		Provenance:   toSource(doc.Provenance),
+		CreatedAt:    doc.CreatedAt,
 	}
 }
 
-// Add saves a new location to the store.
+// Add saves a new location to the store, replacing any with the same ID.
 func (s *LocationsStore) Add(ctx context.Context, loc locations.Location) error {
 	doc := s.collection.Doc(loc.ID.String())
 	_, err := doc.Set(ctx, toLocationDocument(loc))
@@ -77,7 +100,7 @@ func (s *LocationsStore) GetByID(ctx context.Context, id uuid.UUID) (locations.L
 	doc, err := s.collection.Doc(id.String()).Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
-			return locations.Location{}, fmt.Errorf("location with ID %s not found", id)
+			return locations.Location{}, apperr.Errorf(apperr.ErrNotFound, "location with ID %s not found", id)
 		}
 		return locations.Location{}, err
 	}
@@ -89,6 +112,58 @@ func (s *LocationsStore) GetByID(ctx context.Context, id uuid.UUID) (locations.L
 	return toLocation(id, ld), nil
 }
 
+// Update replaces a stored location, failing if it does not exist.
+func (s *LocationsStore) Update(ctx context.Context, loc locations.Location) error {
+	ref := s.collection.Doc(loc.ID.String())
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if _, err := tx.Get(ref); err != nil {
+			if status.Code(err) == codes.NotFound {
+				return apperr.Errorf(apperr.ErrNotFound, "location with ID %s not found", loc.ID)
+			}
+			return err
+		}
+		return tx.Set(ref, toLocationDocument(loc))
+	})
+}
+
+// Delete removes a location, failing if it does not exist.
+func (s *LocationsStore) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := s.collection.Doc(id.String()).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return apperr.Errorf(apperr.ErrNotFound, "location with ID %s not found", id)
+	}
+	return err
+}
+
+// Search returns the locations whose names start with namePrefix, ignoring
+// case, in the given category, ordered by name. The name prefix is a range
+// query on searchName and the category is filtered afterwards, so no composite
+// index is needed. Locations saved before searchName was stored are not found
+// by prefix until they are saved again.
+func (s *LocationsStore) Search(ctx context.Context, namePrefix, category string) ([]locations.Location, error) {
+	query := s.collection.Query
+	prefix := locations.SearchKey(namePrefix)
+	switch {
+	case prefix != "":
+		// "\uf8ff" sorts after every other character, closing the prefix range.
+		query = query.Where("searchName", ">=", prefix).Where("searchName", "<", prefix+"\uf8ff")
+	case category != "":
+		query = query.Where("category", "==", category)
+	}
+	results, err := processLocationIterator(query.Documents(ctx))
+	if err != nil {
+		return nil, err
+	}
+	matched := results[:0]
+	for _, loc := range results {
+		if category == "" || loc.Category == category {
+			matched = append(matched, loc)
+		}
+	}
+	locations.SortByName(matched)
+	return matched, nil
+}
+
 // ListByUserID retrieves all locations for a specific user.
 func (s *LocationsStore) ListByUserID(ctx context.Context, userID string) ([]locations.Location, error) {
 	iter := s.collection.Where("userId", "==", userID).Documents(ctx)
@@ -109,7 +184,7 @@ func (s *LocationsStore) FindByGlobalID(ctx context.Context, globalID string) (l
 		return locations.Location{}, err
 	}
 	if len(results) == 0 {
-		return locations.Location{}, fmt.Errorf("location with global ID %s not found", globalID)
+		return locations.Location{}, apperr.Errorf(apperr.ErrNotFound, "location with global ID %s not found", globalID)
 	}
 	return results[0], nil
 }
@@ -120,6 +195,39 @@ func (s *LocationsStore) ListAllForMatching(ctx context.Context) ([]locations.Lo
 	return processLocationIterator(iter)
 }
 
+// NearbyForMatching returns the locations with coordinates within radiusKm of
+// the point. It runs one range query on the geohash field per covering cell,
+// which needs only the single-field index Firestore creates automatically, and
+// then drops the results outside the radius. Locations saved before geohashes
+// were stored are not found until they are saved again.
+func (s *LocationsStore) NearbyForMatching(ctx context.Context, lat, lon, radiusKm float64) ([]locations.Location, error) {
+	seen := make(map[uuid.UUID]bool)
+	var nearby []locations.Location
+	for _, prefix := range locations.GeohashCoverage(lat, lon, radiusKm) {
+		// "~" sorts after every geohash character, closing the prefix range.
+		iter := s.collection.Where("geohash", ">=", prefix).Where("geohash", "<", prefix+"~").Documents(ctx)
+		results, err := processLocationIterator(iter)
+		if err != nil {
+			return nil, err
+		}
+		for _, loc := range results {
+			if !seen[loc.ID] && locations.WithinKm(loc, lat, lon, radiusKm) {
+				seen[loc.ID] = true
+				nearby = append(nearby, loc)
+			}
+		}
+	}
+	return nearby, nil
+}
+
+// ListWithoutCoordinatesForMatching returns the locations that have no
+// coordinates, found by their empty geohash. Like NearbyForMatching, it misses
+// locations saved before geohashes were stored until they are saved again.
+func (s *LocationsStore) ListWithoutCoordinatesForMatching(ctx context.Context) ([]locations.Location, error) {
+	iter := s.collection.Where("geohash", "==", "").Documents(ctx)
+	return processLocationIterator(iter)
+}
+
 // processLocationIterator is a helper to drain results from a Firestore iterator.
 func processLocationIterator(iter *firestore.DocumentIterator) ([]locations.Location, error) {
 	var results []locations.Location
@@ -10,12 +10,13 @@ import (
 	"github.com/google/uuid"
 	fs "github.com/illmade-knight/action-intention/internal/storage/firestore"
 	"github.com/illmade-knight/action-intention/pkg/locations"
+	"github.com/illmade-knight/action-intention/pkg/storetest"
 	"github.com/illmade-knight/go-test/emulators"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func setupLocationsTest(t *testing.T) (context.Context, *firestore.Client, *fs.PeopleStore) {
+func setupLocationsTest(t *testing.T) (context.Context, *firestore.Client, *fs.LocationsStore) {
 	t.Helper()
 	ctx := context.Background()
 	fsConn := emulators.SetupFirestoreEmulator(t, ctx, emulators.GetDefaultFirestoreConfig("test-project"))
@@ -96,4 +97,51 @@ func TestLocationsStore(t *testing.T) {
 		require.NoError(t, err)
 		assert.Len(t, all, 2)
 	})
+
+	// Act & Assert: Coordinates, address and radius survive a round trip
+	t.Run("Details", func(t *testing.T) {
+		radius := 250.0
+		park := locations.Location{
+			ID:           uuid.New(),
+			Name:         "Meadows",
+			Type:         locations.LocationTypeShared,
+			Matcher:      locations.LocationMatcher{Name: "Meadows"},
+			Category:     "Park",
+			Address:      &locations.Address{Locality: "Edinburgh", PostalCode: "EH9 1JY", Country: "UK"},
+			RadiusMeters: &radius,
+		}
+		park.SetCoordinates(locations.Coordinates{Lat: 55.9410, Lon: -3.1920})
+		require.NoError(t, store.Add(ctx, park))
+		defer func() {
+			require.NoError(t, store.Delete(ctx, park.ID))
+		}()
+
+		retrieved, err := store.GetByID(ctx, park.ID)
+		require.NoError(t, err)
+		assert.Equal(t, park.Coordinates, retrieved.Coordinates)
+		assert.Equal(t, park.Address, retrieved.Address)
+		assert.Equal(t, park.RadiusMeters, retrieved.RadiusMeters)
+		assert.Equal(t, park.Matcher.Lat, retrieved.Matcher.Lat)
+	})
+
+}
+
+func TestLocationsStore_Conformance(t *testing.T) {
+	ctx, fsClient, store := setupLocationsTest(t)
+	storetest.RunLocationStoreTests(t, func(t *testing.T) locations.Store {
+		clearCollection(t, ctx, fsClient, "locations")
+		return store
+	})
+}
+
+// clearCollection deletes every document in a collection so a conformance
+// test starts from an empty store.
+func clearCollection(t *testing.T, ctx context.Context, client *firestore.Client, name string) {
+	t.Helper()
+	refs, err := client.Collection(name).DocumentRefs(ctx).GetAll()
+	require.NoError(t, err)
+	for _, ref := range refs {
+		_, err := ref.Delete(ctx)
+		require.NoError(t, err)
+	}
 }
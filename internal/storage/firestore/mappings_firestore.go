@@ -0,0 +1,104 @@
+// Package firestore provides persistent storage implementations using Google Cloud Firestore.
+package firestore
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/reconciliation"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// mappingDocument is the private struct used for Firestore marshalling of
+// identity mappings.
+type mappingDocument struct {
+	SenderID       string    `firestore:"senderId"`
+	SenderEntityID string    `firestore:"senderEntityId"`
+	Kind           string    `firestore:"kind"`
+	LocalID        string    `firestore:"localId"`
+	UpdatedAt      time.Time `firestore:"updatedAt"`
+}
+
+// MappingsStore is a concrete implementation of the reconciliation.MappingStore interface using Firestore.
+type MappingsStore struct {
+	client     *firestore.Client
+	collection *firestore.CollectionRef
+}
+
+// NewMappingsStore creates a new Firestore-backed store for identity mappings.
+func NewMappingsStore(client *firestore.Client) *MappingsStore {
+	return &MappingsStore{
+		client:     client,
+		collection: client.Collection("identityMappings"),
+	}
+}
+
+// mappingDoc returns the document of a sender's entity. The sender ID is part
+// of the document ID, escaped because user IDs may contain slashes.
+func (s *MappingsStore) mappingDoc(senderID string, senderEntityID uuid.UUID) *firestore.DocumentRef {
+	return s.collection.Doc(url.PathEscape(senderID) + ":" + senderEntityID.String())
+}
+
+// GetMapping returns the mapping of a sender's entity.
+func (s *MappingsStore) GetMapping(ctx context.Context, senderID string, senderEntityID uuid.UUID) (reconciliation.IdentityMapping, error) {
+	snap, err := s.mappingDoc(senderID, senderEntityID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return reconciliation.IdentityMapping{}, apperr.Errorf(apperr.ErrNotFound, "no mapping of %s from %s", senderEntityID, senderID)
+		}
+		return reconciliation.IdentityMapping{}, err
+	}
+	var doc mappingDocument
+	if err := snap.DataTo(&doc); err != nil {
+		return reconciliation.IdentityMapping{}, err
+	}
+	localID, err := uuid.Parse(doc.LocalID)
+	if err != nil {
+		return reconciliation.IdentityMapping{}, fmt.Errorf("mapping of %s from %s has an invalid local ID: %w", senderEntityID, senderID, err)
+	}
+	return reconciliation.IdentityMapping{
+		SenderID:       doc.SenderID,
+		SenderEntityID: senderEntityID,
+		Kind:           reconciliation.EntityKind(doc.Kind),
+		LocalID:        localID,
+		UpdatedAt:      doc.UpdatedAt,
+	}, nil
+}
+
+// PutMappings saves mappings in one transaction, replacing any for the same
+// sender entities.
+func (s *MappingsStore) PutMappings(ctx context.Context, mappings []reconciliation.IdentityMapping) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		for _, m := range mappings {
+			err := tx.Set(s.mappingDoc(m.SenderID, m.SenderEntityID), mappingDocument{
+				SenderID:       m.SenderID,
+				SenderEntityID: m.SenderEntityID.String(),
+				Kind:           string(m.Kind),
+				LocalID:        m.LocalID.String(),
+				UpdatedAt:      m.UpdatedAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteMapping removes the mapping of a sender's entity.
+func (s *MappingsStore) DeleteMapping(ctx context.Context, senderID string, senderEntityID uuid.UUID) error {
+	_, err := s.mappingDoc(senderID, senderEntityID).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return apperr.Errorf(apperr.ErrNotFound, "no mapping of %s from %s", senderEntityID, senderID)
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+//go:build integration
+
+package firestore_test
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	fst "github.com/illmade-knight/action-intention/internal/storage/firestore"
+	"github.com/illmade-knight/action-intention/pkg/reconciliation"
+	"github.com/illmade-knight/action-intention/pkg/storetest"
+	"github.com/illmade-knight/go-test/emulators"
+	"github.com/stretchr/testify/require"
+)
+
+func setupMappingsTest(t *testing.T) (context.Context, *firestore.Client, *fst.MappingsStore) {
+	t.Helper()
+	ctx := context.Background()
+	fsConn := emulators.SetupFirestoreEmulator(t, ctx, emulators.GetDefaultFirestoreConfig("test-project"))
+	fsClient, err := firestore.NewClient(ctx, "test-project", fsConn.ClientOptions...)
+	require.NoError(t, err)
+
+	store := fst.NewMappingsStore(fsClient)
+	require.NotNil(t, store)
+
+	t.Cleanup(func() {
+		fsClient.Close()
+	})
+	return ctx, fsClient, store
+}
+
+func TestMappingsStore_Conformance(t *testing.T) {
+	ctx, fsClient, store := setupMappingsTest(t)
+	storetest.RunMappingStoreTests(t, func(t *testing.T) reconciliation.MappingStore {
+		clearCollection(t, ctx, fsClient, "identityMappings")
+		return store
+	})
+}
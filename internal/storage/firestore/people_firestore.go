@@ -8,26 +8,99 @@ import (
 
 	"cloud.google.com/go/firestore"
 	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 	"github.com/illmade-knight/action-intention/pkg/people"
+	"github.com/illmade-knight/action-intention/pkg/provenance"
 	"google.golang.org/api/iterator"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-// personDocument is the private struct for Firestore marshalling.
+// personDocument is the private struct for Firestore marshalling. HandleKeys
+// repeats the matcher's handles as keys so FindByHandle can use array-contains.
 type personDocument struct {
-	Name      string               `firestore:"name"`
-	GlobalID  *string              `firestore:"globalId,omitempty"`
-	Matcher   people.PersonMatcher `firestore:"matcher"`
-	UserID    *string              `firestore:"userId,omitempty"`
-	CreatedAt time.Time            `firestore:"createdAt"`
+	Name       string               `firestore:"name"`
+	GlobalID   *string              `firestore:"globalId,omitempty"`
+	Matcher    people.PersonMatcher `firestore:"matcher"`
+	HandleKeys []string             `firestore:"handleKeys,omitempty"`
+	UserID     *string              `firestore:"userId,omitempty"`
+	Provenance *sourceDocument      `firestore:"provenance,omitempty"`
+	CreatedAt  time.Time            `firestore:"createdAt"`
 }
 
-// groupDocument is the private struct for Firestore marshalling.
+func toPersonDocument(p people.Person) personDocument {
+	var handleKeys []string
+	for _, h := range p.Matcher.Handles {
+		handleKeys = append(handleKeys, h.Key())
+	}
+	return personDocument{
+		Name:       p.Name,
+		GlobalID:   p.GlobalID,
+		Matcher:    p.Matcher,
+		HandleKeys: handleKeys,
+		UserID:     p.UserID,
+		Provenance: toSourceDocument(p.Provenance),
+		CreatedAt:  p.CreatedAt,
+	}
+}
+
+func toPerson(docID uuid.UUID, doc personDocument) people.Person {
+	return people.Person{
+		ID:         docID,
+		Name:       doc.Name,
+		GlobalID:   doc.GlobalID,
+		Matcher:    doc.Matcher,
+		UserID:     doc.UserID,
+		Provenance: toSource(doc.Provenance),
+		CreatedAt:  doc.CreatedAt,
+	}
+}
+
+// groupDocument is the private struct for Firestore marshalling. Member and
+// subgroup IDs are stored as strings so they can be changed with ArrayUnion
+// and ArrayRemove, and queried with array-contains.
 type groupDocument struct {
-	Name      string      `firestore:"name"`
-	MemberIDs []uuid.UUID `firestore:"memberIds"`
-	CreatedAt time.Time   `firestore:"createdAt"`
+	Name        string          `firestore:"name"`
+	MemberIDs   []string        `firestore:"memberIds"`
+	SubgroupIDs []string        `firestore:"subgroupIds"`
+	Provenance  *sourceDocument `firestore:"provenance,omitempty"`
+	CreatedAt   time.Time       `firestore:"createdAt"`
+}
+
+// sourceDocument is the Firestore form of a provenance.Source, shared by
+// locations, people and groups.
+type sourceDocument struct {
+	SenderID       string    `firestore:"senderId"`
+	SenderEntityID string    `firestore:"senderEntityId"`
+	ReceivedAt     time.Time `firestore:"receivedAt"`
+}
+
+func toSourceDocument(src *provenance.Source) *sourceDocument {
+	if src == nil {
+		return nil
+	}
+	return &sourceDocument{
+		SenderID:       src.SenderID,
+		SenderEntityID: src.SenderEntityID.String(),
+		ReceivedAt:     src.ReceivedAt,
+	}
+}
+
+// toSource drops a provenance whose sender entity ID cannot be parsed; the
+// record is then treated as if it had been created locally.
+func toSource(doc *sourceDocument) *provenance.Source {
+	if doc == nil {
+		return nil
+	}
+	senderEntityID, err := uuid.Parse(doc.SenderEntityID)
+	if err != nil {
+		return nil
+	}
+	return &provenance.Source{
+		SenderID:       doc.SenderID,
+		SenderEntityID: senderEntityID,
+		ReceivedAt:     doc.ReceivedAt,
+	}
 }
 
 // LocationsStore is a concrete implementation of the people.Store interface using Firestore.
@@ -50,13 +123,7 @@ func NewPeopleStore(client *firestore.Client) *PeopleStore {
 
 func (s *PeopleStore) AddPerson(ctx context.Context, p people.Person) error {
 	doc := s.peopleCollection.Doc(p.ID.String())
-	_, err := doc.Set(ctx, personDocument{
-		Name:      p.Name,
-		GlobalID:  p.GlobalID,
-		Matcher:   p.Matcher,
-		UserID:    p.UserID,
-		CreatedAt: p.CreatedAt,
-	})
+	_, err := doc.Set(ctx, toPersonDocument(p))
 	return err
 }
 
@@ -64,7 +131,7 @@ func (s *PeopleStore) GetPerson(ctx context.Context, id uuid.UUID) (people.Perso
 	doc, err := s.peopleCollection.Doc(id.String()).Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
-			return people.Person{}, fmt.Errorf("person with ID %s not found", id)
+			return people.Person{}, apperr.Errorf(apperr.ErrNotFound, "person with ID %s not found", id)
 		}
 		return people.Person{}, err
 	}
@@ -72,14 +139,7 @@ func (s *PeopleStore) GetPerson(ctx context.Context, id uuid.UUID) (people.Perso
 	if err := doc.DataTo(&pd); err != nil {
 		return people.Person{}, err
 	}
-	return people.Person{
-		ID:        id,
-		Name:      pd.Name,
-		GlobalID:  pd.GlobalID,
-		Matcher:   pd.Matcher,
-		UserID:    pd.UserID,
-		CreatedAt: pd.CreatedAt,
-	}, nil
+	return toPerson(id, pd), nil
 }
 
 func (s *PeopleStore) FindByGlobalID(ctx context.Context, globalID string) (people.Person, error) {
@@ -89,7 +149,19 @@ func (s *PeopleStore) FindByGlobalID(ctx context.Context, globalID string) (peop
 		return people.Person{}, err
 	}
 	if len(results) == 0 {
-		return people.Person{}, fmt.Errorf("person with global ID %s not found", globalID)
+		return people.Person{}, apperr.Errorf(apperr.ErrNotFound, "person with global ID %s not found", globalID)
+	}
+	return results[0], nil
+}
+
+func (s *PeopleStore) FindByHandle(ctx context.Context, h people.Handle) (people.Person, error) {
+	iter := s.peopleCollection.Where("handleKeys", "array-contains", h.Key()).Limit(1).Documents(ctx)
+	results, err := processPersonIterator(iter)
+	if err != nil {
+		return people.Person{}, err
+	}
+	if len(results) == 0 {
+		return people.Person{}, apperr.Errorf(apperr.ErrNotFound, "person with handle %s not found", h.Key())
 	}
 	return results[0], nil
 }
@@ -104,9 +176,11 @@ func (s *PeopleStore) ListAllForMatching(ctx context.Context) ([]people.Person,
 func (s *PeopleStore) AddGroup(ctx context.Context, g people.Group) error {
 	doc := s.groupsCollection.Doc(g.ID.String())
 	_, err := doc.Set(ctx, groupDocument{
-		Name:      g.Name,
-		MemberIDs: g.MemberIDs,
-		CreatedAt: g.CreatedAt,
+		Name:        g.Name,
+		MemberIDs:   uuidStrings(g.MemberIDs),
+		SubgroupIDs: uuidStrings(g.SubgroupIDs),
+		Provenance:  toSourceDocument(g.Provenance),
+		CreatedAt:   g.CreatedAt,
 	})
 	return err
 }
@@ -115,30 +189,64 @@ func (s *PeopleStore) GetGroup(ctx context.Context, id uuid.UUID) (people.Group,
 	doc, err := s.groupsCollection.Doc(id.String()).Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
-			return people.Group{}, fmt.Errorf("group with ID %s not found", id)
+			return people.Group{}, apperr.Errorf(apperr.ErrNotFound, "group with ID %s not found", id)
 		}
 		return people.Group{}, err
 	}
-	var gd groupDocument
-	if err := doc.DataTo(&gd); err != nil {
-		return people.Group{}, err
+	return toGroup(doc)
+}
+
+func (s *PeopleStore) RenameGroup(ctx context.Context, id uuid.UUID, name string) error {
+	_, err := s.groupsCollection.Doc(id.String()).Update(ctx, []firestore.Update{
+		{Path: "name", Value: name},
+	})
+	if status.Code(err) == codes.NotFound {
+		return apperr.Errorf(apperr.ErrNotFound, "group with ID %s not found", id)
 	}
-	return people.Group{
-		ID:        id,
-		Name:      gd.Name,
-		MemberIDs: gd.MemberIDs,
-		CreatedAt: gd.CreatedAt,
-	}, nil
+	return err
+}
+
+// DeleteGroup removes the group and, in the same transaction, takes it out of
+// every group that nests it.
+func (s *PeopleStore) DeleteGroup(ctx context.Context, id uuid.UUID) error {
+	groupRef := s.groupsCollection.Doc(id.String())
+	parents := s.groupsCollection.Where("subgroupIds", "array-contains", id.String())
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if _, err := tx.Get(groupRef); err != nil {
+			if status.Code(err) == codes.NotFound {
+				return apperr.Errorf(apperr.ErrNotFound, "group with ID %s not found", id)
+			}
+			return err
+		}
+		parentRefs, err := tx.Documents(parents).GetAll()
+		if err != nil {
+			return err
+		}
+		for _, parent := range parentRefs {
+			if err := tx.Update(parent.Ref, []firestore.Update{
+				{Path: "subgroupIds", Value: firestore.ArrayRemove(id.String())},
+			}); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(groupRef)
+	})
 }
 
 func (s *PeopleStore) AddMemberToGroup(ctx context.Context, groupID, personID uuid.UUID) error {
 	groupRef := s.groupsCollection.Doc(groupID.String())
 	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
-		// First, verify the person exists to maintain data integrity.
+		// First, verify the group and person exist to maintain data integrity.
+		if _, err := tx.Get(groupRef); err != nil {
+			if status.Code(err) == codes.NotFound {
+				return apperr.Errorf(apperr.ErrNotFound, "group with ID %s not found", groupID)
+			}
+			return err
+		}
 		_, err := tx.Get(s.peopleCollection.Doc(personID.String()))
 		if err != nil {
 			if status.Code(err) == codes.NotFound {
-				return fmt.Errorf("person with ID %s does not exist", personID)
+				return apperr.Errorf(apperr.ErrNotFound, "person with ID %s not found", personID)
 			}
 			return err
 		}
@@ -149,7 +257,133 @@ func (s *PeopleStore) AddMemberToGroup(ctx context.Context, groupID, personID uu
 	})
 }
 
+func (s *PeopleStore) RemoveMemberFromGroup(ctx context.Context, groupID, personID uuid.UUID) error {
+	_, err := s.groupsCollection.Doc(groupID.String()).Update(ctx, []firestore.Update{
+		{Path: "memberIds", Value: firestore.ArrayRemove(personID.String())},
+	})
+	if status.Code(err) == codes.NotFound {
+		return apperr.Errorf(apperr.ErrNotFound, "group with ID %s not found", groupID)
+	}
+	return err
+}
+
+// AddSubgroup checks for cycles inside the transaction, so two concurrent
+// calls cannot each nest one group in the other.
+func (s *PeopleStore) AddSubgroup(ctx context.Context, groupID, subgroupID uuid.UUID) error {
+	groupRef := s.groupsCollection.Doc(groupID.String())
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		lookup := s.txGroup(tx)
+		g, err := lookup(ctx, groupID)
+		if err != nil {
+			return err
+		}
+		for _, id := range g.SubgroupIDs {
+			if id == subgroupID {
+				return nil
+			}
+		}
+		if err := people.CheckSubgroup(ctx, lookup, groupID, subgroupID); err != nil {
+			return err
+		}
+		return tx.Update(groupRef, []firestore.Update{
+			{Path: "subgroupIds", Value: firestore.ArrayUnion(subgroupID.String())},
+		})
+	})
+}
+
+func (s *PeopleStore) RemoveSubgroup(ctx context.Context, groupID, subgroupID uuid.UUID) error {
+	_, err := s.groupsCollection.Doc(groupID.String()).Update(ctx, []firestore.Update{
+		{Path: "subgroupIds", Value: firestore.ArrayRemove(subgroupID.String())},
+	})
+	if status.Code(err) == codes.NotFound {
+		return apperr.Errorf(apperr.ErrNotFound, "group with ID %s not found", groupID)
+	}
+	return err
+}
+
+func (s *PeopleStore) ListGroupsForPerson(ctx context.Context, personID uuid.UUID) ([]people.Group, error) {
+	iter := s.groupsCollection.Where("memberIds", "array-contains", personID.String()).Documents(ctx)
+	var results []people.Group
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		g, err := toGroup(doc)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, g)
+	}
+	return results, nil
+}
+
+// txGroup returns a people.GroupLookup that reads groups within tx.
+func (s *PeopleStore) txGroup(tx *firestore.Transaction) people.GroupLookup {
+	return func(ctx context.Context, id uuid.UUID) (people.Group, error) {
+		doc, err := tx.Get(s.groupsCollection.Doc(id.String()))
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return people.Group{}, apperr.Errorf(apperr.ErrNotFound, "group with ID %s not found", id)
+			}
+			return people.Group{}, err
+		}
+		return toGroup(doc)
+	}
+}
+
 // --- Helper Functions ---
+
+func toGroup(doc *firestore.DocumentSnapshot) (people.Group, error) {
+	var gd groupDocument
+	if err := doc.DataTo(&gd); err != nil {
+		return people.Group{}, err
+	}
+	id, err := uuid.Parse(doc.Ref.ID)
+	if err != nil {
+		return people.Group{}, err
+	}
+	memberIDs, err := parseUUIDs(gd.MemberIDs)
+	if err != nil {
+		return people.Group{}, fmt.Errorf("group %s has an invalid member ID: %w", id, err)
+	}
+	subgroupIDs, err := parseUUIDs(gd.SubgroupIDs)
+	if err != nil {
+		return people.Group{}, fmt.Errorf("group %s has an invalid subgroup ID: %w", id, err)
+	}
+	return people.Group{
+		ID:          id,
+		Name:        gd.Name,
+		MemberIDs:   memberIDs,
+		SubgroupIDs: subgroupIDs,
+		Provenance:  toSource(gd.Provenance),
+		CreatedAt:   gd.CreatedAt,
+	}, nil
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
+
+func parseUUIDs(ids []string) ([]uuid.UUID, error) {
+	var out []uuid.UUID
+	for _, id := range ids {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", id, err)
+		}
+		out = append(out, parsed)
+	}
+	return out, nil
+}
+
 func processPersonIterator(iter *firestore.DocumentIterator) ([]people.Person, error) {
 	var results []people.Person
 	for {
@@ -169,14 +403,7 @@ func processPersonIterator(iter *firestore.DocumentIterator) ([]people.Person, e
 		if err != nil {
 			return nil, err
 		}
-		results = append(results, people.Person{
-			ID:        docID,
-			Name:      pd.Name,
-			GlobalID:  pd.GlobalID,
-			Matcher:   pd.Matcher,
-			UserID:    pd.UserID,
-			CreatedAt: pd.CreatedAt,
-		})
+		results = append(results, toPerson(docID, pd))
 	}
 	return results, nil
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	fst "github.com/illmade-knight/action-intention/internal/storage/firestore"
 	"github.com/illmade-knight/action-intention/pkg/people"
+	"github.com/illmade-knight/action-intention/pkg/storetest"
 	"github.com/illmade-knight/go-test/emulators"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -104,3 +105,12 @@ func TestPeopleStore(t *testing.T) {
 		assert.Len(t, idempotentGroup.MemberIDs, 1)
 	})
 }
+
+func TestPeopleStore_Conformance(t *testing.T) {
+	ctx, fsClient, store := setupPeopleTest(t)
+	storetest.RunPeopleStoreTests(t, func(t *testing.T) people.Store {
+		clearCollection(t, ctx, fsClient, "people")
+		clearCollection(t, ctx, fsClient, "groups")
+		return store
+	})
+}
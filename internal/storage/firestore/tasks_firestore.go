@@ -0,0 +1,411 @@
+// Package firestore provides persistent storage implementations using Google Cloud Firestore.
+package firestore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/illmade-knight/action-intention/pkg/reconciliation"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// candidateDocument is the stored form of reconciliation.Candidate.
+type candidateDocument struct {
+	LocalID     string  `firestore:"localId"`
+	Name        string  `firestore:"name"`
+	Score       float64 `firestore:"score"`
+	Explanation string  `firestore:"explanation"`
+}
+
+// taskDocument is the private struct used for Firestore marshalling of tasks.
+// The incoming entity is stored in the same form as local locations and people.
+type taskDocument struct {
+	PendingID      string              `firestore:"pendingId"`
+	Kind           string              `firestore:"kind"`
+	SenderID       string              `firestore:"senderId"`
+	SenderEntityID string              `firestore:"senderEntityId"`
+	Location       *locationDocument   `firestore:"location,omitempty"`
+	Person         *personDocument     `firestore:"person,omitempty"`
+	Candidates     []candidateDocument `firestore:"candidates"`
+	Status         string              `firestore:"status"`
+	ResolvedID     *string             `firestore:"resolvedId,omitempty"`
+	CreatedAt      time.Time           `firestore:"createdAt"`
+	ResolvedAt     *time.Time          `firestore:"resolvedAt,omitempty"`
+}
+
+// pendingDocument is the private struct used for Firestore marshalling of
+// pending intentions. The intention is kept as its JSON encoding: it is only
+// ever read back whole, so nothing needs to query its fields.
+type pendingDocument struct {
+	SenderID         string            `firestore:"senderId"`
+	RecipientID      string            `firestore:"recipientId"`
+	Intention        string            `firestore:"intention"`
+	LocationMappings map[string]string `firestore:"locationMappings"`
+	PersonMappings   map[string]string `firestore:"personMappings"`
+	GroupMappings    map[string]string `firestore:"groupMappings,omitempty"`
+	TaskIDs          []string          `firestore:"taskIds"`
+	CreatedAt        time.Time         `firestore:"createdAt"`
+}
+
+// TasksStore is a concrete implementation of the reconciliation.TaskStore interface using Firestore.
+type TasksStore struct {
+	client  *firestore.Client
+	tasks   *firestore.CollectionRef
+	pending *firestore.CollectionRef
+}
+
+// NewTasksStore creates a new Firestore-backed store for reconciliation tasks.
+func NewTasksStore(client *firestore.Client) *TasksStore {
+	return &TasksStore{
+		client:  client,
+		tasks:   client.Collection("reconciliationTasks"),
+		pending: client.Collection("pendingIntentions"),
+	}
+}
+
+func toTaskDocument(task reconciliation.Task) taskDocument {
+	doc := taskDocument{
+		PendingID:      task.PendingID.String(),
+		Kind:           string(task.Kind),
+		SenderID:       task.SenderID,
+		SenderEntityID: task.SenderEntityID.String(),
+		Candidates:     make([]candidateDocument, len(task.Candidates)),
+		Status:         string(task.Status),
+		CreatedAt:      task.CreatedAt,
+		ResolvedAt:     task.ResolvedAt,
+	}
+	if task.Location != nil {
+		loc := toLocationDocument(*task.Location)
+		doc.Location = &loc
+	}
+	if task.Person != nil {
+		person := toPersonDocument(*task.Person)
+		doc.Person = &person
+	}
+	for i, c := range task.Candidates {
+		doc.Candidates[i] = candidateDocument{LocalID: c.LocalID.String(), Name: c.Name, Score: c.Score, Explanation: c.Explanation}
+	}
+	if task.ResolvedID != nil {
+		id := task.ResolvedID.String()
+		doc.ResolvedID = &id
+	}
+	return doc
+}
+
+func toTask(snap *firestore.DocumentSnapshot) (reconciliation.Task, error) {
+	var doc taskDocument
+	if err := snap.DataTo(&doc); err != nil {
+		return reconciliation.Task{}, err
+	}
+	id, err := uuid.Parse(snap.Ref.ID)
+	if err != nil {
+		return reconciliation.Task{}, err
+	}
+	pendingID, err := uuid.Parse(doc.PendingID)
+	if err != nil {
+		return reconciliation.Task{}, err
+	}
+	senderEntityID, err := uuid.Parse(doc.SenderEntityID)
+	if err != nil {
+		return reconciliation.Task{}, err
+	}
+
+	task := reconciliation.Task{
+		ID:             id,
+		PendingID:      pendingID,
+		Kind:           reconciliation.EntityKind(doc.Kind),
+		SenderID:       doc.SenderID,
+		SenderEntityID: senderEntityID,
+		Candidates:     make([]reconciliation.Candidate, len(doc.Candidates)),
+		Status:         reconciliation.TaskStatus(doc.Status),
+		CreatedAt:      doc.CreatedAt,
+		ResolvedAt:     doc.ResolvedAt,
+	}
+	if doc.Location != nil {
+		loc := toLocation(senderEntityID, *doc.Location)
+		task.Location = &loc
+	}
+	if doc.Person != nil {
+		person := toPerson(senderEntityID, *doc.Person)
+		task.Person = &person
+	}
+	for i, c := range doc.Candidates {
+		localID, err := uuid.Parse(c.LocalID)
+		if err != nil {
+			return reconciliation.Task{}, err
+		}
+		task.Candidates[i] = reconciliation.Candidate{LocalID: localID, Name: c.Name, Score: c.Score, Explanation: c.Explanation}
+	}
+	if doc.ResolvedID != nil {
+		resolvedID, err := uuid.Parse(*doc.ResolvedID)
+		if err != nil {
+			return reconciliation.Task{}, err
+		}
+		task.ResolvedID = &resolvedID
+	}
+	return task, nil
+}
+
+func toMappingDocument(mappings map[uuid.UUID]uuid.UUID) map[string]string {
+	doc := make(map[string]string, len(mappings))
+	for k, v := range mappings {
+		doc[k.String()] = v.String()
+	}
+	return doc
+}
+
+func toMappings(doc map[string]string) (map[uuid.UUID]uuid.UUID, error) {
+	mappings := make(map[uuid.UUID]uuid.UUID, len(doc))
+	for k, v := range doc {
+		senderID, err := uuid.Parse(k)
+		if err != nil {
+			return nil, err
+		}
+		localID, err := uuid.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		mappings[senderID] = localID
+	}
+	return mappings, nil
+}
+
+func toPendingDocument(p reconciliation.PendingIntention) (pendingDocument, error) {
+	intent, err := json.Marshal(p.Intention)
+	if err != nil {
+		return pendingDocument{}, fmt.Errorf("failed to encode pending intention: %w", err)
+	}
+	doc := pendingDocument{
+		SenderID:         p.SenderID,
+		RecipientID:      p.RecipientID,
+		Intention:        string(intent),
+		LocationMappings: toMappingDocument(p.Mappings.LocationMappings),
+		PersonMappings:   toMappingDocument(p.Mappings.PersonMappings),
+		GroupMappings:    toMappingDocument(p.Mappings.GroupMappings),
+		TaskIDs:          make([]string, len(p.TaskIDs)),
+		CreatedAt:        p.CreatedAt,
+	}
+	for i, id := range p.TaskIDs {
+		doc.TaskIDs[i] = id.String()
+	}
+	return doc, nil
+}
+
+func toPending(snap *firestore.DocumentSnapshot) (reconciliation.PendingIntention, error) {
+	var doc pendingDocument
+	if err := snap.DataTo(&doc); err != nil {
+		return reconciliation.PendingIntention{}, err
+	}
+	id, err := uuid.Parse(snap.Ref.ID)
+	if err != nil {
+		return reconciliation.PendingIntention{}, err
+	}
+	var intent intentions.Intention
+	if err := json.Unmarshal([]byte(doc.Intention), &intent); err != nil {
+		return reconciliation.PendingIntention{}, fmt.Errorf("failed to decode pending intention: %w", err)
+	}
+	locationMappings, err := toMappings(doc.LocationMappings)
+	if err != nil {
+		return reconciliation.PendingIntention{}, err
+	}
+	personMappings, err := toMappings(doc.PersonMappings)
+	if err != nil {
+		return reconciliation.PendingIntention{}, err
+	}
+	groupMappings, err := toMappings(doc.GroupMappings)
+	if err != nil {
+		return reconciliation.PendingIntention{}, err
+	}
+
+	pending := reconciliation.PendingIntention{
+		ID:          id,
+		SenderID:    doc.SenderID,
+		RecipientID: doc.RecipientID,
+		Intention:   intent,
+		Mappings: reconciliation.MappingResult{
+			LocationMappings: locationMappings,
+			PersonMappings:   personMappings,
+			GroupMappings:    groupMappings,
+		},
+		TaskIDs:   make([]uuid.UUID, len(doc.TaskIDs)),
+		CreatedAt: doc.CreatedAt,
+	}
+	for i, taskID := range doc.TaskIDs {
+		if pending.TaskIDs[i], err = uuid.Parse(taskID); err != nil {
+			return reconciliation.PendingIntention{}, err
+		}
+	}
+	return pending, nil
+}
+
+// AddPending saves a held-back intention together with its tasks in one batch.
+func (s *TasksStore) AddPending(ctx context.Context, pending reconciliation.PendingIntention, tasks []reconciliation.Task) error {
+	doc, err := toPendingDocument(pending)
+	if err != nil {
+		return err
+	}
+	return s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if err := tx.Set(s.pending.Doc(pending.ID.String()), doc); err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			if err := tx.Set(s.tasks.Doc(task.ID.String()), toTaskDocument(task)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetPending retrieves a held-back intention by its ID.
+func (s *TasksStore) GetPending(ctx context.Context, id uuid.UUID) (reconciliation.PendingIntention, error) {
+	snap, err := s.pending.Doc(id.String()).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return reconciliation.PendingIntention{}, apperr.Errorf(apperr.ErrNotFound, "pending intention with ID %s not found", id)
+		}
+		return reconciliation.PendingIntention{}, err
+	}
+	return toPending(snap)
+}
+
+// DeletePending removes a finalized intention. Its tasks are kept.
+func (s *TasksStore) DeletePending(ctx context.Context, id uuid.UUID) error {
+	_, err := s.pending.Doc(id.String()).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return apperr.Errorf(apperr.ErrNotFound, "pending intention with ID %s not found", id)
+	}
+	return err
+}
+
+// GetTask retrieves a task by its ID.
+func (s *TasksStore) GetTask(ctx context.Context, id uuid.UUID) (reconciliation.Task, error) {
+	snap, err := s.tasks.Doc(id.String()).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return reconciliation.Task{}, apperr.Errorf(apperr.ErrNotFound, "reconciliation task with ID %s not found", id)
+		}
+		return reconciliation.Task{}, err
+	}
+	return toTask(snap)
+}
+
+// ListTasks returns tasks with the given status, oldest first. Sorting happens
+// here rather than in the query so no composite index is needed.
+func (s *TasksStore) ListTasks(ctx context.Context, taskStatus reconciliation.TaskStatus) ([]reconciliation.Task, error) {
+	query := s.tasks.Query
+	if taskStatus != "" {
+		query = query.Where("status", "==", string(taskStatus))
+	}
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var results []reconciliation.Task
+	for {
+		snap, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		task, err := toTask(snap)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, task)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if !results[i].CreatedAt.Equal(results[j].CreatedAt) {
+			return results[i].CreatedAt.Before(results[j].CreatedAt)
+		}
+		return results[i].ID.String() < results[j].ID.String()
+	})
+	return results, nil
+}
+
+// ResolveTask marks a pending task resolved and records its mapping on the
+// pending intention. Everything is read and written in one transaction, so of
+// two concurrent resolutions only one can see the intention complete.
+func (s *TasksStore) ResolveTask(ctx context.Context, id uuid.UUID, taskStatus reconciliation.TaskStatus, localID uuid.UUID, resolvedAt time.Time) (reconciliation.PendingIntention, bool, error) {
+	var pending reconciliation.PendingIntention
+	var complete bool
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		taskRef := s.tasks.Doc(id.String())
+		snap, err := tx.Get(taskRef)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return apperr.Errorf(apperr.ErrNotFound, "reconciliation task with ID %s not found", id)
+			}
+			return err
+		}
+		task, err := toTask(snap)
+		if err != nil {
+			return err
+		}
+		if task.Status != reconciliation.TaskPending {
+			return fmt.Errorf("task %s is %s: %w", id, task.Status, reconciliation.ErrTaskResolved)
+		}
+
+		pendingRef := s.pending.Doc(task.PendingID.String())
+		pendingSnap, err := tx.Get(pendingRef)
+		if err != nil {
+			if status.Code(err) == codes.NotFound {
+				return apperr.Errorf(apperr.ErrNotFound, "pending intention with ID %s not found", task.PendingID)
+			}
+			return err
+		}
+		if pending, err = toPending(pendingSnap); err != nil {
+			return err
+		}
+
+		// All reads must happen before the first write in a transaction.
+		var siblingRefs []*firestore.DocumentRef
+		for _, taskID := range pending.TaskIDs {
+			if taskID != id {
+				siblingRefs = append(siblingRefs, s.tasks.Doc(taskID.String()))
+			}
+		}
+		complete = true
+		var siblings []*firestore.DocumentSnapshot
+		if len(siblingRefs) > 0 {
+			if siblings, err = tx.GetAll(siblingRefs); err != nil {
+				return err
+			}
+		}
+		for _, sibling := range siblings {
+			if sibling.Exists() {
+				if value, err := sibling.DataAt("status"); err == nil && value == string(reconciliation.TaskPending) {
+					complete = false
+				}
+			}
+		}
+
+		task.Status = taskStatus
+		task.ResolvedID = &localID
+		task.ResolvedAt = &resolvedAt
+		if err := tx.Set(taskRef, toTaskDocument(task)); err != nil {
+			return err
+		}
+
+		pending.Mappings.Record(task.Kind, task.SenderEntityID, localID)
+		doc, err := toPendingDocument(pending)
+		if err != nil {
+			return err
+		}
+		return tx.Set(pendingRef, doc)
+	})
+	if err != nil {
+		return reconciliation.PendingIntention{}, false, err
+	}
+	return pending, complete, nil
+}
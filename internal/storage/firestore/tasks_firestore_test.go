@@ -0,0 +1,145 @@
+//go:build integration
+
+package firestore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	fst "github.com/illmade-knight/action-intention/internal/storage/firestore"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/illmade-knight/action-intention/pkg/locations"
+	"github.com/illmade-knight/action-intention/pkg/people"
+	"github.com/illmade-knight/action-intention/pkg/reconciliation"
+	"github.com/illmade-knight/go-test/emulators"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTasksTest(t *testing.T) (context.Context, *firestore.Client, *fst.TasksStore) {
+	t.Helper()
+	ctx := context.Background()
+	fsConn := emulators.SetupFirestoreEmulator(t, ctx, emulators.GetDefaultFirestoreConfig("test-project"))
+	fsClient, err := firestore.NewClient(ctx, "test-project", fsConn.ClientOptions...)
+	require.NoError(t, err)
+
+	store := fst.NewTasksStore(fsClient)
+	require.NotNil(t, store)
+
+	t.Cleanup(func() {
+		fsClient.Close()
+	})
+	return ctx, fsClient, store
+}
+
+func TestTasksStore(t *testing.T) {
+	ctx, _, store := setupTasksTest(t)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	// Arrange: A pending intention waiting on a location task and a person task
+	senderLocID, senderPersonID, mappedLocID := uuid.New(), uuid.New(), uuid.New()
+	intent := intentions.Intention{
+		ID:     uuid.New(),
+		User:   "user-alice",
+		Action: "Meet at the cafe",
+		Targets: []intentions.Target{
+			intentions.LocationTarget{LocationID: senderLocID},
+			intentions.ProximityTarget{PersonIDs: []uuid.UUID{senderPersonID}},
+		},
+		StartTime: now,
+		EndTime:   now.Add(time.Hour),
+		Status:    intentions.StatusPlanned,
+		Version:   1,
+	}
+	pending := reconciliation.PendingIntention{
+		ID:          uuid.New(),
+		SenderID:    "user-alice",
+		RecipientID: "user-bob",
+		Intention:   intent,
+		Mappings: reconciliation.MappingResult{
+			LocationMappings: map[uuid.UUID]uuid.UUID{uuid.New(): mappedLocID},
+			PersonMappings:   map[uuid.UUID]uuid.UUID{},
+		},
+		CreatedAt: now,
+	}
+	locCandidate, personCandidate := uuid.New(), uuid.New()
+	locTask := reconciliation.Task{
+		ID:             uuid.New(),
+		PendingID:      pending.ID,
+		Kind:           reconciliation.EntityLocation,
+		SenderID:       "user-alice",
+		SenderEntityID: senderLocID,
+		Location:       &locations.Location{ID: senderLocID, Name: "Corner Cafe", Category: "Cafe", Matcher: locations.LocationMatcher{Name: "Corner Cafe", Category: "Cafe"}, Type: locations.LocationTypeShared},
+		Candidates:     []reconciliation.Candidate{{LocalID: locCandidate, Name: "Corner Cafe", Score: 0.6, Explanation: "name matches, category differs"}},
+		Status:         reconciliation.TaskPending,
+		CreatedAt:      now,
+	}
+	personTask := reconciliation.Task{
+		ID:             uuid.New(),
+		PendingID:      pending.ID,
+		Kind:           reconciliation.EntityPerson,
+		SenderID:       "user-alice",
+		SenderEntityID: senderPersonID,
+		Person:         &people.Person{ID: senderPersonID, Name: "Sam", Matcher: people.PersonMatcher{Name: "Sam"}},
+		Candidates:     []reconciliation.Candidate{{LocalID: personCandidate, Name: "Sam", Score: 0.6, Explanation: "name matches"}},
+		Status:         reconciliation.TaskPending,
+		CreatedAt:      now.Add(time.Second),
+	}
+	pending.TaskIDs = []uuid.UUID{locTask.ID, personTask.ID}
+
+	// Act & Assert: AddPending and read back
+	require.NoError(t, store.AddPending(ctx, pending, []reconciliation.Task{locTask, personTask}))
+
+	storedPending, err := store.GetPending(ctx, pending.ID)
+	require.NoError(t, err)
+	assert.Equal(t, intent.Targets, storedPending.Intention.Targets)
+	assert.Equal(t, pending.TaskIDs, storedPending.TaskIDs)
+	assert.Equal(t, pending.Mappings.LocationMappings, storedPending.Mappings.LocationMappings)
+
+	storedTask, err := store.GetTask(ctx, locTask.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Corner Cafe", storedTask.Location.Name)
+	assert.Equal(t, senderLocID, storedTask.Location.ID)
+	assert.Equal(t, locTask.Candidates, storedTask.Candidates)
+
+	listed, err := store.ListTasks(ctx, reconciliation.TaskPending)
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+	assert.Equal(t, locTask.ID, listed[0].ID, "tasks are listed oldest first")
+
+	// Act & Assert: Resolving the first task leaves the intention incomplete
+	resolved, complete, err := store.ResolveTask(ctx, locTask.ID, reconciliation.TaskConfirmed, locCandidate, now)
+	require.NoError(t, err)
+	assert.False(t, complete)
+	assert.Equal(t, locCandidate, resolved.Mappings.LocationMappings[senderLocID])
+
+	_, _, err = store.ResolveTask(ctx, locTask.ID, reconciliation.TaskConfirmed, locCandidate, now)
+	assert.ErrorIs(t, err, reconciliation.ErrTaskResolved)
+
+	// Act & Assert: Resolving the last task completes it
+	resolved, complete, err = store.ResolveTask(ctx, personTask.ID, reconciliation.TaskMerged, personCandidate, now)
+	require.NoError(t, err)
+	assert.True(t, complete)
+	assert.Equal(t, personCandidate, resolved.Mappings.PersonMappings[senderPersonID])
+	assert.Equal(t, locCandidate, resolved.Mappings.LocationMappings[senderLocID])
+
+	listed, err = store.ListTasks(ctx, reconciliation.TaskPending)
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+	storedTask, err = store.GetTask(ctx, personTask.ID)
+	require.NoError(t, err)
+	assert.Equal(t, reconciliation.TaskMerged, storedTask.Status)
+	require.NotNil(t, storedTask.ResolvedID)
+	assert.Equal(t, personCandidate, *storedTask.ResolvedID)
+
+	// Act & Assert: DeletePending keeps the task history
+	require.NoError(t, store.DeletePending(ctx, pending.ID))
+	_, err = store.GetPending(ctx, pending.ID)
+	assert.Error(t, err)
+	all, err := store.ListTasks(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
@@ -0,0 +1,51 @@
+// FILE: pkg/apperr/apperr.go
+
+// Package apperr defines the error categories shared by every package, so
+// callers can tell missing data from a rejected request or an outage with
+// errors.Is, whichever store or client the error came from.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound means the requested record does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means the request clashes with the current state, such as a
+	// stale version or a task that has already been resolved.
+	ErrConflict = errors.New("conflict")
+	// ErrInvalid means the request itself is malformed and retrying it will not help.
+	ErrInvalid = errors.New("invalid")
+	// ErrUnauthorized means the caller is not allowed to do what was asked,
+	// or could not prove who they are.
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// kindError carries a category alongside an error without adding the
+// category's text to the message.
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// New returns an error with the given message that matches kind with errors.Is.
+// It is meant for package-level sentinels that refine a category.
+func New(kind error, message string) error {
+	return &kindError{kind: kind, err: errors.New(message)}
+}
+
+// Errorf formats an error as fmt.Errorf does, including any %w wrapping, and
+// makes it match kind with errors.Is.
+func Errorf(kind error, format string, args ...any) error {
+	return &kindError{kind: kind, err: fmt.Errorf(format, args...)}
+}
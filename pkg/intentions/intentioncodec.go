@@ -0,0 +1,240 @@
+// FILE: pkg/intentions/intentioncodec.go
+
+package intentions
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+)
+
+// TargetRecord is the tagged, serializable form of a Target. The Type field
+// selects the codec used to interpret Data, which lets a []Target slice
+// survive a round trip through JSON or a document database.
+type TargetRecord struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// TargetRefs lists the entities a target refers to by ID. It is used to
+// hydrate a target with its related data, for example when gathering the
+// sub-graph for a SharedPayload.
+type TargetRefs struct {
+	LocationIDs  []uuid.UUID
+	PersonIDs    []uuid.UUID
+	GroupIDs     []uuid.UUID
+	IntentionIDs []uuid.UUID
+}
+
+// TargetCodec holds the hooks for a single kind of Target. Encode and Decode
+// are required; Validate, References and Rewrite are optional.
+type TargetCodec struct {
+	// Encode converts the target to its JSON form.
+	Encode func(Target) (json.RawMessage, error)
+	// Decode rebuilds the concrete target from its JSON form.
+	Decode func(json.RawMessage) (Target, error)
+	// Validate reports whether the target's fields are usable.
+	Validate func(Target) error
+	// References reports the entities the target points at, so callers can
+	// hydrate it without switching on concrete types.
+	References func(Target) TargetRefs
+	// Rewrite returns the target pointing at the entities in refs instead,
+	// which are listed in the same order References reported them. It lets
+	// callers translate IDs, for example into a recipient's local records.
+	Rewrite func(Target, TargetRefs) Target
+}
+
+var (
+	targetRegistryMu sync.RWMutex
+	targetRegistry   = make(map[string]TargetCodec)
+)
+
+// RegisterTarget makes a Target kind known to the codec. The kind must match
+// the value returned by the target's Type method. Registering the same kind
+// twice replaces the earlier codec.
+func RegisterTarget(kind string, codec TargetCodec) {
+	if kind == "" || codec.Encode == nil || codec.Decode == nil {
+		panic("intentions: RegisterTarget requires a kind, an encoder and a decoder")
+	}
+	targetRegistryMu.Lock()
+	defer targetRegistryMu.Unlock()
+	targetRegistry[kind] = codec
+}
+
+// JSONTargetCodec builds a TargetCodec for a concrete Target type whose fields
+// can be handled directly by encoding/json.
+func JSONTargetCodec[T Target]() TargetCodec {
+	return TargetCodec{
+		Encode: func(t Target) (json.RawMessage, error) {
+			return json.Marshal(t)
+		},
+		Decode: func(data json.RawMessage) (Target, error) {
+			var t T
+			if err := json.Unmarshal(data, &t); err != nil {
+				return nil, err
+			}
+			return t, nil
+		},
+	}
+}
+
+func lookupTargetCodec(kind string) (TargetCodec, bool) {
+	targetRegistryMu.RLock()
+	defer targetRegistryMu.RUnlock()
+	codec, ok := targetRegistry[kind]
+	return codec, ok
+}
+
+// RegisteredTargetTypes returns the kinds currently known to the registry, sorted.
+func RegisteredTargetTypes() []string {
+	targetRegistryMu.RLock()
+	defer targetRegistryMu.RUnlock()
+	kinds := make([]string, 0, len(targetRegistry))
+	for kind := range targetRegistry {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// ValidateTarget checks that the target's kind is registered and, if the kind
+// has a Validate hook, that its fields are usable.
+func ValidateTarget(target Target) error {
+	if target == nil {
+		return apperr.Errorf(apperr.ErrInvalid, "target cannot be nil")
+	}
+	codec, ok := lookupTargetCodec(target.Type())
+	if !ok {
+		return apperr.Errorf(apperr.ErrInvalid, "unknown target type: %s", target.Type())
+	}
+	if codec.Validate == nil {
+		return nil
+	}
+	if err := codec.Validate(target); err != nil {
+		return apperr.Errorf(apperr.ErrInvalid, "invalid %s target: %w", target.Type(), err)
+	}
+	return nil
+}
+
+// TargetReferences returns the entities the target refers to. Unregistered
+// kinds and kinds without a References hook refer to nothing.
+func TargetReferences(target Target) TargetRefs {
+	if target == nil {
+		return TargetRefs{}
+	}
+	codec, ok := lookupTargetCodec(target.Type())
+	if !ok || codec.References == nil {
+		return TargetRefs{}
+	}
+	return codec.References(target)
+}
+
+// RewriteTargetReferences returns the target pointing at the entities in refs,
+// which must be listed as TargetReferences reported them. It reports false,
+// and returns the target unchanged, if the kind has no Rewrite hook.
+func RewriteTargetReferences(target Target, refs TargetRefs) (Target, bool) {
+	if target == nil {
+		return nil, false
+	}
+	codec, ok := lookupTargetCodec(target.Type())
+	if !ok || codec.Rewrite == nil {
+		return target, false
+	}
+	return codec.Rewrite(target, refs), true
+}
+
+// EncodeTarget converts a Target into its tagged record form.
+func EncodeTarget(target Target) (TargetRecord, error) {
+	if target == nil {
+		return TargetRecord{}, apperr.Errorf(apperr.ErrInvalid, "cannot encode nil target")
+	}
+	codec, ok := lookupTargetCodec(target.Type())
+	if !ok {
+		return TargetRecord{}, apperr.Errorf(apperr.ErrInvalid, "unknown target type: %s", target.Type())
+	}
+	data, err := codec.Encode(target)
+	if err != nil {
+		return TargetRecord{}, fmt.Errorf("failed to encode %s target: %w", target.Type(), err)
+	}
+	return TargetRecord{Type: target.Type(), Data: data}, nil
+}
+
+// DecodeTarget rebuilds a concrete Target from its tagged record form.
+func DecodeTarget(record TargetRecord) (Target, error) {
+	codec, ok := lookupTargetCodec(record.Type)
+	if !ok {
+		return nil, apperr.Errorf(apperr.ErrInvalid, "unknown target type: %s", record.Type)
+	}
+	target, err := codec.Decode(record.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s target: %w", record.Type, err)
+	}
+	return target, nil
+}
+
+// EncodeTargets converts a slice of targets, preserving order.
+func EncodeTargets(targets []Target) ([]TargetRecord, error) {
+	records := make([]TargetRecord, len(targets))
+	for i, target := range targets {
+		record, err := EncodeTarget(target)
+		if err != nil {
+			return nil, err
+		}
+		records[i] = record
+	}
+	return records, nil
+}
+
+// DecodeTargets converts a slice of records back into targets, preserving order.
+func DecodeTargets(records []TargetRecord) ([]Target, error) {
+	targets := make([]Target, len(records))
+	for i, record := range records {
+		target, err := DecodeTarget(record)
+		if err != nil {
+			return nil, err
+		}
+		targets[i] = target
+	}
+	return targets, nil
+}
+
+// intentionJSON is an alias that drops Intention's JSON methods so the default
+// encoding can be reused for every field except Targets.
+type intentionJSON Intention
+
+// MarshalJSON encodes the intention with its targets as tagged records.
+func (i Intention) MarshalJSON() ([]byte, error) {
+	records, err := EncodeTargets(i.Targets)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		intentionJSON
+		Targets []TargetRecord `json:"targets"`
+	}{
+		intentionJSON: intentionJSON(i),
+		Targets:       records,
+	})
+}
+
+// UnmarshalJSON decodes an intention, rebuilding each target through the registry.
+func (i *Intention) UnmarshalJSON(data []byte) error {
+	var aux struct {
+		intentionJSON
+		Targets []TargetRecord `json:"targets"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	targets, err := DecodeTargets(aux.Targets)
+	if err != nil {
+		return err
+	}
+	*i = Intention(aux.intentionJSON)
+	i.Targets = targets
+	return nil
+}
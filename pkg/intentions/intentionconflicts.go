@@ -0,0 +1,231 @@
+// FILE: pkg/intentions/intentionconflicts.go
+
+package intentions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+)
+
+// LocationDistancer reports the distance in kilometres between two locations.
+// locations.Service satisfies this interface.
+type LocationDistancer interface {
+	DistanceBetween(ctx context.Context, a, b uuid.UUID) (float64, error)
+}
+
+// ConflictPolicy configures the opt-in conflict check run when intentions are
+// added or updated.
+type ConflictPolicy struct {
+	// Locations enables the travel check. Without it only overlaps are reported.
+	Locations LocationDistancer
+	// TravelSpeedKmh is the assumed speed between locations; zero means 30 km/h.
+	TravelSpeedKmh float64
+	// TravelWindow is how far apart two intentions may be and still be checked
+	// for travel time; zero means 6 hours.
+	TravelWindow time.Duration
+	// Horizon limits how far ahead an endlessly recurring intention is checked;
+	// zero means 90 days.
+	Horizon time.Duration
+}
+
+func (p ConflictPolicy) travelSpeed() float64 {
+	if p.TravelSpeedKmh <= 0 {
+		return 30
+	}
+	return p.TravelSpeedKmh
+}
+
+func (p ConflictPolicy) travelWindow() time.Duration {
+	if p.TravelWindow <= 0 {
+		return 6 * time.Hour
+	}
+	return p.TravelWindow
+}
+
+func (p ConflictPolicy) horizon() time.Duration {
+	if p.Horizon <= 0 {
+		return 90 * 24 * time.Hour
+	}
+	return p.Horizon
+}
+
+// ConflictKind describes why two intentions clash.
+type ConflictKind string
+
+const (
+	// ConflictOverlap means the same person is in both intentions at the same time.
+	ConflictOverlap ConflictKind = "overlap"
+	// ConflictTravel means there is not enough time to get between the two locations.
+	ConflictTravel ConflictKind = "travel"
+)
+
+// Conflict is a single clash with an existing intention.
+type Conflict struct {
+	IntentionID uuid.UUID
+	Kind        ConflictKind
+	// Person is the user or participant who would be in both places.
+	Person string
+	// At is the start of the clashing occurrence of the existing intention.
+	At time.Time
+}
+
+// ConflictError is returned when an intention clashes with existing ones.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	parts := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		parts[i] = fmt.Sprintf("%s with %s for %s", c.Kind, c.IntentionID, c.Person)
+	}
+	return "intention conflicts with existing intentions: " + strings.Join(parts, "; ")
+}
+
+// Unwrap makes a ConflictError match apperr.ErrConflict.
+func (e *ConflictError) Unwrap() error {
+	return apperr.ErrConflict
+}
+
+// IntentionIDs returns the distinct IDs of the clashing intentions.
+func (e *ConflictError) IntentionIDs() []uuid.UUID {
+	seen := make(map[uuid.UUID]bool)
+	var ids []uuid.UUID
+	for _, c := range e.Conflicts {
+		if !seen[c.IntentionID] {
+			seen[c.IntentionID] = true
+			ids = append(ids, c.IntentionID)
+		}
+	}
+	return ids
+}
+
+// SetConflictPolicy turns on the conflict check for AddIntention,
+// AddRecurringIntention and UpdateIntention. Passing nil turns it off.
+func (s *IntentionService) SetConflictPolicy(policy *ConflictPolicy) {
+	s.conflicts = policy
+}
+
+// checkConflicts runs the configured conflict check, if any.
+func (s *IntentionService) checkConflicts(ctx context.Context, intent Intention) error {
+	if s.conflicts == nil {
+		return nil
+	}
+	conflicts, err := s.FindConflicts(ctx, intent, *s.conflicts)
+	if err != nil {
+		return fmt.Errorf("failed to check for conflicts: %w", err)
+	}
+	if len(conflicts) > 0 {
+		return &ConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// FindConflicts lists the existing intentions that clash with intent for its
+// user or any of its participants. Cancelled intentions and intent itself are
+// ignored.
+func (s *IntentionService) FindConflicts(ctx context.Context, intent Intention, policy ConflictPolicy) ([]Conflict, error) {
+	from := intent.StartTime
+	to, bounded := intent.SeriesEnd()
+	if !bounded || to.Sub(from) > policy.horizon() {
+		to = from.Add(policy.horizon())
+	}
+	candidates := intent.Occurrences(&from, &to)
+
+	people := append([]string{intent.User}, intent.Participants...)
+	seenPerson := make(map[string]bool)
+	var conflicts []Conflict
+	for _, person := range people {
+		if seenPerson[person] {
+			continue
+		}
+		seenPerson[person] = true
+
+		existing, err := s.intentionsInvolving(ctx, person, from.Add(-policy.travelWindow()), to.Add(policy.travelWindow()))
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range existing {
+			if other.ID == intent.ID || other.Status == StatusCancelled {
+				continue
+			}
+			for _, candidate := range candidates {
+				if kind, clash := clashBetween(ctx, candidate, other, policy); clash {
+					conflicts = append(conflicts, Conflict{IntentionID: other.ID, Kind: kind, Person: person, At: other.StartTime})
+					break
+				}
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// intentionsInvolving returns occurrences in the range where person is the
+// owner or a participant.
+func (s *IntentionService) intentionsInvolving(ctx context.Context, person string, from, to time.Time) ([]Intention, error) {
+	owned, err := s.store.Query(ctx, QuerySpec{User: &person, From: &from, To: &to})
+	if err != nil {
+		return nil, err
+	}
+	joined, err := s.store.Query(ctx, QuerySpec{Participant: &person, From: &from, To: &to})
+	if err != nil {
+		return nil, err
+	}
+
+	type occurrenceKey struct {
+		id    uuid.UUID
+		start time.Time
+	}
+	seen := make(map[occurrenceKey]bool)
+	var results []Intention
+	for _, intent := range append(owned, joined...) {
+		key := occurrenceKey{intent.ID, intent.StartTime.UTC()}
+		if !seen[key] {
+			seen[key] = true
+			results = append(results, intent)
+		}
+	}
+	return results, nil
+}
+
+// clashBetween reports whether two concrete occurrences clash. Back-to-back
+// intentions do not overlap; they clash only if the gap is too short to travel
+// from the earlier intention's last location to the later one's first.
+func clashBetween(ctx context.Context, a, b Intention, policy ConflictPolicy) (ConflictKind, bool) {
+	if a.StartTime.Before(b.EndTime) && b.StartTime.Before(a.EndTime) {
+		return ConflictOverlap, true
+	}
+	if policy.Locations == nil {
+		return "", false
+	}
+
+	earlier, later := a, b
+	if b.EndTime.Compare(a.StartTime) <= 0 {
+		earlier, later = b, a
+	}
+	gap := later.StartTime.Sub(earlier.EndTime)
+	if gap > policy.travelWindow() {
+		return "", false
+	}
+	fromIDs := IntentionReferences(earlier).LocationIDs
+	toIDs := IntentionReferences(later).LocationIDs
+	if len(fromIDs) == 0 || len(toIDs) == 0 {
+		return "", false
+	}
+
+	distance, err := policy.Locations.DistanceBetween(ctx, fromIDs[len(fromIDs)-1], toIDs[0])
+	if err != nil {
+		// Locations without coordinates cannot be checked; that is not a clash.
+		return "", false
+	}
+	needed := time.Duration(distance / policy.travelSpeed() * float64(time.Hour))
+	if gap < needed {
+		return ConflictTravel, true
+	}
+	return "", false
+}
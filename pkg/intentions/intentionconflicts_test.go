@@ -0,0 +1,167 @@
+package intentions_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubDistancer knows the distance between fixed pairs of locations; any other
+// pair has no coordinates.
+type stubDistancer map[[2]uuid.UUID]float64
+
+func (d stubDistancer) DistanceBetween(ctx context.Context, a, b uuid.UUID) (float64, error) {
+	if km, ok := d[[2]uuid.UUID{a, b}]; ok {
+		return km, nil
+	}
+	if km, ok := d[[2]uuid.UUID{b, a}]; ok {
+		return km, nil
+	}
+	return 0, apperr.Errorf(apperr.ErrInvalid, "no coordinates")
+}
+
+func TestIntentionService_Conflicts(t *testing.T) {
+	ctx := context.Background()
+	ten := time.Date(2025, time.January, 1, 10, 0, 0, 0, time.UTC)
+	park, cafe, shop := uuid.New(), uuid.New(), uuid.New()
+	at := func(id uuid.UUID) []intentions.Target {
+		return []intentions.Target{intentions.LocationTarget{LocationID: id}}
+	}
+	online := []intentions.Target{intentions.OnlineTarget{Platform: "video"}}
+	policy := &intentions.ConflictPolicy{Locations: stubDistancer{{park, cafe}: 20, {park, shop}: 2}}
+
+	newService := func(t *testing.T) *intentions.IntentionService {
+		t.Helper()
+		svc := intentions.NewIntentionService(intentions.NewInMemoryStore())
+		svc.SetConflictPolicy(policy)
+		return svc
+	}
+	conflictError := func(t *testing.T, err error) *intentions.ConflictError {
+		t.Helper()
+		require.ErrorIs(t, err, apperr.ErrConflict)
+		var conflictErr *intentions.ConflictError
+		require.True(t, errors.As(err, &conflictErr))
+		return conflictErr
+	}
+
+	t.Run("Overlap for the same user", func(t *testing.T) {
+		svc := newService(t)
+		existing, err := svc.AddIntention(ctx, "user-alice", "Run", at(park), ten, ten.Add(time.Hour))
+		require.NoError(t, err)
+
+		_, err = svc.AddIntention(ctx, "user-alice", "Call", online, ten.Add(30*time.Minute), ten.Add(90*time.Minute))
+
+		conflictErr := conflictError(t, err)
+		require.Len(t, conflictErr.Conflicts, 1)
+		assert.Equal(t, intentions.Conflict{IntentionID: existing.ID, Kind: intentions.ConflictOverlap, Person: "user-alice", At: existing.StartTime}, conflictErr.Conflicts[0])
+	})
+
+	t.Run("Overlap for a participant", func(t *testing.T) {
+		svc := newService(t)
+		existing, err := svc.AddIntention(ctx, "user-bob", "Run", at(park), ten, ten.Add(time.Hour))
+		require.NoError(t, err)
+		candidate := intentions.Intention{ID: uuid.New(), User: "user-alice", Participants: []string{"user-bob"}, Action: "Call", Targets: online, StartTime: ten.Add(30 * time.Minute), EndTime: ten.Add(90 * time.Minute)}
+
+		conflicts, err := svc.FindConflicts(ctx, candidate, *policy)
+
+		require.NoError(t, err)
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, existing.ID, conflicts[0].IntentionID)
+		assert.Equal(t, "user-bob", conflicts[0].Person)
+	})
+
+	t.Run("Back-to-back intentions do not clash", func(t *testing.T) {
+		svc := newService(t)
+		_, err := svc.AddIntention(ctx, "user-alice", "Run", at(park), ten, ten.Add(time.Hour))
+		require.NoError(t, err)
+
+		_, err = svc.AddIntention(ctx, "user-alice", "Call", online, ten.Add(time.Hour), ten.Add(2*time.Hour))
+		assert.NoError(t, err, "an online intention needs no travel")
+		_, err = svc.AddIntention(ctx, "user-alice", "Read", at(uuid.New()), ten.Add(-time.Hour), ten)
+		assert.NoError(t, err, "a location without coordinates cannot be checked")
+	})
+
+	t.Run("Not enough time to travel", func(t *testing.T) {
+		svc := newService(t)
+		run, err := svc.AddIntention(ctx, "user-alice", "Run", at(park), ten, ten.Add(time.Hour))
+		require.NoError(t, err)
+
+		// 20 km at the default 30 km/h takes 40 minutes.
+		_, err = svc.AddIntention(ctx, "user-alice", "Coffee", at(cafe), ten.Add(70*time.Minute), ten.Add(2*time.Hour))
+		conflictErr := conflictError(t, err)
+		require.Len(t, conflictErr.Conflicts, 1)
+		assert.Equal(t, intentions.ConflictTravel, conflictErr.Conflicts[0].Kind)
+		assert.Equal(t, run.ID, conflictErr.Conflicts[0].IntentionID)
+
+		_, err = svc.AddIntention(ctx, "user-alice", "Coffee", at(cafe), ten.Add(2*time.Hour), ten.Add(3*time.Hour))
+		assert.NoError(t, err, "an hour is enough time")
+		_, err = svc.AddIntention(ctx, "user-alice", "Shopping", at(shop), ten.Add(-40*time.Minute), ten.Add(-10*time.Minute))
+		assert.NoError(t, err, "2 km takes four minutes")
+	})
+
+	t.Run("Cancelled intentions are ignored", func(t *testing.T) {
+		svc := newService(t)
+		existing, err := svc.AddIntention(ctx, "user-alice", "Run", at(park), ten, ten.Add(time.Hour))
+		require.NoError(t, err)
+		require.NoError(t, svc.CancelIntention(ctx, existing.ID))
+
+		_, err = svc.AddIntention(ctx, "user-alice", "Call", online, ten, ten.Add(time.Hour))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Every occurrence of a recurring candidate is checked", func(t *testing.T) {
+		svc := newService(t)
+		existing, err := svc.AddIntention(ctx, "user-alice", "Dentist", online, ten.AddDate(0, 0, 14).Add(30*time.Minute), ten.AddDate(0, 0, 14).Add(time.Hour))
+		require.NoError(t, err)
+
+		_, err = svc.AddRecurringIntention(ctx, "user-alice", "Run", at(park), ten, ten.Add(time.Hour), intentions.Recurrence{Frequency: intentions.FrequencyWeekly, Count: 4})
+
+		conflictErr := conflictError(t, err)
+		require.Len(t, conflictErr.Conflicts, 1)
+		assert.Equal(t, existing.ID, conflictErr.Conflicts[0].IntentionID)
+		assert.Equal(t, existing.StartTime, conflictErr.Conflicts[0].At)
+	})
+
+	t.Run("An update does not clash with itself", func(t *testing.T) {
+		svc := newService(t)
+		run, err := svc.AddIntention(ctx, "user-alice", "Run", at(park), ten, ten.Add(time.Hour))
+		require.NoError(t, err)
+
+		run.EndTime = ten.Add(90 * time.Minute)
+		_, err = svc.UpdateIntention(ctx, run)
+		assert.NoError(t, err)
+	})
+
+	t.Run("No check without a policy", func(t *testing.T) {
+		svc := newService(t)
+		svc.SetConflictPolicy(nil)
+		_, err := svc.AddIntention(ctx, "user-alice", "Run", at(park), ten, ten.Add(time.Hour))
+		require.NoError(t, err)
+
+		_, err = svc.AddIntention(ctx, "user-alice", "Call", online, ten, ten.Add(time.Hour))
+		assert.NoError(t, err)
+	})
+
+	t.Run("IntentionIDs lists each clashing intention once", func(t *testing.T) {
+		svc := newService(t)
+		existing := intentions.Intention{ID: uuid.New(), User: "user-alice", Participants: []string{"user-bob"}, Action: "Climb", Targets: online, StartTime: ten, EndTime: ten.Add(time.Hour)}
+		require.NoError(t, svc.GetStore().Add(ctx, existing))
+
+		candidate := intentions.Intention{ID: uuid.New(), User: "user-alice", Participants: []string{"user-bob", "user-alice"}, Action: "Call", Targets: online, StartTime: ten, EndTime: ten.Add(time.Hour)}
+		conflicts, err := svc.FindConflicts(ctx, candidate, *policy)
+		require.NoError(t, err)
+
+		conflictErr := &intentions.ConflictError{Conflicts: conflicts}
+		assert.Len(t, conflictErr.Conflicts, 2, "one conflict for each person")
+		assert.Equal(t, []uuid.UUID{existing.ID}, conflictErr.IntentionIDs())
+		assert.ErrorIs(t, conflictErr, apperr.ErrConflict)
+		assert.Contains(t, conflictErr.Error(), "overlap with "+existing.ID.String()+" for user-bob")
+	})
+}
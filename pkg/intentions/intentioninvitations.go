@@ -0,0 +1,107 @@
+// FILE: pkg/intentions/intentioninvitations.go
+
+package intentions
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+)
+
+// RSVP is a participant's reply to an invitation.
+type RSVP string
+
+const (
+	RSVPPending   RSVP = "pending"
+	RSVPAccepted  RSVP = "accepted"
+	RSVPDeclined  RSVP = "declined"
+	RSVPTentative RSVP = "tentative"
+)
+
+// Valid reports whether the RSVP is a reply a participant can send.
+func (r RSVP) Valid() bool {
+	return r == RSVPAccepted || r == RSVPDeclined || r == RSVPTentative
+}
+
+// Invitation records that the owner invited a participant and how they replied.
+type Invitation struct {
+	Participant string     `json:"participant"`
+	RSVP        RSVP       `json:"rsvp"`
+	InvitedAt   time.Time  `json:"invited_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+}
+
+// Invitation returns the invitation for a participant, if there is one.
+func (i Intention) Invitation(participant string) (Invitation, bool) {
+	for _, inv := range i.Invitations {
+		if inv.Participant == participant {
+			return inv, true
+		}
+	}
+	return Invitation{}, false
+}
+
+// InviteParticipants adds participants to an intention with a pending RSVP.
+// Participants who are already invited keep their current reply.
+func (s *IntentionService) InviteParticipants(ctx context.Context, id uuid.UUID, participants ...string) (Intention, error) {
+	intent, err := s.store.Get(ctx, id)
+	if err != nil {
+		return Intention{}, err
+	}
+
+	// The store may share these slices with the stored intention, so they are
+	// copied before changing them.
+	intent.Invitations = slices.Clone(intent.Invitations)
+	intent.Participants = slices.Clone(intent.Participants)
+	now := time.Now()
+	for _, participant := range participants {
+		if participant == "" {
+			return Intention{}, apperr.Errorf(apperr.ErrInvalid, "participant cannot be empty")
+		}
+		if participant == intent.User {
+			return Intention{}, apperr.Errorf(apperr.ErrInvalid, "the owner cannot be invited to their own intention")
+		}
+		if _, ok := intent.Invitation(participant); ok {
+			continue
+		}
+		intent.Invitations = append(intent.Invitations, Invitation{Participant: participant, RSVP: RSVPPending, InvitedAt: now})
+		if !slices.Contains(intent.Participants, participant) {
+			intent.Participants = append(intent.Participants, participant)
+		}
+	}
+
+	if err := s.store.Update(ctx, intent); err != nil {
+		return Intention{}, fmt.Errorf("failed to save invitations: %w", err)
+	}
+	intent.Version++
+	return intent, nil
+}
+
+// RecordRSVP stores a participant's reply to their invitation.
+func (s *IntentionService) RecordRSVP(ctx context.Context, id uuid.UUID, participant string, rsvp RSVP, respondedAt time.Time) (Intention, error) {
+	if !rsvp.Valid() {
+		return Intention{}, apperr.Errorf(apperr.ErrInvalid, "invalid RSVP: %q", rsvp)
+	}
+	intent, err := s.store.Get(ctx, id)
+	if err != nil {
+		return Intention{}, err
+	}
+
+	idx := slices.IndexFunc(intent.Invitations, func(inv Invitation) bool { return inv.Participant == participant })
+	if idx < 0 {
+		return Intention{}, apperr.Errorf(apperr.ErrInvalid, "%s was not invited to intention %s", participant, id)
+	}
+	intent.Invitations = slices.Clone(intent.Invitations)
+	intent.Invitations[idx].RSVP = rsvp
+	intent.Invitations[idx].RespondedAt = &respondedAt
+
+	if err := s.store.Update(ctx, intent); err != nil {
+		return Intention{}, fmt.Errorf("failed to save RSVP: %w", err)
+	}
+	intent.Version++
+	return intent, nil
+}
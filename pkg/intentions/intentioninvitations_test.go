@@ -0,0 +1,108 @@
+package intentions_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntentionService_RecordRSVP(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	target := []intentions.Target{intentions.OnlineTarget{Platform: "video"}}
+
+	t.Run("Records a reply", func(t *testing.T) {
+		svc := intentions.NewIntentionService(intentions.NewInMemoryStore())
+		intent, err := svc.AddIntention(ctx, "user-alice", "Picnic", target, now, now.Add(time.Hour))
+		require.NoError(t, err)
+		_, err = svc.InviteParticipants(ctx, intent.ID, "user-bob")
+		require.NoError(t, err)
+
+		_, err = svc.RecordRSVP(ctx, intent.ID, "user-bob", intentions.RSVPPending, now)
+		assert.ErrorIs(t, err, apperr.ErrInvalid, "pending is not a reply")
+		_, err = svc.RecordRSVP(ctx, intent.ID, "user-carol", intentions.RSVPAccepted, now)
+		assert.ErrorIs(t, err, apperr.ErrInvalid, "only invited participants can reply")
+
+		updated, err := svc.RecordRSVP(ctx, intent.ID, "user-bob", intentions.RSVPDeclined, now)
+		require.NoError(t, err)
+		invitation, ok := updated.Invitation("user-bob")
+		require.True(t, ok)
+		assert.Equal(t, intentions.RSVPDeclined, invitation.RSVP)
+		stored, err := svc.GetIntention(ctx, intent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, updated, stored)
+	})
+
+	t.Run("A conflicting reply leaves the stored invitation alone", func(t *testing.T) {
+		inner := intentions.NewInMemoryStore()
+		store := &racingStore{Store: inner}
+		svc := intentions.NewIntentionService(store)
+		intent, err := svc.AddIntention(ctx, "user-alice", "Picnic", target, now, now.Add(time.Hour))
+		require.NoError(t, err)
+		_, err = svc.InviteParticipants(ctx, intent.ID, "user-bob")
+		require.NoError(t, err)
+		store.race = func(read intentions.Intention) {
+			read.Action = "Changed elsewhere"
+			require.NoError(t, inner.Update(ctx, read))
+		}
+
+		_, err = svc.RecordRSVP(ctx, intent.ID, "user-bob", intentions.RSVPAccepted, now)
+		require.ErrorIs(t, err, intentions.ErrVersionConflict)
+		_, err = svc.InviteParticipants(ctx, intent.ID, "user-carol")
+		require.ErrorIs(t, err, intentions.ErrVersionConflict)
+
+		stored, err := inner.Get(ctx, intent.ID)
+		require.NoError(t, err)
+		invitation, ok := stored.Invitation("user-bob")
+		require.True(t, ok)
+		assert.Equal(t, intentions.RSVPPending, invitation.RSVP)
+		assert.Len(t, stored.Invitations, 1)
+		assert.Equal(t, []string{"user-bob"}, stored.Participants)
+	})
+
+	t.Run("Concurrent replies are all recorded", func(t *testing.T) {
+		svc := intentions.NewIntentionService(intentions.NewInMemoryStore())
+		intent, err := svc.AddIntention(ctx, "user-alice", "Picnic", target, now, now.Add(time.Hour))
+		require.NoError(t, err)
+		const guests = 8
+		participants := make([]string, guests)
+		for i := range participants {
+			participants[i] = fmt.Sprintf("user-%d", i)
+		}
+		_, err = svc.InviteParticipants(ctx, intent.ID, participants...)
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for _, participant := range participants {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					_, err := svc.RecordRSVP(ctx, intent.ID, participant, intentions.RSVPAccepted, now)
+					if !errors.Is(err, intentions.ErrVersionConflict) {
+						assert.NoError(t, err)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		stored, err := svc.GetIntention(ctx, intent.ID)
+		require.NoError(t, err)
+		for _, participant := range participants {
+			invitation, ok := stored.Invitation(participant)
+			require.True(t, ok)
+			assert.Equal(t, intentions.RSVPAccepted, invitation.RSVP, participant)
+		}
+		assert.Equal(t, 2+guests, stored.Version)
+	})
+}
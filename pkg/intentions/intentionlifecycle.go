@@ -0,0 +1,103 @@
+// FILE: pkg/intentions/intentionlifecycle.go
+
+package intentions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+)
+
+// Status is the lifecycle state of an intention.
+type Status string
+
+const (
+	StatusPlanned    Status = "planned"     // Tentative; the default for new intentions.
+	StatusConfirmed  Status = "confirmed"   // Committed to.
+	StatusInProgress Status = "in_progress" // Happening now.
+	StatusDone       Status = "done"        // Happened.
+	StatusCancelled  Status = "cancelled"   // Will not happen.
+)
+
+// statusTransitions lists the states each state may move to. Done and
+// cancelled are final.
+var statusTransitions = map[Status][]Status{
+	StatusPlanned:    {StatusConfirmed, StatusInProgress, StatusCancelled},
+	StatusConfirmed:  {StatusPlanned, StatusInProgress, StatusCancelled},
+	StatusInProgress: {StatusDone, StatusCancelled},
+}
+
+// CanTransition reports whether an intention may move from one status to
+// another. An empty status is treated as planned.
+func CanTransition(from, to Status) bool {
+	if from == "" {
+		from = StatusPlanned
+	}
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTransition returns an apperr.ErrConflict error if the intention may not
+// move from one status to another.
+func checkTransition(id uuid.UUID, from, to Status) error {
+	if !CanTransition(from, to) {
+		return apperr.Errorf(apperr.ErrConflict, "cannot change intention %s from %s to %s", id, from, to)
+	}
+	return nil
+}
+
+// applyTransition sets the status and records the time of the transition.
+func (i *Intention) applyTransition(to Status, at time.Time) {
+	i.Status = to
+	switch to {
+	case StatusConfirmed:
+		i.ConfirmedAt = &at
+	case StatusInProgress:
+		i.StartedAt = &at
+	case StatusDone:
+		i.CompletedAt = &at
+	case StatusCancelled:
+		i.CancelledAt = &at
+	}
+}
+
+// TransitionIntention moves an intention to a new status, enforcing the allowed
+// transitions, and records when the change happened.
+func (s *IntentionService) TransitionIntention(ctx context.Context, id uuid.UUID, to Status) (Intention, error) {
+	intent, err := s.store.Get(ctx, id)
+	if err != nil {
+		return Intention{}, err
+	}
+	if err := checkTransition(id, intent.Status, to); err != nil {
+		return Intention{}, err
+	}
+
+	intent.applyTransition(to, time.Now())
+	if err := s.store.Update(ctx, intent); err != nil {
+		return Intention{}, fmt.Errorf("failed to update intention status: %w", err)
+	}
+	intent.Version++
+	return intent, nil
+}
+
+// ConfirmIntention marks a planned intention as committed to.
+func (s *IntentionService) ConfirmIntention(ctx context.Context, id uuid.UUID) (Intention, error) {
+	return s.TransitionIntention(ctx, id, StatusConfirmed)
+}
+
+// StartIntention marks an intention as happening now.
+func (s *IntentionService) StartIntention(ctx context.Context, id uuid.UUID) (Intention, error) {
+	return s.TransitionIntention(ctx, id, StatusInProgress)
+}
+
+// CompleteIntention records that an intention happened.
+func (s *IntentionService) CompleteIntention(ctx context.Context, id uuid.UUID) (Intention, error) {
+	return s.TransitionIntention(ctx, id, StatusDone)
+}
@@ -0,0 +1,172 @@
+package intentions_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanTransition(t *testing.T) {
+	statuses := []intentions.Status{
+		intentions.StatusPlanned,
+		intentions.StatusConfirmed,
+		intentions.StatusInProgress,
+		intentions.StatusDone,
+		intentions.StatusCancelled,
+	}
+	allowed := map[intentions.Status][]intentions.Status{
+		"":                          {intentions.StatusConfirmed, intentions.StatusInProgress, intentions.StatusCancelled},
+		intentions.StatusPlanned:    {intentions.StatusConfirmed, intentions.StatusInProgress, intentions.StatusCancelled},
+		intentions.StatusConfirmed:  {intentions.StatusPlanned, intentions.StatusInProgress, intentions.StatusCancelled},
+		intentions.StatusInProgress: {intentions.StatusDone, intentions.StatusCancelled},
+		intentions.StatusDone:       nil,
+		intentions.StatusCancelled:  nil,
+	}
+
+	for from, next := range allowed {
+		for _, to := range statuses {
+			want := false
+			for _, n := range next {
+				want = want || n == to
+			}
+			t.Run(string(from)+" to "+string(to), func(t *testing.T) {
+				assert.Equal(t, want, intentions.CanTransition(from, to))
+			})
+		}
+	}
+}
+
+// racingStore simulates another writer changing an intention between the
+// service reading and updating it.
+type racingStore struct {
+	intentions.Store
+	race func(intentions.Intention)
+}
+
+func (s racingStore) Get(ctx context.Context, id uuid.UUID) (intentions.Intention, error) {
+	intent, err := s.Store.Get(ctx, id)
+	if err == nil && s.race != nil {
+		s.race(intent)
+	}
+	return intent, err
+}
+
+func TestIntentionService_TransitionIntention(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	target := []intentions.Target{intentions.OnlineTarget{Platform: "video"}}
+
+	testCases := []struct {
+		name  string
+		path  []intentions.Status
+		check func(t *testing.T, intent intentions.Intention)
+	}{
+		{
+			name: "confirm",
+			path: []intentions.Status{intentions.StatusConfirmed},
+			check: func(t *testing.T, intent intentions.Intention) {
+				assert.NotNil(t, intent.ConfirmedAt)
+				assert.Nil(t, intent.StartedAt)
+			},
+		},
+		{
+			name: "confirm, start and complete",
+			path: []intentions.Status{intentions.StatusConfirmed, intentions.StatusInProgress, intentions.StatusDone},
+			check: func(t *testing.T, intent intentions.Intention) {
+				require.NotNil(t, intent.ConfirmedAt)
+				require.NotNil(t, intent.StartedAt)
+				require.NotNil(t, intent.CompletedAt)
+				assert.False(t, intent.StartedAt.Before(*intent.ConfirmedAt))
+				assert.False(t, intent.CompletedAt.Before(*intent.StartedAt))
+				assert.Nil(t, intent.CancelledAt)
+			},
+		},
+		{
+			name: "back to planned keeps the confirmation time",
+			path: []intentions.Status{intentions.StatusConfirmed, intentions.StatusPlanned},
+			check: func(t *testing.T, intent intentions.Intention) {
+				assert.NotNil(t, intent.ConfirmedAt)
+			},
+		},
+		{
+			name: "cancel while in progress",
+			path: []intentions.Status{intentions.StatusInProgress, intentions.StatusCancelled},
+			check: func(t *testing.T, intent intentions.Intention) {
+				assert.NotNil(t, intent.StartedAt)
+				assert.NotNil(t, intent.CancelledAt)
+				assert.Nil(t, intent.CompletedAt)
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := intentions.NewIntentionService(intentions.NewInMemoryStore())
+			intent, err := svc.AddIntention(ctx, "user-alice", "Run", target, now, now.Add(time.Hour))
+			require.NoError(t, err)
+
+			for i, to := range tc.path {
+				intent, err = svc.TransitionIntention(ctx, intent.ID, to)
+				require.NoError(t, err)
+				assert.Equal(t, to, intent.Status)
+				assert.Equal(t, i+2, intent.Version, "each transition bumps the version")
+			}
+
+			stored, err := svc.GetIntention(ctx, intent.ID)
+			require.NoError(t, err)
+			assert.Equal(t, intent, stored, "the returned intention is what was stored")
+			tc.check(t, stored)
+		})
+	}
+
+	t.Run("Disallowed transitions are conflicts", func(t *testing.T) {
+		svc := intentions.NewIntentionService(intentions.NewInMemoryStore())
+		intent, err := svc.AddIntention(ctx, "user-alice", "Run", target, now, now.Add(time.Hour))
+		require.NoError(t, err)
+
+		_, err = svc.CompleteIntention(ctx, intent.ID)
+		assert.ErrorIs(t, err, apperr.ErrConflict, "planned cannot go straight to done")
+
+		_, err = svc.StartIntention(ctx, intent.ID)
+		require.NoError(t, err)
+		_, err = svc.CompleteIntention(ctx, intent.ID)
+		require.NoError(t, err)
+		for _, to := range []intentions.Status{intentions.StatusPlanned, intentions.StatusConfirmed, intentions.StatusInProgress, intentions.StatusCancelled} {
+			_, err = svc.TransitionIntention(ctx, intent.ID, to)
+			assert.ErrorIs(t, err, apperr.ErrConflict, "done is final")
+		}
+		assert.ErrorIs(t, svc.CancelIntention(ctx, intent.ID), apperr.ErrConflict)
+
+		stored, err := svc.GetIntention(ctx, intent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, intentions.StatusDone, stored.Status)
+		assert.Equal(t, 3, stored.Version, "refused transitions are not stored")
+
+		_, err = svc.ConfirmIntention(ctx, uuid.New())
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+	})
+
+	t.Run("A concurrent change is a version conflict", func(t *testing.T) {
+		inner := intentions.NewInMemoryStore()
+		store := &racingStore{Store: inner}
+		svc := intentions.NewIntentionService(store)
+		intent, err := svc.AddIntention(ctx, "user-alice", "Run", target, now, now.Add(time.Hour))
+		require.NoError(t, err)
+		store.race = func(read intentions.Intention) {
+			read.Action = "Changed elsewhere"
+			require.NoError(t, inner.Update(ctx, read))
+		}
+
+		_, err = svc.ConfirmIntention(ctx, intent.ID)
+
+		assert.ErrorIs(t, err, intentions.ErrVersionConflict)
+		stored, err := inner.Get(ctx, intent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, intentions.StatusPlanned, stored.Status)
+	})
+}
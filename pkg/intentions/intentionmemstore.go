@@ -4,9 +4,12 @@ package intentions
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 )
 
 // InMemoryStore is a thread-safe, in-memory implementation of the Store interface.
@@ -24,6 +27,11 @@ func NewInMemoryStore() *InMemoryStore {
 
 // Add saves a new intention to the store.
 func (s *InMemoryStore) Add(ctx context.Context, intent Intention) error {
+	// Reject targets a persistent store could not encode, so the two behave alike.
+	if _, err := EncodeTargets(intent.Targets); err != nil {
+		return err
+	}
+
 	s.Lock()
 	defer s.Unlock()
 
@@ -32,29 +40,108 @@ func (s *InMemoryStore) Add(ctx context.Context, intent Intention) error {
 	return nil
 }
 
-// Query retrieves intentions based on the provided specification.
-func (s *InMemoryStore) Query(ctx context.Context, spec QuerySpec) ([]Intention, error) {
+// Get retrieves a single intention by its ID.
+func (s *InMemoryStore) Get(ctx context.Context, id uuid.UUID) (Intention, error) {
 	s.RLock()
 	defer s.RUnlock()
+	intent, ok := s.intentions[id]
+	if !ok {
+		return Intention{}, apperr.Errorf(apperr.ErrNotFound, "intention with ID %s not found", id)
+	}
+	return intent, nil
+}
 
-	var results []Intention
+// Update replaces a stored intention if its version matches the stored one.
+func (s *InMemoryStore) Update(ctx context.Context, intent Intention) error {
+	if _, err := EncodeTargets(intent.Targets); err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	existing, ok := s.intentions[intent.ID]
+	if !ok {
+		return apperr.Errorf(apperr.ErrNotFound, "intention with ID %s not found", intent.ID)
+	}
+	if existing.Version != intent.Version {
+		return fmt.Errorf("update intention %s at version %d (stored %d): %w", intent.ID, intent.Version, existing.Version, ErrVersionConflict)
+	}
+	intent.Version++
+	s.intentions[intent.ID] = intent
+	return nil
+}
+
+// Cancel marks an intention as cancelled without removing it.
+func (s *InMemoryStore) Cancel(ctx context.Context, id uuid.UUID) error {
+	s.Lock()
+	defer s.Unlock()
+
+	intent, ok := s.intentions[id]
+	if !ok {
+		return apperr.Errorf(apperr.ErrNotFound, "intention with ID %s not found", id)
+	}
+	if err := checkTransition(id, intent.Status, StatusCancelled); err != nil {
+		return err
+	}
+	intent.applyTransition(StatusCancelled, time.Now())
+	intent.Version++
+	s.intentions[id] = intent
+	return nil
+}
+
+// Delete permanently removes an intention.
+func (s *InMemoryStore) Delete(ctx context.Context, id uuid.UUID) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.intentions[id]; !ok {
+		return apperr.Errorf(apperr.ErrNotFound, "intention with ID %s not found", id)
+	}
+	delete(s.intentions, id)
+	return nil
+}
 
+// FindByProvenance retrieves the local copy of another user's intention.
+func (s *InMemoryStore) FindByProvenance(ctx context.Context, senderID string, senderIntentionID uuid.UUID) (Intention, error) {
+	s.RLock()
+	defer s.RUnlock()
 	for _, intent := range s.intentions {
-		// If a user filter is specified, and it doesn't match, skip.
-		if spec.User != nil && intent.User != *spec.User {
-			continue
+		if intent.Provenance.Is(senderID, senderIntentionID) {
+			return intent, nil
 		}
+	}
+	return Intention{}, apperr.Errorf(apperr.ErrNotFound, "no copy of intention %s from %s", senderIntentionID, senderID)
+}
 
-		// If an active-at filter is specified, and the intention is not active at that time, skip.
-		if spec.ActiveAt != nil {
-			t := *spec.ActiveAt
-			if t.Before(intent.StartTime) || t.After(intent.EndTime) {
+// Query retrieves intentions based on the provided specification, ordered by start time.
+// Recurring intentions are expanded into occurrences when the spec has a time range.
+func (s *InMemoryStore) Query(ctx context.Context, spec QuerySpec) ([]Intention, error) {
+	var cursor *QueryCursor
+	if spec.After != "" {
+		c, err := DecodeCursor(spec.After)
+		if err != nil {
+			return nil, err
+		}
+		cursor = &c
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	var results []Intention
+	for _, intent := range s.intentions {
+		for _, match := range spec.Expand(intent) {
+			if cursor != nil && cursor.Precedes(match) {
 				continue
 			}
+			results = append(results, match)
 		}
-
-		results = append(results, intent)
 	}
 
+	SortIntentions(results)
+	if spec.Limit > 0 && len(results) > spec.Limit {
+		results = results[:spec.Limit]
+	}
 	return results, nil
 }
@@ -0,0 +1,14 @@
+package intentions_test
+
+import (
+	"testing"
+
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/illmade-knight/action-intention/pkg/storetest"
+)
+
+func TestInMemoryStore_Conformance(t *testing.T) {
+	storetest.RunIntentionStoreTests(t, func(t *testing.T) intentions.Store {
+		return intentions.NewInMemoryStore()
+	})
+}
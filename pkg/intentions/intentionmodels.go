@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/provenance"
 )
 
 // Target represents the object or destination of an intention.
@@ -24,7 +25,7 @@ type Target interface {
 // LocationTarget represents a physical place by referencing its unique ID.
 // The full location details are managed by the locations package.
 type LocationTarget struct {
-	LocationID uuid.UUID
+	LocationID uuid.UUID `json:"location_id"`
 }
 
 func (l LocationTarget) Type() string {
@@ -39,8 +40,8 @@ func (l LocationTarget) Description() string {
 
 // ProximityTarget now represents being with specific people or groups by ID.
 type ProximityTarget struct {
-	PersonIDs []uuid.UUID
-	GroupIDs  []uuid.UUID
+	PersonIDs []uuid.UUID `json:"person_ids,omitempty"`
+	GroupIDs  []uuid.UUID `json:"group_ids,omitempty"`
 }
 
 func (p ProximityTarget) Type() string {
@@ -51,16 +52,98 @@ func (p ProximityTarget) Description() string {
 	return fmt.Sprintf("People: %d, Groups: %d", len(p.PersonIDs), len(p.GroupIDs))
 }
 
+// OnlineTarget represents an online activity, such as a video call or a game,
+// identified by the platform it happens on and an optional link.
+type OnlineTarget struct {
+	Platform string `json:"platform"`
+	URL      string `json:"url,omitempty"`
+}
+
+func (o OnlineTarget) Type() string {
+	return "Online"
+}
+
+func (o OnlineTarget) Description() string {
+	if o.URL == "" {
+		return fmt.Sprintf("Platform: %s", o.Platform)
+	}
+	return fmt.Sprintf("Platform: %s, URL: %s", o.Platform, o.URL)
+}
+
+// RouteTarget represents travelling through an ordered list of locations by ID.
+// The first entry is the start of the route and the last is its destination.
+type RouteTarget struct {
+	LocationIDs []uuid.UUID `json:"location_ids"`
+}
+
+func (r RouteTarget) Type() string {
+	return "Route"
+}
+
+func (r RouteTarget) Description() string {
+	return fmt.Sprintf("Stops: %d", len(r.LocationIDs))
+}
+
+// EventTarget represents taking part in something described by another intention,
+// for example joining a friend's planned match.
+type EventTarget struct {
+	IntentionID uuid.UUID `json:"intention_id"`
+}
+
+func (e EventTarget) Type() string {
+	return "Event"
+}
+
+func (e EventTarget) Description() string {
+	return fmt.Sprintf("Intention ID: %s", e.IntentionID)
+}
+
+// TimeWindowTarget represents a span of time the action should fit into,
+// for example "a run some time on Saturday morning", when no place or people
+// are involved. The intention's own StartTime and EndTime say when it is
+// planned; the window says when it could happen.
+type TimeWindowTarget struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+func (w TimeWindowTarget) Type() string {
+	return "TimeWindow"
+}
+
+func (w TimeWindowTarget) Description() string {
+	return fmt.Sprintf("From %s to %s", w.Start.Format(time.RFC3339), w.End.Format(time.RFC3339))
+}
+
 // --- Main Intention Struct ---
 
 // Intention holds the complete details of a user's plan.
+// Targets are encoded as tagged records; see MarshalJSON in intentioncodec.go.
 type Intention struct {
-	ID           uuid.UUID
-	User         string
-	Participants []string
-	Action       string
-	Targets      []Target // Changed from "Target Target" to "Targets []Target"
-	StartTime    time.Time
-	EndTime      time.Time
-	CreatedAt    time.Time
+	ID           uuid.UUID `json:"id"`
+	User         string    `json:"user"`
+	Participants []string  `json:"participants,omitempty"`
+	Action       string    `json:"action"`
+	Targets      []Target  `json:"targets"` // Changed from "Target Target" to "Targets []Target"
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	CreatedAt    time.Time `json:"created_at"`
+	// Invitations tracks the RSVP of each invited participant.
+	Invitations []Invitation `json:"invitations,omitempty"`
+	// Recurrence makes the intention repeat; StartTime and EndTime are then the
+	// first occurrence. Queries with a time range return concrete occurrences.
+	Recurrence *Recurrence `json:"recurrence,omitempty"`
+	// Status is the intention's lifecycle state; see intentionlifecycle.go.
+	// Each transition records when it happened in the matching timestamp.
+	Status      Status     `json:"status,omitempty"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+	// Version is incremented by the store on every change and used for
+	// optimistic concurrency in Update.
+	Version int `json:"version,omitempty"`
+	// Provenance links a received copy to the sender's original intention, so
+	// a later share of it updates the copy instead of adding another.
+	Provenance *provenance.Source `json:"provenance,omitempty"`
 }
@@ -0,0 +1,148 @@
+// FILE: pkg/intentions/intentionquery.go
+
+package intentions
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+)
+
+// QueryCursor marks a position in the start-time ordering used by Query.
+type QueryCursor struct {
+	StartTime time.Time `json:"s"`
+	EndTime   time.Time `json:"e"`
+	ID        uuid.UUID `json:"i"`
+}
+
+// CursorAfter returns an opaque cursor that makes a query continue after intent.
+// Pass the last intention of a page to fetch the next one.
+func CursorAfter(intent Intention) string {
+	data, _ := json.Marshal(QueryCursor{StartTime: intent.StartTime, EndTime: intent.EndTime, ID: intent.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor produced by CursorAfter.
+func DecodeCursor(cursor string) (QueryCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return QueryCursor{}, apperr.Errorf(apperr.ErrInvalid, "invalid query cursor: %w", err)
+	}
+	var c QueryCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return QueryCursor{}, apperr.Errorf(apperr.ErrInvalid, "invalid query cursor: %w", err)
+	}
+	return c, nil
+}
+
+// Precedes reports whether intent comes before or at the cursor position, and
+// so should be skipped when paginating.
+func (c QueryCursor) Precedes(intent Intention) bool {
+	return compareIntentions(intent, Intention{StartTime: c.StartTime, EndTime: c.EndTime, ID: c.ID}) <= 0
+}
+
+// SortIntentions orders intentions the way Query returns them.
+func SortIntentions(results []Intention) {
+	slices.SortFunc(results, compareIntentions)
+}
+
+func compareIntentions(a, b Intention) int {
+	if c := a.StartTime.Compare(b.StartTime); c != 0 {
+		return c
+	}
+	if c := a.EndTime.Compare(b.EndTime); c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID.String(), b.ID.String())
+}
+
+// TimeBounds folds ActiveAt, From and To into a single overlap range. A nil
+// bound leaves that end of the range open.
+func (q QuerySpec) TimeBounds() (from, to *time.Time) {
+	from, to = q.From, q.To
+	if q.ActiveAt != nil {
+		if from == nil || q.ActiveAt.After(*from) {
+			from = q.ActiveAt
+		}
+		if to == nil || q.ActiveAt.Before(*to) {
+			to = q.ActiveAt
+		}
+	}
+	return from, to
+}
+
+// Matches reports whether the intention satisfies every filter in the spec.
+// Limit and After are not filters and are ignored.
+func (q QuerySpec) Matches(intent Intention) bool {
+	if q.User != nil && intent.User != *q.User {
+		return false
+	}
+	if q.Participant != nil && !slices.Contains(intent.Participants, *q.Participant) {
+		return false
+	}
+
+	from, to := q.TimeBounds()
+	if from != nil && intent.EndTime.Before(*from) {
+		return false
+	}
+	if to != nil && intent.StartTime.After(*to) {
+		return false
+	}
+
+	if q.ActionContains != nil && !strings.Contains(strings.ToLower(intent.Action), strings.ToLower(*q.ActionContains)) {
+		return false
+	}
+
+	if q.LocationID != nil || q.PersonID != nil || q.GroupID != nil {
+		refs := IntentionReferences(intent)
+		if q.LocationID != nil && !slices.Contains(refs.LocationIDs, *q.LocationID) {
+			return false
+		}
+		if q.PersonID != nil && !slices.Contains(refs.PersonIDs, *q.PersonID) {
+			return false
+		}
+		if q.GroupID != nil && !slices.Contains(refs.GroupIDs, *q.GroupID) {
+			return false
+		}
+	}
+	return true
+}
+
+// Expand returns the intentions that satisfy the spec for a stored intention.
+// When the spec has a time range, a recurring intention is expanded into its
+// concrete occurrences within that range; otherwise it is matched as a series.
+func (q QuerySpec) Expand(intent Intention) []Intention {
+	from, to := q.TimeBounds()
+	if intent.Recurrence == nil || (from == nil && to == nil) {
+		if q.Matches(intent) {
+			return []Intention{intent}
+		}
+		return nil
+	}
+
+	var results []Intention
+	for _, occurrence := range intent.Occurrences(from, to) {
+		if q.Matches(occurrence) {
+			results = append(results, occurrence)
+		}
+	}
+	return results
+}
+
+// IntentionReferences merges the references of all of an intention's targets.
+func IntentionReferences(intent Intention) TargetRefs {
+	var refs TargetRefs
+	for _, target := range intent.Targets {
+		r := TargetReferences(target)
+		refs.LocationIDs = append(refs.LocationIDs, r.LocationIDs...)
+		refs.PersonIDs = append(refs.PersonIDs, r.PersonIDs...)
+		refs.GroupIDs = append(refs.GroupIDs, r.GroupIDs...)
+		refs.IntentionIDs = append(refs.IntentionIDs, r.IntentionIDs...)
+	}
+	return refs
+}
@@ -0,0 +1,139 @@
+package intentions_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuerySpec_Matches(t *testing.T) {
+	now := time.Now()
+	park, friend, climbers := uuid.New(), uuid.New(), uuid.New()
+	intent := intentions.Intention{
+		ID:           uuid.New(),
+		User:         "user-alice",
+		Participants: []string{"user-bob"},
+		Action:       "Evening Run",
+		Targets: []intentions.Target{
+			intentions.LocationTarget{LocationID: park},
+			intentions.ProximityTarget{PersonIDs: []uuid.UUID{friend}, GroupIDs: []uuid.UUID{climbers}},
+		},
+		StartTime: now,
+		EndTime:   now.Add(time.Hour),
+	}
+	ptr := func(s string) *string { return &s }
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+	other := uuid.New()
+
+	testCases := []struct {
+		name string
+		spec intentions.QuerySpec
+		want bool
+	}{
+		{"no filters", intentions.QuerySpec{}, true},
+		{"user", intentions.QuerySpec{User: ptr("user-alice")}, true},
+		{"other user", intentions.QuerySpec{User: ptr("user-bob")}, false},
+		{"participant", intentions.QuerySpec{Participant: ptr("user-bob")}, true},
+		{"not a participant", intentions.QuerySpec{Participant: ptr("user-carol")}, false},
+		{"active during", intentions.QuerySpec{ActiveAt: at(30 * time.Minute)}, true},
+		{"active at the end", intentions.QuerySpec{ActiveAt: at(time.Hour)}, true},
+		{"active after", intentions.QuerySpec{ActiveAt: at(2 * time.Hour)}, false},
+		{"overlapping range", intentions.QuerySpec{From: at(-time.Hour), To: at(10 * time.Minute)}, true},
+		{"range before", intentions.QuerySpec{From: at(-2 * time.Hour), To: at(-time.Hour)}, false},
+		{"open-ended from", intentions.QuerySpec{From: at(-time.Hour)}, true},
+		{"open-ended to in the past", intentions.QuerySpec{To: at(-time.Minute)}, false},
+		{"action ignoring case", intentions.QuerySpec{ActionContains: ptr("RUN")}, true},
+		{"other action", intentions.QuerySpec{ActionContains: ptr("swim")}, false},
+		{"location", intentions.QuerySpec{LocationID: &park}, true},
+		{"other location", intentions.QuerySpec{LocationID: &other}, false},
+		{"person", intentions.QuerySpec{PersonID: &friend}, true},
+		{"group", intentions.QuerySpec{GroupID: &climbers}, true},
+		{"other group", intentions.QuerySpec{GroupID: &other}, false},
+		{"every filter", intentions.QuerySpec{User: ptr("user-alice"), LocationID: &park, PersonID: &friend, ActionContains: ptr("run")}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.spec.Matches(intent))
+		})
+	}
+}
+
+func TestIntentionService_QueryIntentions_Pagination(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	target := []intentions.Target{intentions.OnlineTarget{Platform: "video"}}
+
+	collect := func(t *testing.T, svc *intentions.IntentionService, limit int) ([]uuid.UUID, int) {
+		t.Helper()
+		alice := "user-alice"
+		spec := intentions.QuerySpec{User: &alice, Limit: limit}
+		var ids []uuid.UUID
+		pages := 0
+		for {
+			page, next, err := svc.QueryIntentions(ctx, spec)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(page), limit)
+			pages++
+			for _, intent := range page {
+				ids = append(ids, intent.ID)
+			}
+			if next == "" {
+				return ids, pages
+			}
+			require.NotEmpty(t, page, "a cursor is only returned when there is another page")
+			spec.After = next
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		count     int
+		limit     int
+		wantPages int
+	}{
+		{"partial last page", 5, 2, 3},
+		{"exact multiple of the limit", 4, 2, 2},
+		{"fits in one page", 2, 2, 1},
+		{"no results", 0, 2, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := intentions.NewIntentionService(intentions.NewInMemoryStore())
+			var want []uuid.UUID
+			for i := 0; i < tc.count; i++ {
+				intent, err := svc.AddIntention(ctx, "user-alice", "Work", target, now.Add(time.Duration(i)*time.Hour), now.Add(time.Duration(i+1)*time.Hour))
+				require.NoError(t, err)
+				want = append(want, intent.ID)
+			}
+
+			got, pages := collect(t, svc, tc.limit)
+
+			assert.Equal(t, want, got)
+			assert.Equal(t, tc.wantPages, pages)
+		})
+	}
+
+	t.Run("No limit returns everything without a cursor", func(t *testing.T) {
+		svc := intentions.NewIntentionService(intentions.NewInMemoryStore())
+		for i := 0; i < 3; i++ {
+			_, err := svc.AddIntention(ctx, "user-alice", "Work", target, now, now.Add(time.Hour))
+			require.NoError(t, err)
+		}
+		results, next, err := svc.QueryIntentions(ctx, intentions.QuerySpec{})
+		require.NoError(t, err)
+		assert.Len(t, results, 3)
+		assert.Empty(t, next)
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		svc := intentions.NewIntentionService(intentions.NewInMemoryStore())
+		_, _, err := svc.QueryIntentions(ctx, intentions.QuerySpec{After: "not a cursor", Limit: 2})
+		assert.ErrorIs(t, err, apperr.ErrInvalid)
+	})
+}
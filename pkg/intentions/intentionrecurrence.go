@@ -0,0 +1,208 @@
+// FILE: pkg/intentions/intentionrecurrence.go
+
+package intentions
+
+import (
+	"slices"
+	"time"
+
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+)
+
+// Frequency is the base period of a recurrence rule.
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "daily"
+	FrequencyWeekly  Frequency = "weekly"
+	FrequencyMonthly Frequency = "monthly"
+)
+
+// Recurrence describes how an intention repeats, modelled on a subset of the
+// iCalendar RRULE. The intention's StartTime and EndTime define the first
+// occurrence; later occurrences keep the same wall-clock time and duration.
+type Recurrence struct {
+	Frequency Frequency `json:"frequency"`
+	// Interval is the number of periods between occurrences; zero means 1.
+	Interval int `json:"interval,omitempty"`
+	// ByDay lists the weekdays a weekly rule repeats on. When empty, the
+	// weekday of the first occurrence is used.
+	ByDay []time.Weekday `json:"by_day,omitempty"`
+	// Until ends the rule after the last occurrence starting at or before it.
+	Until *time.Time `json:"until,omitempty"`
+	// Count ends the rule after this many occurrences, exceptions included.
+	Count int `json:"count,omitempty"`
+	// Exceptions lists start times of occurrences that do not take place.
+	Exceptions []time.Time `json:"exceptions,omitempty"`
+}
+
+// Validate reports whether the rule can be expanded.
+func (r Recurrence) Validate() error {
+	switch r.Frequency {
+	case FrequencyDaily, FrequencyWeekly, FrequencyMonthly:
+	default:
+		return apperr.Errorf(apperr.ErrInvalid, "unknown recurrence frequency: %q", r.Frequency)
+	}
+	if r.Interval < 0 {
+		return apperr.Errorf(apperr.ErrInvalid, "recurrence interval cannot be negative")
+	}
+	if r.Count < 0 {
+		return apperr.Errorf(apperr.ErrInvalid, "recurrence count cannot be negative")
+	}
+	if r.Until != nil && r.Count > 0 {
+		return apperr.Errorf(apperr.ErrInvalid, "recurrence cannot have both until and count")
+	}
+	if len(r.ByDay) > 0 && r.Frequency != FrequencyWeekly {
+		return apperr.Errorf(apperr.ErrInvalid, "by-day is only supported for weekly recurrence")
+	}
+	for _, day := range r.ByDay {
+		if day < time.Sunday || day > time.Saturday {
+			return apperr.Errorf(apperr.ErrInvalid, "invalid weekday: %d", day)
+		}
+	}
+	return nil
+}
+
+// Bounded reports whether the rule has a last occurrence.
+func (r Recurrence) Bounded() bool {
+	return r.Until != nil || r.Count > 0
+}
+
+func (r Recurrence) interval() int {
+	if r.Interval <= 0 {
+		return 1
+	}
+	return r.Interval
+}
+
+// starts calls yield with the start time of each occurrence in order, beginning
+// with first, until yield returns false or the rule ends. Exceptions are not
+// removed here so that Count includes them.
+func (r Recurrence) starts(first time.Time, yield func(time.Time) bool) {
+	n := 0
+	emit := func(start time.Time) bool {
+		if r.Until != nil && start.After(*r.Until) {
+			return false
+		}
+		if r.Count > 0 && n >= r.Count {
+			return false
+		}
+		n++
+		return yield(start)
+	}
+
+	clock := func(day time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), first.Hour(), first.Minute(), first.Second(), first.Nanosecond(), first.Location())
+	}
+
+	switch r.Frequency {
+	case FrequencyDaily:
+		for k := 0; ; k++ {
+			if !emit(clock(first.AddDate(0, 0, k*r.interval()))) {
+				return
+			}
+		}
+	case FrequencyWeekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{first.Weekday()}
+		}
+		// Weeks start on Monday, as in the RRULE default.
+		offsets := make([]int, len(days))
+		for i, day := range days {
+			offsets[i] = (int(day) + 6) % 7
+		}
+		slices.Sort(offsets)
+		offsets = slices.Compact(offsets)
+		weekStart := first.AddDate(0, 0, -((int(first.Weekday()) + 6) % 7))
+		for w := 0; ; w += r.interval() {
+			for _, offset := range offsets {
+				start := clock(weekStart.AddDate(0, 0, w*7+offset))
+				if start.Before(first) {
+					continue
+				}
+				if !emit(start) {
+					return
+				}
+			}
+		}
+	case FrequencyMonthly:
+		for k := 0; ; k++ {
+			start := clock(first.AddDate(0, k*r.interval(), 0))
+			// Months without the first occurrence's day are skipped rather than
+			// rolling over into the next month.
+			if start.Day() != first.Day() {
+				continue
+			}
+			if !emit(start) {
+				return
+			}
+		}
+	}
+}
+
+func (r Recurrence) isException(start time.Time) bool {
+	for _, ex := range r.Exceptions {
+		if ex.Equal(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// Occurrences expands a recurring intention into the concrete occurrences that
+// overlap the range from..to. A nil bound leaves that end of the range open;
+// if to is nil and the rule never ends, only the first overlapping occurrence
+// is returned. Each occurrence is a copy of the intention with StartTime and
+// EndTime set to that occurrence. A non-recurring intention is returned as-is
+// if it overlaps the range.
+func (i Intention) Occurrences(from, to *time.Time) []Intention {
+	overlaps := func(start, end time.Time) bool {
+		return (from == nil || !end.Before(*from)) && (to == nil || !start.After(*to))
+	}
+	if i.Recurrence == nil {
+		if overlaps(i.StartTime, i.EndTime) {
+			return []Intention{i}
+		}
+		return nil
+	}
+
+	duration := i.EndTime.Sub(i.StartTime)
+	var results []Intention
+	i.Recurrence.starts(i.StartTime, func(start time.Time) bool {
+		if to != nil && start.After(*to) {
+			return false
+		}
+		end := start.Add(duration)
+		if i.Recurrence.isException(start) || !overlaps(start, end) {
+			return true
+		}
+		occurrence := i
+		occurrence.StartTime = start
+		occurrence.EndTime = end
+		results = append(results, occurrence)
+		return to != nil || i.Recurrence.Bounded()
+	})
+	return results
+}
+
+// SeriesEnd returns the end of the intention's last occurrence. It reports
+// false if the intention recurs without end.
+func (i Intention) SeriesEnd() (time.Time, bool) {
+	if i.Recurrence == nil {
+		return i.EndTime, true
+	}
+	if !i.Recurrence.Bounded() {
+		return time.Time{}, false
+	}
+	if i.Recurrence.Until != nil {
+		// Every occurrence starts at or before Until, so this is a safe upper bound.
+		return i.Recurrence.Until.Add(i.EndTime.Sub(i.StartTime)), true
+	}
+	last := i.StartTime
+	i.Recurrence.starts(i.StartTime, func(start time.Time) bool {
+		last = start
+		return true
+	})
+	return last.Add(i.EndTime.Sub(i.StartTime)), true
+}
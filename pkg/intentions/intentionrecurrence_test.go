@@ -0,0 +1,187 @@
+package intentions_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecurrence_Validate(t *testing.T) {
+	until := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name  string
+		rule  intentions.Recurrence
+		valid bool
+	}{
+		{"daily", intentions.Recurrence{Frequency: intentions.FrequencyDaily}, true},
+		{"weekly by day", intentions.Recurrence{Frequency: intentions.FrequencyWeekly, ByDay: []time.Weekday{time.Monday, time.Thursday}}, true},
+		{"monthly until", intentions.Recurrence{Frequency: intentions.FrequencyMonthly, Until: &until}, true},
+		{"unknown frequency", intentions.Recurrence{Frequency: "yearly"}, false},
+		{"negative interval", intentions.Recurrence{Frequency: intentions.FrequencyDaily, Interval: -1}, false},
+		{"negative count", intentions.Recurrence{Frequency: intentions.FrequencyDaily, Count: -1}, false},
+		{"until and count", intentions.Recurrence{Frequency: intentions.FrequencyDaily, Until: &until, Count: 3}, false},
+		{"by day on a daily rule", intentions.Recurrence{Frequency: intentions.FrequencyDaily, ByDay: []time.Weekday{time.Monday}}, false},
+		{"invalid weekday", intentions.Recurrence{Frequency: intentions.FrequencyWeekly, ByDay: []time.Weekday{7}}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rule.Validate()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, apperr.ErrInvalid)
+			}
+		})
+	}
+}
+
+func TestIntention_Occurrences(t *testing.T) {
+	// Wednesday 1 January 2025, 18:00 to 19:00.
+	first := time.Date(2025, time.January, 1, 18, 0, 0, 0, time.UTC)
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(2025, month, d, 18, 0, 0, 0, time.UTC)
+	}
+	until := day(time.January, 15)
+	rangeEnd := day(time.January, 31)
+
+	testCases := []struct {
+		name     string
+		start    time.Time
+		rule     intentions.Recurrence
+		from, to *time.Time
+		want     []time.Time
+	}{
+		{
+			name: "daily with count",
+			rule: intentions.Recurrence{Frequency: intentions.FrequencyDaily, Count: 3},
+			want: []time.Time{day(time.January, 1), day(time.January, 2), day(time.January, 3)},
+		},
+		{
+			name: "every other day until",
+			rule: intentions.Recurrence{Frequency: intentions.FrequencyDaily, Interval: 2, Until: &until},
+			from: &first,
+			to:   &rangeEnd,
+			want: []time.Time{day(time.January, 1), day(time.January, 3), day(time.January, 5), day(time.January, 7), day(time.January, 9), day(time.January, 11), day(time.January, 13), day(time.January, 15)},
+		},
+		{
+			name: "weekly on Monday and Wednesday",
+			rule: intentions.Recurrence{Frequency: intentions.FrequencyWeekly, ByDay: []time.Weekday{time.Wednesday, time.Monday}, Count: 4},
+			want: []time.Time{day(time.January, 1), day(time.January, 6), day(time.January, 8), day(time.January, 13)},
+		},
+		{
+			name: "count includes exceptions",
+			rule: intentions.Recurrence{Frequency: intentions.FrequencyWeekly, Count: 3, Exceptions: []time.Time{day(time.January, 8)}},
+			want: []time.Time{day(time.January, 1), day(time.January, 15)},
+		},
+		{
+			name:  "monthly skips short months",
+			start: time.Date(2025, time.January, 31, 18, 0, 0, 0, time.UTC),
+			rule:  intentions.Recurrence{Frequency: intentions.FrequencyMonthly, Count: 3},
+			want:  []time.Time{day(time.January, 31), day(time.March, 31), day(time.May, 31)},
+		},
+		{
+			name: "only occurrences overlapping the range",
+			rule: intentions.Recurrence{Frequency: intentions.FrequencyDaily},
+			from: ptrTime(day(time.January, 10).Add(30 * time.Minute)),
+			to:   ptrTime(day(time.January, 11)),
+			want: []time.Time{day(time.January, 10), day(time.January, 11)},
+		},
+		{
+			name: "unending rule without an end of range",
+			rule: intentions.Recurrence{Frequency: intentions.FrequencyDaily},
+			from: ptrTime(day(time.January, 20)),
+			want: []time.Time{day(time.January, 20)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			start := first
+			if !tc.start.IsZero() {
+				start = tc.start
+			}
+			rule := tc.rule
+			intent := intentions.Intention{StartTime: start, EndTime: start.Add(time.Hour), Recurrence: &rule}
+
+			var got []time.Time
+			for _, occurrence := range intent.Occurrences(tc.from, tc.to) {
+				assert.Equal(t, time.Hour, occurrence.EndTime.Sub(occurrence.StartTime))
+				got = append(got, occurrence.StartTime)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestIntention_SeriesEnd(t *testing.T) {
+	first := time.Date(2025, time.January, 1, 18, 0, 0, 0, time.UTC)
+	until := first.AddDate(0, 0, 10)
+
+	single := intentions.Intention{StartTime: first, EndTime: first.Add(time.Hour)}
+	end, bounded := single.SeriesEnd()
+	assert.True(t, bounded)
+	assert.Equal(t, single.EndTime, end)
+
+	counted := single
+	counted.Recurrence = &intentions.Recurrence{Frequency: intentions.FrequencyWeekly, Count: 3}
+	end, bounded = counted.SeriesEnd()
+	assert.True(t, bounded)
+	assert.Equal(t, first.AddDate(0, 0, 14).Add(time.Hour), end)
+
+	untilRule := single
+	untilRule.Recurrence = &intentions.Recurrence{Frequency: intentions.FrequencyDaily, Until: &until}
+	end, bounded = untilRule.SeriesEnd()
+	assert.True(t, bounded)
+	assert.Equal(t, until.Add(time.Hour), end)
+
+	forever := single
+	forever.Recurrence = &intentions.Recurrence{Frequency: intentions.FrequencyDaily}
+	_, bounded = forever.SeriesEnd()
+	assert.False(t, bounded)
+}
+
+func TestIntentionService_RecurringIntentions(t *testing.T) {
+	ctx := context.Background()
+	svc := intentions.NewIntentionService(intentions.NewInMemoryStore())
+	first := time.Date(2025, time.January, 1, 18, 0, 0, 0, time.UTC)
+	target := []intentions.Target{intentions.OnlineTarget{Platform: "video"}}
+
+	_, err := svc.AddRecurringIntention(ctx, "user-alice", "Five-a-side", target, first, first.Add(time.Hour), intentions.Recurrence{Frequency: "yearly"})
+	assert.ErrorIs(t, err, apperr.ErrInvalid)
+
+	weekly, err := svc.AddRecurringIntention(ctx, "user-alice", "Five-a-side", target, first, first.Add(time.Hour), intentions.Recurrence{Frequency: intentions.FrequencyWeekly, Count: 4})
+	require.NoError(t, err)
+
+	t.Run("A range query returns each occurrence", func(t *testing.T) {
+		from, to := first.AddDate(0, 0, 5), first.AddDate(0, 1, 0)
+		results, _, err := svc.QueryIntentions(ctx, intentions.QuerySpec{From: &from, To: &to})
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		for i, occurrence := range results {
+			assert.Equal(t, weekly.ID, occurrence.ID)
+			assert.Equal(t, first.AddDate(0, 0, 7*(i+1)), occurrence.StartTime)
+		}
+	})
+
+	t.Run("A query without a range returns the series", func(t *testing.T) {
+		results, _, err := svc.QueryIntentions(ctx, intentions.QuerySpec{})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		assert.Equal(t, first, results[0].StartTime)
+	})
+
+	t.Run("Nothing after the last occurrence", func(t *testing.T) {
+		after := first.AddDate(0, 0, 22)
+		results, _, err := svc.QueryIntentions(ctx, intentions.QuerySpec{ActiveAt: &after})
+		require.NoError(t, err)
+		assert.Empty(t, results)
+	})
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -4,16 +4,20 @@ package intentions
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/provenance"
 )
 
 // IntentionService provides the business logic for managing intentions.
 // It orchestrates the storage and retrieval of intention data.
 type IntentionService struct {
-	store Store
+	store     Store
+	conflicts *ConflictPolicy
 }
 
 // NewIntentionService is the constructor for our intentions IntentionService.
@@ -33,14 +37,8 @@ func (s *IntentionService) GetStore() Store {
 // MODIFIED: The 'target' parameter is now a slice 'targets []Target'.
 func (s *IntentionService) AddIntention(ctx context.Context, user, action string, targets []Target, start, end time.Time) (Intention, error) {
 	// --- Validation ---
-	if user == "" || action == "" {
-		return Intention{}, fmt.Errorf("user and action cannot be empty")
-	}
-	if end.Before(start) {
-		return Intention{}, fmt.Errorf("end time cannot be before start time")
-	}
-	if len(targets) == 0 { // MODIFIED: Validation logic updated
-		return Intention{}, fmt.Errorf("at least one target is required")
+	if err := validateIntention(user, action, targets, start, end); err != nil {
+		return Intention{}, err
 	}
 
 	intent := Intention{
@@ -51,15 +49,179 @@ func (s *IntentionService) AddIntention(ctx context.Context, user, action string
 		StartTime: start,
 		EndTime:   end,
 		CreatedAt: time.Now(),
+		Status:    StatusPlanned,
+		Version:   1,
 	}
 
+	if err := s.checkConflicts(ctx, intent); err != nil {
+		return Intention{}, err
+	}
 	if err := s.store.Add(ctx, intent); err != nil {
 		return Intention{}, fmt.Errorf("failed to save intention: %w", err)
 	}
 	return intent, nil
 }
 
+// AddRecurringIntention creates an intention that repeats according to rule.
+// start and end define the first occurrence.
+func (s *IntentionService) AddRecurringIntention(ctx context.Context, user, action string, targets []Target, start, end time.Time, rule Recurrence) (Intention, error) {
+	if err := rule.Validate(); err != nil {
+		return Intention{}, err
+	}
+	if err := validateIntention(user, action, targets, start, end); err != nil {
+		return Intention{}, err
+	}
+
+	intent := Intention{
+		ID:         uuid.New(),
+		User:       user,
+		Action:     action,
+		Targets:    targets,
+		StartTime:  start,
+		EndTime:    end,
+		CreatedAt:  time.Now(),
+		Recurrence: &rule,
+		Status:     StatusPlanned,
+		Version:    1,
+	}
+
+	if err := s.checkConflicts(ctx, intent); err != nil {
+		return Intention{}, err
+	}
+	if err := s.store.Add(ctx, intent); err != nil {
+		return Intention{}, fmt.Errorf("failed to save intention: %w", err)
+	}
+	return intent, nil
+}
+
+// SaveReceivedIntention stores a local copy of an intention received from
+// another user. The copy gets a new local ID and version but keeps the sender's
+// times, status, recurrence and invitations. Its Provenance links it to the
+// sender's original, so if that intention was received before, the existing
+// copy is replaced and keeps its local ID.
+func (s *IntentionService) SaveReceivedIntention(ctx context.Context, intent Intention) (Intention, error) {
+	if err := validateIntention(intent.User, intent.Action, intent.Targets, intent.StartTime, intent.EndTime); err != nil {
+		return Intention{}, err
+	}
+	if intent.Recurrence != nil {
+		if err := intent.Recurrence.Validate(); err != nil {
+			return Intention{}, err
+		}
+	}
+
+	intent.Provenance = provenance.New(intent.User, intent.ID)
+	existing, err := s.store.FindByProvenance(ctx, intent.User, intent.ID)
+	switch {
+	case err == nil:
+		intent.ID = existing.ID
+		intent.CreatedAt = existing.CreatedAt
+		intent.Version = existing.Version
+		if err := s.store.Update(ctx, intent); err != nil {
+			return Intention{}, fmt.Errorf("failed to update received intention: %w", err)
+		}
+		intent.Version++
+		return intent, nil
+	case !errors.Is(err, apperr.ErrNotFound):
+		return Intention{}, fmt.Errorf("failed to look up received intention: %w", err)
+	}
+
+	intent.ID = uuid.New()
+	intent.CreatedAt = time.Now()
+	intent.Version = 1
+	if err := s.store.Add(ctx, intent); err != nil {
+		return Intention{}, fmt.Errorf("failed to save intention: %w", err)
+	}
+	return intent, nil
+}
+
+// FindReceivedIntention fetches the local copy of an intention received from
+// senderID, by the sender's ID for it.
+func (s *IntentionService) FindReceivedIntention(ctx context.Context, senderID string, senderIntentionID uuid.UUID) (Intention, error) {
+	return s.store.FindByProvenance(ctx, senderID, senderIntentionID)
+}
+
+// GetIntention fetches a single intention by its ID.
+func (s *IntentionService) GetIntention(ctx context.Context, id uuid.UUID) (Intention, error) {
+	return s.store.Get(ctx, id)
+}
+
+// UpdateIntention validates and saves changes to an existing intention. The
+// intention's Version must be the one that was read; if the intention has been
+// changed since, the returned error wraps ErrVersionConflict. For a recurring
+// intention, pass the series from GetIntention rather than a queried occurrence.
+func (s *IntentionService) UpdateIntention(ctx context.Context, intent Intention) (Intention, error) {
+	if err := validateIntention(intent.User, intent.Action, intent.Targets, intent.StartTime, intent.EndTime); err != nil {
+		return Intention{}, err
+	}
+	if intent.Recurrence != nil {
+		if err := intent.Recurrence.Validate(); err != nil {
+			return Intention{}, err
+		}
+	}
+
+	// Status is only changed through TransitionIntention, so keep the stored lifecycle.
+	existing, err := s.store.Get(ctx, intent.ID)
+	if err != nil {
+		return Intention{}, err
+	}
+	if intent.Status != existing.Status {
+		return Intention{}, apperr.Errorf(apperr.ErrInvalid, "status cannot be changed by an update; use TransitionIntention")
+	}
+	intent.ConfirmedAt = existing.ConfirmedAt
+	intent.StartedAt = existing.StartedAt
+	intent.CompletedAt = existing.CompletedAt
+	intent.CancelledAt = existing.CancelledAt
+	if err := s.checkConflicts(ctx, intent); err != nil {
+		return Intention{}, err
+	}
+	if err := s.store.Update(ctx, intent); err != nil {
+		return Intention{}, fmt.Errorf("failed to update intention: %w", err)
+	}
+	intent.Version++
+	return intent, nil
+}
+
+// CancelIntention marks an intention as cancelled. The intention is kept so it
+// can still be shown to people it was shared with. Finished intentions cannot
+// be cancelled.
+func (s *IntentionService) CancelIntention(ctx context.Context, id uuid.UUID) error {
+	if err := s.store.Cancel(ctx, id); err != nil {
+		return fmt.Errorf("failed to cancel intention: %w", err)
+	}
+	return nil
+}
+
+// DeleteIntention permanently removes an intention.
+func (s *IntentionService) DeleteIntention(ctx context.Context, id uuid.UUID) error {
+	if err := s.store.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete intention: %w", err)
+	}
+	return nil
+}
+
+// QueryIntentions runs a query and returns one page of results. The returned
+// cursor fetches the next page when passed as spec.After; it is empty when
+// there are no more results. One result more than the limit is fetched to
+// tell whether another page exists.
+func (s *IntentionService) QueryIntentions(ctx context.Context, spec QuerySpec) ([]Intention, string, error) {
+	limit := spec.Limit
+	if limit > 0 {
+		spec.Limit = limit + 1
+	}
+	results, err := s.store.Query(ctx, spec)
+	if err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+		next = CursorAfter(results[len(results)-1])
+	}
+	return results, next, nil
+}
+
 // GetActiveIntentionsForUser is a convenient method to find what a user is currently doing.
+// For a recurring intention the current occurrence is returned.
 func (s *IntentionService) GetActiveIntentionsForUser(ctx context.Context, user string) ([]Intention, error) {
 	now := time.Now()
 	spec := QuerySpec{
@@ -67,5 +229,36 @@ func (s *IntentionService) GetActiveIntentionsForUser(ctx context.Context, user
 		ActiveAt: &now,
 	}
 
-	return s.store.Query(ctx, spec)
+	results, err := s.store.Query(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cancelled and finished intentions are kept in the store but are no longer happening.
+	active := results[:0]
+	for _, intent := range results {
+		if intent.Status != StatusCancelled && intent.Status != StatusDone {
+			active = append(active, intent)
+		}
+	}
+	return active, nil
+}
+
+// validateIntention checks the fields shared by new and updated intentions.
+func validateIntention(user, action string, targets []Target, start, end time.Time) error {
+	if user == "" || action == "" {
+		return apperr.Errorf(apperr.ErrInvalid, "user and action cannot be empty")
+	}
+	if end.Before(start) {
+		return apperr.Errorf(apperr.ErrInvalid, "end time cannot be before start time")
+	}
+	if len(targets) == 0 {
+		return apperr.Errorf(apperr.ErrInvalid, "at least one target is required")
+	}
+	for _, target := range targets {
+		if err := ValidateTarget(target); err != nil {
+			return err
+		}
+	}
+	return nil
 }
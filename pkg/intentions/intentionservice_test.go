@@ -0,0 +1,86 @@
+package intentions_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntentionService_CRUD(t *testing.T) {
+	ctx := context.Background()
+	svc := intentions.NewIntentionService(intentions.NewInMemoryStore())
+	now := time.Now()
+	park := []intentions.Target{intentions.LocationTarget{LocationID: uuid.New()}}
+
+	_, err := svc.AddIntention(ctx, "user-alice", "Run", park, now, now.Add(-time.Hour))
+	assert.ErrorIs(t, err, apperr.ErrInvalid, "end before start")
+	_, err = svc.AddIntention(ctx, "user-alice", "Run", nil, now, now.Add(time.Hour))
+	assert.ErrorIs(t, err, apperr.ErrInvalid, "no targets")
+
+	intent, err := svc.AddIntention(ctx, "user-alice", "Run", park, now, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, intentions.StatusPlanned, intent.Status)
+	assert.Equal(t, 1, intent.Version)
+
+	t.Run("Get", func(t *testing.T) {
+		got, err := svc.GetIntention(ctx, intent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, intent.Action, got.Action)
+
+		_, err = svc.GetIntention(ctx, uuid.New())
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+	})
+
+	t.Run("Update bumps the version", func(t *testing.T) {
+		read, err := svc.GetIntention(ctx, intent.ID)
+		require.NoError(t, err)
+		read.Action = "Long run"
+
+		updated, err := svc.UpdateIntention(ctx, read)
+		require.NoError(t, err)
+		assert.Equal(t, read.Version+1, updated.Version)
+		got, err := svc.GetIntention(ctx, intent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Long run", got.Action)
+		assert.Equal(t, updated.Version, got.Version)
+
+		_, err = svc.UpdateIntention(ctx, read)
+		assert.ErrorIs(t, err, intentions.ErrVersionConflict, "the version read is now stale")
+		assert.ErrorIs(t, err, apperr.ErrConflict)
+	})
+
+	t.Run("Update cannot change the status", func(t *testing.T) {
+		read, err := svc.GetIntention(ctx, intent.ID)
+		require.NoError(t, err)
+		read.Status = intentions.StatusDone
+
+		_, err = svc.UpdateIntention(ctx, read)
+		assert.ErrorIs(t, err, apperr.ErrInvalid)
+	})
+
+	t.Run("Cancel keeps the intention", func(t *testing.T) {
+		require.NoError(t, svc.CancelIntention(ctx, intent.ID))
+
+		got, err := svc.GetIntention(ctx, intent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, intentions.StatusCancelled, got.Status)
+		require.NotNil(t, got.CancelledAt)
+		active, err := svc.GetActiveIntentionsForUser(ctx, "user-alice")
+		require.NoError(t, err)
+		assert.Empty(t, active, "a cancelled intention is not active")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		require.NoError(t, svc.DeleteIntention(ctx, intent.ID))
+
+		_, err := svc.GetIntention(ctx, intent.ID)
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+		assert.ErrorIs(t, svc.DeleteIntention(ctx, intent.ID), apperr.ErrNotFound)
+	})
+}
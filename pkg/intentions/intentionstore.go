@@ -5,14 +5,42 @@ package intentions
 import (
 	"context"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 )
 
+// ErrVersionConflict is returned by Update when the stored intention has
+// changed since the caller read it. It matches apperr.ErrConflict.
+var ErrVersionConflict = apperr.New(apperr.ErrConflict, "intention has been modified since it was read")
+
 // QuerySpec defines the parameters for a query.
 // Using pointers allows us to distinguish between a filter not being set
 // and a filter having an empty value.
+// Results are always ordered by start time (then end time, then ID), which is
+// the order the pagination cursor follows.
 type QuerySpec struct {
-	User     *string
-	ActiveAt *time.Time // Find intentions that are active at this specific time.
+	User        *string
+	Participant *string    // Find intentions that list this participant.
+	ActiveAt    *time.Time // Find intentions that are active at this specific time.
+
+	// From and To find intentions that overlap the range. Either may be set alone
+	// to leave that end of the range open.
+	From *time.Time
+	To   *time.Time
+
+	// Find intentions with a target that refers to this location, person or group.
+	LocationID *uuid.UUID
+	PersonID   *uuid.UUID
+	GroupID    *uuid.UUID
+
+	// ActionContains finds intentions whose action contains this text, ignoring case.
+	ActionContains *string
+
+	// Limit caps the number of results; zero means no limit.
+	Limit int
+	// After is a cursor from CursorAfter; results start after that intention.
+	After string
 }
 
 // Store is the interface for storing and retrieving intentions.
@@ -20,6 +48,22 @@ type QuerySpec struct {
 type Store interface {
 	// Add saves a new intention to the store.
 	Add(ctx context.Context, intent Intention) error
+	// Get retrieves a single intention by its ID.
+	Get(ctx context.Context, id uuid.UUID) (Intention, error)
+	// Update replaces a stored intention. The intention's Version must match the
+	// stored version, otherwise ErrVersionConflict is returned. On success the
+	// stored version is incremented.
+	Update(ctx context.Context, intent Intention) error
+	// Cancel marks an intention as cancelled without removing it, recording
+	// when it was cancelled and incrementing its version. An intention that
+	// may not move to cancelled, see CanTransition, is left unchanged and
+	// apperr.ErrConflict is returned.
+	Cancel(ctx context.Context, id uuid.UUID) error
+	// Delete permanently removes an intention.
+	Delete(ctx context.Context, id uuid.UUID) error
 	// Query retrieves intentions based on the provided specification.
 	Query(ctx context.Context, spec QuerySpec) ([]Intention, error)
+	// FindByProvenance retrieves the local copy of another user's intention,
+	// i.e. the intention whose Provenance records that sender and ID.
+	FindByProvenance(ctx context.Context, senderID string, senderIntentionID uuid.UUID) (Intention, error)
 }
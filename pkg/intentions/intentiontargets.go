@@ -0,0 +1,145 @@
+// FILE: pkg/intentions/intentiontargets.go
+
+package intentions
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/google/uuid"
+)
+
+// init registers the built-in target kinds with the target registry.
+func init() {
+	location := JSONTargetCodec[LocationTarget]()
+	location.Validate = func(t Target) error {
+		l, ok := t.(LocationTarget)
+		if !ok {
+			return fmt.Errorf("unexpected target value %T", t)
+		}
+		if l.LocationID == uuid.Nil {
+			return fmt.Errorf("location ID cannot be empty")
+		}
+		return nil
+	}
+	location.References = func(t Target) TargetRefs {
+		l, _ := t.(LocationTarget)
+		return TargetRefs{LocationIDs: []uuid.UUID{l.LocationID}}
+	}
+	location.Rewrite = func(t Target, refs TargetRefs) Target {
+		l, _ := t.(LocationTarget)
+		if len(refs.LocationIDs) == 1 {
+			l.LocationID = refs.LocationIDs[0]
+		}
+		return l
+	}
+	RegisterTarget(LocationTarget{}.Type(), location)
+
+	proximity := JSONTargetCodec[ProximityTarget]()
+	proximity.Validate = func(t Target) error {
+		p, ok := t.(ProximityTarget)
+		if !ok {
+			return fmt.Errorf("unexpected target value %T", t)
+		}
+		if len(p.PersonIDs) == 0 && len(p.GroupIDs) == 0 {
+			return fmt.Errorf("at least one person or group is required")
+		}
+		return nil
+	}
+	proximity.References = func(t Target) TargetRefs {
+		p, _ := t.(ProximityTarget)
+		return TargetRefs{PersonIDs: p.PersonIDs, GroupIDs: p.GroupIDs}
+	}
+	proximity.Rewrite = func(t Target, refs TargetRefs) Target {
+		p, _ := t.(ProximityTarget)
+		p.PersonIDs, p.GroupIDs = refs.PersonIDs, refs.GroupIDs
+		return p
+	}
+	RegisterTarget(ProximityTarget{}.Type(), proximity)
+
+	online := JSONTargetCodec[OnlineTarget]()
+	online.Validate = func(t Target) error {
+		o, ok := t.(OnlineTarget)
+		if !ok {
+			return fmt.Errorf("unexpected target value %T", t)
+		}
+		if o.Platform == "" && o.URL == "" {
+			return fmt.Errorf("a platform or URL is required")
+		}
+		if o.URL != "" {
+			u, err := url.Parse(o.URL)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return fmt.Errorf("URL %q must be absolute", o.URL)
+			}
+		}
+		return nil
+	}
+	RegisterTarget(OnlineTarget{}.Type(), online)
+
+	route := JSONTargetCodec[RouteTarget]()
+	route.Validate = func(t Target) error {
+		r, ok := t.(RouteTarget)
+		if !ok {
+			return fmt.Errorf("unexpected target value %T", t)
+		}
+		if len(r.LocationIDs) < 2 {
+			return fmt.Errorf("a route needs at least two locations")
+		}
+		for i, id := range r.LocationIDs {
+			if id == uuid.Nil {
+				return fmt.Errorf("location ID at stop %d cannot be empty", i)
+			}
+		}
+		return nil
+	}
+	route.References = func(t Target) TargetRefs {
+		r, _ := t.(RouteTarget)
+		return TargetRefs{LocationIDs: r.LocationIDs}
+	}
+	route.Rewrite = func(t Target, refs TargetRefs) Target {
+		r, _ := t.(RouteTarget)
+		r.LocationIDs = refs.LocationIDs
+		return r
+	}
+	RegisterTarget(RouteTarget{}.Type(), route)
+
+	event := JSONTargetCodec[EventTarget]()
+	event.Validate = func(t Target) error {
+		e, ok := t.(EventTarget)
+		if !ok {
+			return fmt.Errorf("unexpected target value %T", t)
+		}
+		if e.IntentionID == uuid.Nil {
+			return fmt.Errorf("intention ID cannot be empty")
+		}
+		return nil
+	}
+	event.References = func(t Target) TargetRefs {
+		e, _ := t.(EventTarget)
+		return TargetRefs{IntentionIDs: []uuid.UUID{e.IntentionID}}
+	}
+	event.Rewrite = func(t Target, refs TargetRefs) Target {
+		e, _ := t.(EventTarget)
+		if len(refs.IntentionIDs) == 1 {
+			e.IntentionID = refs.IntentionIDs[0]
+		}
+		return e
+	}
+	RegisterTarget(EventTarget{}.Type(), event)
+
+	window := JSONTargetCodec[TimeWindowTarget]()
+	window.Validate = func(t Target) error {
+		w, ok := t.(TimeWindowTarget)
+		if !ok {
+			return fmt.Errorf("unexpected target value %T", t)
+		}
+		if w.Start.IsZero() || w.End.IsZero() {
+			return fmt.Errorf("a time window needs a start and an end")
+		}
+		if !w.End.After(w.Start) {
+			return fmt.Errorf("time window must end after it starts")
+		}
+		return nil
+	}
+	RegisterTarget(TimeWindowTarget{}.Type(), window)
+}
@@ -0,0 +1,64 @@
+package intentions_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntention_JSONRoundTripsBuiltInTargets(t *testing.T) {
+	start := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	intent := intentions.Intention{
+		ID:     uuid.New(),
+		User:   "user-alice",
+		Action: "Run",
+		Targets: []intentions.Target{
+			intentions.LocationTarget{LocationID: uuid.New()},
+			intentions.ProximityTarget{PersonIDs: []uuid.UUID{uuid.New()}},
+			intentions.OnlineTarget{Platform: "Strava", URL: "https://strava.example/club"},
+			intentions.RouteTarget{LocationIDs: []uuid.UUID{uuid.New(), uuid.New()}},
+			intentions.EventTarget{IntentionID: uuid.New()},
+			intentions.TimeWindowTarget{Start: start, End: start.Add(3 * time.Hour)},
+		},
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+	}
+
+	data, err := json.Marshal(intent)
+	require.NoError(t, err)
+	var decoded intentions.Intention
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	assert.Equal(t, intent.Targets, decoded.Targets)
+}
+
+func TestValidateTarget_TimeWindow(t *testing.T) {
+	start := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name   string
+		target intentions.TimeWindowTarget
+		valid  bool
+	}{
+		{"three hour window", intentions.TimeWindowTarget{Start: start, End: start.Add(3 * time.Hour)}, true},
+		{"missing start", intentions.TimeWindowTarget{End: start}, false},
+		{"missing end", intentions.TimeWindowTarget{Start: start}, false},
+		{"empty window", intentions.TimeWindowTarget{Start: start, End: start}, false},
+		{"ends before it starts", intentions.TimeWindowTarget{Start: start, End: start.Add(-time.Hour)}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := intentions.ValidateTarget(tc.target)
+			if tc.valid {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, apperr.ErrInvalid)
+		})
+	}
+}
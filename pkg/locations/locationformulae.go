@@ -15,3 +15,12 @@ func haversine(lat1, lon1, lat2, lon2 float64) float64 {
 	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
 	return R * c
 }
+
+// DistanceKm returns the great-circle distance between two locations. It
+// reports false if either location has no coordinates.
+func DistanceKm(a, b Location) (float64, bool) {
+	if a.Matcher.Lat == nil || a.Matcher.Lon == nil || b.Matcher.Lat == nil || b.Matcher.Lon == nil {
+		return 0, false
+	}
+	return haversine(*a.Matcher.Lat, *a.Matcher.Lon, *b.Matcher.Lat, *b.Matcher.Lon), true
+}
@@ -0,0 +1,72 @@
+// FILE: pkg/locations/locationgeocoder.go
+
+package locations
+
+import (
+	"context"
+	"sync"
+
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/textmatch"
+)
+
+// ErrAddressNotFound is returned by a Geocoder that has no coordinates for an
+// address. It matches apperr.ErrNotFound.
+var ErrAddressNotFound = apperr.New(apperr.ErrNotFound, "address not found")
+
+// Geocoder looks up the coordinates of a postal address.
+type Geocoder interface {
+	Geocode(ctx context.Context, address Address) (Coordinates, error)
+}
+
+// FixtureGeocoder is an offline Geocoder backed by a fixed table of addresses,
+// for tests, demos and deployments without a geocoding provider.
+type FixtureGeocoder struct {
+	sync.RWMutex
+	byAddress    map[string]Coordinates
+	byPostalCode map[string]Coordinates
+}
+
+// NewFixtureGeocoder creates an empty FixtureGeocoder.
+func NewFixtureGeocoder() *FixtureGeocoder {
+	return &FixtureGeocoder{
+		byAddress:    make(map[string]Coordinates),
+		byPostalCode: make(map[string]Coordinates),
+	}
+}
+
+// Add records the coordinates for an address. If the address has a postal
+// code, the coordinates also answer other addresses with the same postal code
+// and country that are not in the table themselves.
+func (g *FixtureGeocoder) Add(address Address, c Coordinates) {
+	g.Lock()
+	defer g.Unlock()
+	g.byAddress[textmatch.Normalize(address.String())] = c
+	if key, ok := postalCodeKey(address); ok {
+		g.byPostalCode[key] = c
+	}
+}
+
+// Geocode returns the coordinates recorded for the address, comparing
+// addresses after normalization, then falls back to its postal code.
+func (g *FixtureGeocoder) Geocode(ctx context.Context, address Address) (Coordinates, error) {
+	g.RLock()
+	defer g.RUnlock()
+	if c, ok := g.byAddress[textmatch.Normalize(address.String())]; ok {
+		return c, nil
+	}
+	if key, ok := postalCodeKey(address); ok {
+		if c, ok := g.byPostalCode[key]; ok {
+			return c, nil
+		}
+	}
+	return Coordinates{}, ErrAddressNotFound
+}
+
+func postalCodeKey(address Address) (string, bool) {
+	code := textmatch.Normalize(address.PostalCode)
+	if code == "" {
+		return "", false
+	}
+	return textmatch.Normalize(address.Country) + "|" + code, true
+}
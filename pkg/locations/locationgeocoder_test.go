@@ -0,0 +1,49 @@
+package locations_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/locations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixtureGeocoder_Geocode(t *testing.T) {
+	ctx := context.Background()
+	cafe := locations.Coordinates{Lat: 53.3498, Lon: -6.2603}
+	geocoder := locations.NewFixtureGeocoder()
+	geocoder.Add(locations.Address{Street: "1 Main Street", Locality: "Dublin", PostalCode: "D01 F5P2", Country: "Ireland"}, cafe)
+	geocoder.Add(locations.Address{Street: "2 High Street", Locality: "Galway", Country: "Ireland"}, locations.Coordinates{Lat: 53.2707, Lon: -9.0568})
+
+	testCases := []struct {
+		name    string
+		address locations.Address
+		want    locations.Coordinates
+		found   bool
+	}{
+		{"exact address", locations.Address{Street: "1 Main Street", Locality: "Dublin", PostalCode: "D01 F5P2", Country: "Ireland"}, cafe, true},
+		{"address differing in case and spacing", locations.Address{Street: "1  main street", Locality: "DUBLIN", PostalCode: "d01 f5p2", Country: "ireland"}, cafe, true},
+		{"other street with the same postal code", locations.Address{Street: "9 Side Lane", PostalCode: "D01 F5P2", Country: "Ireland"}, cafe, true},
+		{"same postal code in another country", locations.Address{Street: "9 Side Lane", PostalCode: "D01 F5P2", Country: "France"}, locations.Coordinates{}, false},
+		{"unknown address without a postal code", locations.Address{Street: "3 High Street", Locality: "Galway", Country: "Ireland"}, locations.Coordinates{}, false},
+		{"empty address", locations.Address{}, locations.Coordinates{}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := geocoder.Geocode(ctx, tc.address)
+
+			if !tc.found {
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, locations.ErrAddressNotFound))
+				assert.True(t, errors.Is(err, apperr.ErrNotFound))
+				assert.Equal(t, locations.Coordinates{}, got)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// FILE: pkg/locations/locationgeohash.go
+
+package locations
+
+import (
+	"math"
+	"strings"
+)
+
+// GeohashPrecision is the length of the geohash stored for each location with
+// coordinates. Nine characters is a cell of a few metres.
+const GeohashPrecision = 9
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Geohash encodes a coordinate as a geohash of the given length. Locations
+// that are close together share a geohash prefix, so a prefix identifies a
+// grid cell that can be looked up with a simple range query.
+func Geohash(lat, lon float64, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+	var b strings.Builder
+	bit, ch, even := 0, 0, true
+	for b.Len() < precision {
+		// Bits alternate between longitude and latitude, longitude first.
+		r, v := &latRange, lat
+		if even {
+			r, v = &lonRange, lon
+		}
+		mid := (r[0] + r[1]) / 2
+		ch <<= 1
+		if v >= mid {
+			ch |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+		even = !even
+		if bit++; bit == 5 {
+			b.WriteByte(geohashAlphabet[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return b.String()
+}
+
+// geohashCellSize returns the size in degrees of a geohash cell of the given length.
+func geohashCellSize(precision int) (latDeg, lonDeg float64) {
+	bits := 5 * precision
+	lonBits := (bits + 1) / 2
+	latBits := bits / 2
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lonBits))
+}
+
+// GeohashCoverage returns the geohash prefixes whose cells together contain
+// every point within radiusKm of the coordinate: the cell holding the point
+// and its eight neighbours, at the finest precision whose cells are at least
+// radiusKm across.
+func GeohashCoverage(lat, lon, radiusKm float64) []string {
+	const kmPerDegree = 111.32
+	// Cells are narrowest on the side nearest the pole, so measure them there.
+	edgeLat := math.Min(89.9, math.Abs(lat)+radiusKm/kmPerDegree)
+	precision := 1
+	for p := GeohashPrecision; p >= 1; p-- {
+		latDeg, lonDeg := geohashCellSize(p)
+		heightKm := latDeg * kmPerDegree
+		widthKm := lonDeg * kmPerDegree * math.Cos(edgeLat*math.Pi/180)
+		if heightKm >= radiusKm && widthKm >= radiusKm {
+			precision = p
+			break
+		}
+	}
+
+	latDeg, lonDeg := geohashCellSize(precision)
+	seen := make(map[string]bool)
+	var prefixes []string
+	for _, dLat := range []float64{-latDeg, 0, latDeg} {
+		for _, dLon := range []float64{-lonDeg, 0, lonDeg} {
+			cellLat := math.Max(-90, math.Min(90, lat+dLat))
+			cellLon := math.Mod(lon+dLon+540, 360) - 180
+			hash := Geohash(cellLat, cellLon, precision)
+			if !seen[hash] {
+				seen[hash] = true
+				prefixes = append(prefixes, hash)
+			}
+		}
+	}
+	return prefixes
+}
+
+// LocationGeohash returns the geohash of a location's coordinates, or false if
+// it has none.
+func LocationGeohash(loc Location) (string, bool) {
+	if loc.Matcher.Lat == nil || loc.Matcher.Lon == nil {
+		return "", false
+	}
+	return Geohash(*loc.Matcher.Lat, *loc.Matcher.Lon, GeohashPrecision), true
+}
+
+// WithinKm reports whether a location has coordinates within radiusKm of the point.
+func WithinKm(loc Location, lat, lon, radiusKm float64) bool {
+	if loc.Matcher.Lat == nil || loc.Matcher.Lon == nil {
+		return false
+	}
+	return haversine(lat, lon, *loc.Matcher.Lat, *loc.Matcher.Lon) <= radiusKm
+}
@@ -0,0 +1,82 @@
+package locations_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/illmade-knight/action-intention/pkg/locations"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeohash(t *testing.T) {
+	testCases := []struct {
+		name      string
+		lat, lon  float64
+		precision int
+		want      string
+	}{
+		{"Jutland", 57.64911, 10.40744, 11, "u4pruydqqvj"},
+		{"northern Spain", 42.6, -5.6, 5, "ezs42"},
+		{"Curitiba", -25.382708, -49.265506, 12, "6gkzwgjzn820"},
+		{"origin", 0, 0, 5, "s0000"},
+		{"just south-west of the origin", -0.000001, -0.000001, 5, "7zzzz"},
+		{"single character", 57.64911, 10.40744, 1, "u"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, locations.Geohash(tc.lat, tc.lon, tc.precision))
+		})
+	}
+}
+
+func TestGeohashCoverage(t *testing.T) {
+	testCases := []struct {
+		name     string
+		lat, lon float64
+		radiusKm float64
+	}{
+		{"middle of a cell", 53.3498, -6.2603, 1},
+		{"on the prime meridian", 51.4779, -0.0001, 2},
+		{"on the equator", 0.0001, 30, 2},
+		{"at the corner of four cells", 0, 0, 5},
+		{"west of the antimeridian", 10, 179.999, 5},
+		{"east of the antimeridian", -10, -179.999, 5},
+		{"high latitude", 70, 25, 10},
+		{"large radius", 40, -100, 300},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prefixes := locations.GeohashCoverage(tc.lat, tc.lon, tc.radiusKm)
+			assert.LessOrEqual(t, len(prefixes), 9)
+
+			// Every point just inside the radius falls in one of the cells.
+			for bearing := 0.0; bearing < 360; bearing += 15 {
+				lat, lon := destination(tc.lat, tc.lon, bearing, 0.99*tc.radiusKm)
+				hash := locations.Geohash(lat, lon, locations.GeohashPrecision)
+				assert.True(t, hasAnyPrefix(hash, prefixes), "point at bearing %v (%.5f, %.5f) is outside %v", bearing, lat, lon, prefixes)
+			}
+		})
+	}
+}
+
+// destination returns the point distanceKm from the start along the bearing,
+// with its longitude normalized to [-180, 180).
+func destination(lat, lon, bearingDeg, distanceKm float64) (float64, float64) {
+	const earthRadiusKm = 6371
+	rad := math.Pi / 180
+	d := distanceKm / earthRadiusKm
+	lat1, lon1, b := lat*rad, lon*rad, bearingDeg*rad
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(b))
+	lon2 := lon1 + math.Atan2(math.Sin(b)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	return lat2 / rad, math.Mod(lon2/rad+540, 360) - 180
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
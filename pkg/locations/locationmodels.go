@@ -3,11 +3,13 @@
 package locations
 
 import (
-	"log"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/provenance"
+	"github.com/illmade-knight/action-intention/pkg/textmatch"
 )
 
 // LocationType distinguishes between different kinds of locations.
@@ -18,14 +20,21 @@ const (
 	LocationTypeShared LocationType = "SHARED" // A public, shared location (e.g., "Fairview Park").
 )
 
-// MatchResult indicates the confidence of a match.
-type MatchResult string
+// nameThreshold is the name similarity below which two locations are not compared further.
+const nameThreshold = 0.8
 
-const (
-	MatchNone     MatchResult = "NONE"
-	MatchPossible MatchResult = "POSSIBLE"
-	MatchExact    MatchResult = "EXACT"
-)
+// MaxMatchDistanceKm is how far apart two locations with coordinates can be
+// and still match. Beyond it Match scores zero, so matching only needs the
+// local locations within this radius.
+const MaxMatchDistanceKm = 0.5
+
+// MatchScore rates how likely an incoming location is to be a local one, from
+// 0 (certainly not) to 1 (certainly), with a human-readable explanation.
+// Reconciler thresholds turn the score into a mapping decision.
+type MatchScore struct {
+	Score       float64 `json:"score"`
+	Explanation string  `json:"explanation"`
+}
 
 // LocationMatcher holds de-normalized data used to find a match.
 type LocationMatcher struct {
@@ -35,44 +44,88 @@ type LocationMatcher struct {
 	Lon      *float64 `json:"lon,omitempty"`
 }
 
-// Match compares the matcher against a local location to determine match quality.
-func (m *LocationMatcher) Match(local Location) MatchResult {
-	log.Printf("[Matcher Debug] Comparing INCOMING ('%s') vs LOCAL ('%s')", m.Name, local.Matcher.Name)
-
-	if !strings.EqualFold(m.Name, local.Matcher.Name) {
-		log.Println("[Matcher Debug] -> Name MISMATCH. Result: NONE")
-		return MatchNone
+// Match scores the matcher against a local location. The names must be similar;
+// coordinates then decide how close the match is, falling back to the category
+// when either side has none.
+func (m *LocationMatcher) Match(local Location) MatchScore {
+	similarity := textmatch.Similarity(m.Name, local.Matcher.Name)
+	if similarity < nameThreshold {
+		return MatchScore{Score: 0, Explanation: fmt.Sprintf("name differs (%.2f)", similarity)}
+	}
+	name := "name matches"
+	if similarity < 1 {
+		name = fmt.Sprintf("name similar (%.2f)", similarity)
 	}
-	log.Println("[Matcher Debug] -> Name MATCH.")
 
+	// The rest of the evidence is scaled by how alike the names are.
 	if m.Lat != nil && m.Lon != nil && local.Matcher.Lat != nil && local.Matcher.Lon != nil {
 		distanceKm := haversine(*m.Lat, *m.Lon, *local.Matcher.Lat, *local.Matcher.Lon)
-		if distanceKm <= 0.05 {
-			return MatchExact
+		explanation := fmt.Sprintf("%s, %.0f m apart", name, distanceKm*1000)
+		switch {
+		case distanceKm <= 0.05:
+			return MatchScore{Score: similarity, Explanation: explanation}
+		case distanceKm > MaxMatchDistanceKm:
+			return MatchScore{Score: 0, Explanation: explanation}
+		default:
+			// Fall from 0.85 at 50 m to 0.5 at MaxMatchDistanceKm.
+			return MatchScore{Score: similarity * (0.85 - 0.35*(distanceKm-0.05)/(MaxMatchDistanceKm-0.05)), Explanation: explanation}
 		}
-		if distanceKm > 0.5 {
-			return MatchNone
-		}
-		return MatchPossible
 	}
 
-	if strings.EqualFold(m.Category, local.Matcher.Category) {
-		log.Println("[Matcher Debug] -> Category MATCH. Result: EXACT")
-		return MatchExact
+	if textmatch.Normalize(m.Category) == textmatch.Normalize(local.Matcher.Category) {
+		return MatchScore{Score: 0.95 * similarity, Explanation: name + ", category matches"}
 	}
+	return MatchScore{Score: 0.6 * similarity, Explanation: fmt.Sprintf("%s, category differs (%q vs %q)", name, m.Category, local.Matcher.Category)}
+}
 
-	log.Println("[Matcher Debug] -> Category MISMATCH, but name matched. Result: POSSIBLE")
-	return MatchPossible
+// Coordinates is a point in decimal degrees (WGS 84).
+type Coordinates struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Address is a structured postal address. Any field may be empty.
+type Address struct {
+	Street     string `json:"street,omitempty"`
+	Locality   string `json:"locality,omitempty"` // Town or city
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+// String formats the non-empty address fields on one line, comma separated.
+func (a Address) String() string {
+	var parts []string
+	for _, part := range []string{a.Street, a.Locality, a.Region, a.PostalCode, a.Country} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
 }
 
 // Location represents a physical place in the system.
 type Location struct {
-	ID        uuid.UUID       `json:"id"`
-	Name      string          `json:"name"`
-	Category  string          `json:"category"`
-	GlobalID  *string         `json:"global_id,omitempty"` // For public, shared entities
-	Matcher   LocationMatcher `json:"matcher"`             // For matching user-generated entities
-	Type      LocationType    `json:"type"`
-	UserID    *string         `json:"user_id,omitempty"`
-	CreatedAt time.Time       `json:"created_at"`
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Category string    `json:"category"`
+	// Coordinates, Address and RadiusMeters are optional. Coordinates are
+	// copied into the Matcher, which is what matching and the spatial index use.
+	Coordinates *Coordinates `json:"coordinates,omitempty"`
+	Address     *Address     `json:"address,omitempty"`
+	// RadiusMeters is the extent of a place larger than a point, e.g. a park.
+	RadiusMeters *float64        `json:"radius_meters,omitempty"`
+	GlobalID     *string         `json:"global_id,omitempty"` // For public, shared entities
+	Matcher      LocationMatcher `json:"matcher"`             // For matching user-generated entities
+	Type         LocationType    `json:"type"`
+	UserID       *string         `json:"user_id,omitempty"`
+	// Provenance is set on a location created from another user's payload.
+	Provenance *provenance.Source `json:"provenance,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+// SetCoordinates records the location's coordinates and copies them into the Matcher.
+func (l *Location) SetCoordinates(c Coordinates) {
+	l.Coordinates = &c
+	l.Matcher.Lat, l.Matcher.Lon = &c.Lat, &c.Lon
 }
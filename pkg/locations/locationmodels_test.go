@@ -0,0 +1,35 @@
+package locations_test
+
+import (
+	"testing"
+
+	"github.com/illmade-knight/action-intention/pkg/locations"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocationMatcherCorpus(t *testing.T) {
+	lat, lon := 53.3498, -6.2603
+	nearLat := lat + 0.002 // About 220 m north.
+
+	testCases := []struct {
+		name     string
+		incoming locations.LocationMatcher
+		local    locations.LocationMatcher
+		min, max float64
+	}{
+		{"spelling variants in the same category", locations.LocationMatcher{Name: "Alice's Cafe", Category: "Cafe"}, locations.LocationMatcher{Name: "Alices Café", Category: "cafe"}, 0.9, 1},
+		{"same name, other category", locations.LocationMatcher{Name: "Corner Cafe", Category: "Cafe"}, locations.LocationMatcher{Name: "Corner Cafe", Category: "Bar"}, 0.5, 0.89},
+		{"typo in the same category", locations.LocationMatcher{Name: "Fairview Park", Category: "Park"}, locations.LocationMatcher{Name: "Fairveiw Park", Category: "Park"}, 0.5, 0.89},
+		{"same spot", locations.LocationMatcher{Name: "Corner Cafe", Lat: &lat, Lon: &lon}, locations.LocationMatcher{Name: "corner café", Lat: &lat, Lon: &lon}, 1, 1},
+		{"same name nearby", locations.LocationMatcher{Name: "Corner Cafe", Lat: &lat, Lon: &lon}, locations.LocationMatcher{Name: "Corner Cafe", Lat: &nearLat, Lon: &lon}, 0.5, 0.89},
+		{"different name", locations.LocationMatcher{Name: "Corner Cafe", Category: "Cafe"}, locations.LocationMatcher{Name: "Corner Shop", Category: "Cafe"}, 0, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			score := tc.incoming.Match(locations.Location{Matcher: tc.local})
+			assert.GreaterOrEqual(t, score.Score, tc.min, score.Explanation)
+			assert.LessOrEqual(t, score.Score, tc.max, score.Explanation)
+			assert.NotEmpty(t, score.Explanation)
+		})
+	}
+}
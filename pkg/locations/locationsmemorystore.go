@@ -4,40 +4,101 @@ package locations
 
 import (
 	"context"
-	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 )
 
 // InMemoryStore is a thread-safe, in-memory implementation of the Store interface.
 type InMemoryStore struct {
 	sync.RWMutex
 	locations map[uuid.UUID]Location
+	// cells is a spatial index of the locations with coordinates, keyed by
+	// their geohash cut to memIndexPrecision.
+	cells map[string]map[uuid.UUID]bool
 }
 
+// memIndexPrecision sets the grid cells of the in-memory spatial index to
+// roughly 1.2 km by 0.6 km.
+const memIndexPrecision = 6
+
 // NewInMemoryStore creates a new in-memory store.
 func NewInMemoryStore() *InMemoryStore {
 	return &InMemoryStore{
 		locations: make(map[uuid.UUID]Location),
+		cells:     make(map[string]map[uuid.UUID]bool),
 	}
 }
 
-// Add saves a new location to the store.
+// Add saves a new location to the store, replacing any with the same ID.
 func (s *InMemoryStore) Add(ctx context.Context, loc Location) error {
 	s.Lock()
 	defer s.Unlock()
-	s.locations[loc.ID] = loc
+	s.put(loc)
 	return nil
 }
 
+// Update replaces a stored location.
+func (s *InMemoryStore) Update(ctx context.Context, loc Location) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.locations[loc.ID]; !ok {
+		return apperr.Errorf(apperr.ErrNotFound, "location with ID %s not found", loc.ID)
+	}
+	s.put(loc)
+	return nil
+}
+
+// Delete removes a location from the store.
+func (s *InMemoryStore) Delete(ctx context.Context, id uuid.UUID) error {
+	s.Lock()
+	defer s.Unlock()
+	loc, ok := s.locations[id]
+	if !ok {
+		return apperr.Errorf(apperr.ErrNotFound, "location with ID %s not found", id)
+	}
+	s.unindex(loc)
+	delete(s.locations, id)
+	return nil
+}
+
+// put stores a location and indexes its coordinates. The caller holds the lock.
+func (s *InMemoryStore) put(loc Location) {
+	if old, ok := s.locations[loc.ID]; ok {
+		s.unindex(old)
+	}
+	s.locations[loc.ID] = loc
+	if hash, ok := LocationGeohash(loc); ok {
+		cell := hash[:memIndexPrecision]
+		if s.cells[cell] == nil {
+			s.cells[cell] = make(map[uuid.UUID]bool)
+		}
+		s.cells[cell][loc.ID] = true
+	}
+}
+
+// unindex removes a location from the spatial index. The caller holds the lock.
+func (s *InMemoryStore) unindex(loc Location) {
+	hash, ok := LocationGeohash(loc)
+	if !ok {
+		return
+	}
+	cell := hash[:memIndexPrecision]
+	delete(s.cells[cell], loc.ID)
+	if len(s.cells[cell]) == 0 {
+		delete(s.cells, cell)
+	}
+}
+
 // GetByID retrieves a location by its local UUID.
 func (s *InMemoryStore) GetByID(ctx context.Context, id uuid.UUID) (Location, error) {
 	s.RLock()
 	defer s.RUnlock()
 	loc, ok := s.locations[id]
 	if !ok {
-		return Location{}, fmt.Errorf("location with ID %s not found", id)
+		return Location{}, apperr.Errorf(apperr.ErrNotFound, "location with ID %s not found", id)
 	}
 	return loc, nil
 }
@@ -51,7 +112,7 @@ func (s *InMemoryStore) FindByGlobalID(ctx context.Context, globalID string) (Lo
 			return loc, nil
 		}
 	}
-	return Location{}, fmt.Errorf("location with global ID %s not found", globalID)
+	return Location{}, apperr.Errorf(apperr.ErrNotFound, "location with global ID %s not found", globalID)
 }
 
 // ListAllForMatching returns all locations for the purpose of running matcher logic.
@@ -66,8 +127,81 @@ func (s *InMemoryStore) ListAllForMatching(ctx context.Context) ([]Location, err
 	return allLocations, nil
 }
 
-// (Stubs for other list methods as they are not needed for the reconciler yet)
+// NearbyForMatching returns the locations with coordinates within radiusKm of
+// the point. Only the index cells around the point are examined.
+func (s *InMemoryStore) NearbyForMatching(ctx context.Context, lat, lon, radiusKm float64) ([]Location, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	seen := make(map[uuid.UUID]bool)
+	var nearby []Location
+	consider := func(ids map[uuid.UUID]bool) {
+		for id := range ids {
+			loc := s.locations[id]
+			if !seen[id] && WithinKm(loc, lat, lon, radiusKm) {
+				seen[id] = true
+				nearby = append(nearby, loc)
+			}
+		}
+	}
+	for _, prefix := range GeohashCoverage(lat, lon, radiusKm) {
+		if len(prefix) >= memIndexPrecision {
+			consider(s.cells[prefix[:memIndexPrecision]])
+			continue
+		}
+		// A coarse prefix spans many index cells.
+		for cell, ids := range s.cells {
+			if strings.HasPrefix(cell, prefix) {
+				consider(ids)
+			}
+		}
+	}
+	return nearby, nil
+}
+
+// ListWithoutCoordinatesForMatching returns the locations that have no
+// coordinates.
+func (s *InMemoryStore) ListWithoutCoordinatesForMatching(ctx context.Context) ([]Location, error) {
+	return s.filter(func(loc Location) bool {
+		_, ok := LocationGeohash(loc)
+		return !ok
+	}), nil
+}
+
+// ListByUserID retrieves all locations for a specific user.
 func (s *InMemoryStore) ListByUserID(ctx context.Context, userID string) ([]Location, error) {
-	return nil, nil
+	return s.filter(func(loc Location) bool {
+		return loc.UserID != nil && *loc.UserID == userID
+	}), nil
+}
+
+// ListShared retrieves all public, shared locations.
+func (s *InMemoryStore) ListShared(ctx context.Context) ([]Location, error) {
+	return s.filter(func(loc Location) bool {
+		return loc.Type == LocationTypeShared
+	}), nil
+}
+
+// Search returns the locations whose names start with namePrefix, ignoring
+// case, in the given category, ordered by name.
+func (s *InMemoryStore) Search(ctx context.Context, namePrefix, category string) ([]Location, error) {
+	prefix := SearchKey(namePrefix)
+	results := s.filter(func(loc Location) bool {
+		return strings.HasPrefix(SearchKey(loc.Name), prefix) && (category == "" || loc.Category == category)
+	})
+	SortByName(results)
+	return results, nil
+}
+
+// filter returns the stored locations that satisfy keep.
+func (s *InMemoryStore) filter(keep func(Location) bool) []Location {
+	s.RLock()
+	defer s.RUnlock()
+	var results []Location
+	for _, loc := range s.locations {
+		if keep(loc) {
+			results = append(results, loc)
+		}
+	}
+	return results
 }
-func (s *InMemoryStore) ListShared(ctx context.Context) ([]Location, error) { return nil, nil }
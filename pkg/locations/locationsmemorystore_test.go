@@ -0,0 +1,14 @@
+package locations_test
+
+import (
+	"testing"
+
+	"github.com/illmade-knight/action-intention/pkg/locations"
+	"github.com/illmade-knight/action-intention/pkg/storetest"
+)
+
+func TestInMemoryStore_Conformance(t *testing.T) {
+	storetest.RunLocationStoreTests(t, func(t *testing.T) locations.Store {
+		return locations.NewInMemoryStore()
+	})
+}
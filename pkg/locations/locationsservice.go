@@ -4,50 +4,131 @@ package locations
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 )
 
 // Service provides the business logic for managing locations.
 type Service struct {
-	store Store
+	store    Store
+	geocoder Geocoder
 }
 
-func NewService(store Store) *Service {
-	return &Service{store: store}
+// ServiceOption configures a Service.
+type ServiceOption func(*Service)
+
+// WithGeocoder fills in the coordinates of new locations that have an address
+// but no coordinates.
+func WithGeocoder(g Geocoder) ServiceOption {
+	return func(s *Service) {
+		s.geocoder = g
+	}
+}
+
+func NewService(store Store, opts ...ServiceOption) *Service {
+	s := &Service{store: store}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// LocationDetails describes a new location. Everything but Name is optional.
+type LocationDetails struct {
+	Name         string
+	Category     string
+	Coordinates  *Coordinates
+	Address      *Address
+	RadiusMeters *float64
 }
 
 // AddUserLocation creates a new location private to a specific user.
 func (s *Service) AddUserLocation(ctx context.Context, userID, name, category string) (Location, error) {
-	loc := Location{
-		ID:        uuid.New(),
-		Name:      name,
-		Category:  category,
-		Matcher:   LocationMatcher{Name: name, Category: category},
-		Type:      LocationTypeUser,
-		UserID:    &userID,
-		CreatedAt: time.Now(),
-	}
-	err := s.store.Add(ctx, loc)
-	return loc, err
+	return s.AddUserLocationWithDetails(ctx, userID, LocationDetails{Name: name, Category: category})
 }
 
 // AddSharedLocation creates a new public location available to everyone.
 func (s *Service) AddSharedLocation(ctx context.Context, name, category string) (Location, error) {
-	loc := Location{
-		ID:        uuid.New(),
-		Name:      name,
-		Category:  category,
-		Matcher:   LocationMatcher{Name: name, Category: category},
-		Type:      LocationTypeShared,
-		UserID:    nil, // No specific owner
-		CreatedAt: time.Now(),
-	}
-	err := s.store.Add(ctx, loc)
+	return s.AddSharedLocationWithDetails(ctx, LocationDetails{Name: name, Category: category})
+}
+
+// AddUserLocationWithDetails creates a new location private to a specific user.
+func (s *Service) AddUserLocationWithDetails(ctx context.Context, userID string, details LocationDetails) (Location, error) {
+	loc, err := s.newLocation(ctx, details)
+	if err != nil {
+		return Location{}, err
+	}
+	loc.Type = LocationTypeUser
+	loc.UserID = &userID
+	err = s.store.Add(ctx, loc)
+	return loc, err
+}
+
+// AddSharedLocationWithDetails creates a new public location available to everyone.
+func (s *Service) AddSharedLocationWithDetails(ctx context.Context, details LocationDetails) (Location, error) {
+	loc, err := s.newLocation(ctx, details)
+	if err != nil {
+		return Location{}, err
+	}
+	loc.Type = LocationTypeShared
+	loc.UserID = nil // No specific owner
+	err = s.store.Add(ctx, loc)
 	return loc, err
 }
 
+// newLocation builds a location from its details, geocoding the address if
+// there are no coordinates. An address the geocoder does not know leaves the
+// location without coordinates; any other geocoding error is returned.
+func (s *Service) newLocation(ctx context.Context, details LocationDetails) (Location, error) {
+	loc := Location{
+		ID:           uuid.New(),
+		Name:         details.Name,
+		Category:     details.Category,
+		Address:      details.Address,
+		RadiusMeters: details.RadiusMeters,
+		Matcher:      LocationMatcher{Name: details.Name, Category: details.Category},
+		CreatedAt:    time.Now(),
+	}
+	switch {
+	case details.Coordinates != nil:
+		loc.SetCoordinates(*details.Coordinates)
+	case details.Address != nil && s.geocoder != nil:
+		c, err := s.geocoder.Geocode(ctx, *details.Address)
+		if err == nil {
+			loc.SetCoordinates(c)
+		} else if !errors.Is(err, ErrAddressNotFound) {
+			return Location{}, fmt.Errorf("failed to geocode %q: %w", details.Address.String(), err)
+		}
+	}
+	return loc, nil
+}
+
+// UpdateLocation replaces a stored location, first bringing its Matcher in
+// line with its name, category and coordinates.
+func (s *Service) UpdateLocation(ctx context.Context, loc Location) error {
+	loc.Matcher.Name = loc.Name
+	loc.Matcher.Category = loc.Category
+	if loc.Coordinates != nil {
+		loc.SetCoordinates(*loc.Coordinates)
+	}
+	return s.store.Update(ctx, loc)
+}
+
+// DeleteLocation removes a location.
+func (s *Service) DeleteLocation(ctx context.Context, id uuid.UUID) error {
+	return s.store.Delete(ctx, id)
+}
+
+// SearchLocations finds locations by the start of their name and, unless it
+// is empty, their category.
+func (s *Service) SearchLocations(ctx context.Context, namePrefix, category string) ([]Location, error) {
+	return s.store.Search(ctx, namePrefix, category)
+}
+
 func (s *Service) GetStore() Store {
 	return s.store
 }
@@ -56,3 +137,20 @@ func (s *Service) GetStore() Store {
 func (s *Service) GetLocation(ctx context.Context, id uuid.UUID) (Location, error) {
 	return s.store.GetByID(ctx, id)
 }
+
+// DistanceBetween returns the distance in kilometres between two stored locations.
+func (s *Service) DistanceBetween(ctx context.Context, a, b uuid.UUID) (float64, error) {
+	locA, err := s.store.GetByID(ctx, a)
+	if err != nil {
+		return 0, err
+	}
+	locB, err := s.store.GetByID(ctx, b)
+	if err != nil {
+		return 0, err
+	}
+	distance, ok := DistanceKm(locA, locB)
+	if !ok {
+		return 0, apperr.Errorf(apperr.ErrInvalid, "locations %s and %s do not both have coordinates", a, b)
+	}
+	return distance, nil
+}
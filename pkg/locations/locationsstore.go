@@ -4,16 +4,49 @@ package locations
 
 import (
 	"context"
+	"sort"
+	"strings"
 
 	"github.com/google/uuid"
 )
 
 // Store is the interface for storing and retrieving locations.
 type Store interface {
+	// Add saves a location, replacing any stored location with the same ID.
 	Add(ctx context.Context, loc Location) error
 	GetByID(ctx context.Context, id uuid.UUID) (Location, error)
+	// Update replaces a stored location. It fails if there is no location with the ID.
+	Update(ctx context.Context, loc Location) error
+	// Delete removes a location. It fails if there is no location with the ID.
+	Delete(ctx context.Context, id uuid.UUID) error
+	// Search returns the locations whose names start with namePrefix, ignoring
+	// case, in the given category, ordered by name. An empty namePrefix or
+	// category matches every location.
+	Search(ctx context.Context, namePrefix, category string) ([]Location, error)
 	ListByUserID(ctx context.Context, userID string) ([]Location, error)
 	ListShared(ctx context.Context) ([]Location, error)
 	FindByGlobalID(ctx context.Context, globalID string) (Location, error)
 	ListAllForMatching(ctx context.Context) ([]Location, error)
+	// NearbyForMatching returns the locations with coordinates within radiusKm
+	// of the point, using a spatial index rather than a full scan.
+	NearbyForMatching(ctx context.Context, lat, lon, radiusKm float64) ([]Location, error)
+	// ListWithoutCoordinatesForMatching returns the locations that have no
+	// coordinates, which NearbyForMatching cannot find, without a full scan.
+	ListWithoutCoordinatesForMatching(ctx context.Context) ([]Location, error)
+}
+
+// SearchKey is the form of a location name that Search compares prefixes against.
+func SearchKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// SortByName orders locations by SearchKey, then by ID, the order Search returns.
+func SortByName(locs []Location) {
+	sort.Slice(locs, func(i, j int) bool {
+		a, b := SearchKey(locs[i].Name), SearchKey(locs[j].Name)
+		if a != b {
+			return a < b
+		}
+		return locs[i].ID.String() < locs[j].ID.String()
+	})
 }
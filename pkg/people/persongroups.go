@@ -0,0 +1,81 @@
+// FILE: pkg/people/persongroups.go
+
+package people
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// GroupLookup fetches a group by ID. Store.GetGroup satisfies it, as does a
+// read inside a store transaction.
+type GroupLookup func(ctx context.Context, id uuid.UUID) (Group, error)
+
+// ExpandGroups returns the given groups followed by every group nested within
+// them, each once, in depth-first order.
+func ExpandGroups(ctx context.Context, lookup GroupLookup, groupIDs ...uuid.UUID) ([]Group, error) {
+	var groups []Group
+	visited := make(map[uuid.UUID]bool)
+	var visit func(id uuid.UUID) error
+	visit = func(id uuid.UUID) error {
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+		g, err := lookup(ctx, id)
+		if err != nil {
+			return err
+		}
+		groups = append(groups, g)
+		for _, subgroupID := range g.SubgroupIDs {
+			if err := visit(subgroupID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, id := range groupIDs {
+		if err := visit(id); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// ResolveMembers flattens groups, including any nested groups, into the IDs of
+// the people they contain. Each person appears once, in the order first found.
+func ResolveMembers(ctx context.Context, lookup GroupLookup, groupIDs ...uuid.UUID) ([]uuid.UUID, error) {
+	groups, err := ExpandGroups(ctx, lookup, groupIDs...)
+	if err != nil {
+		return nil, err
+	}
+	var members []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, g := range groups {
+		for _, personID := range g.MemberIDs {
+			if !seen[personID] {
+				seen[personID] = true
+				members = append(members, personID)
+			}
+		}
+	}
+	return members, nil
+}
+
+// CheckSubgroup returns ErrGroupCycle if nesting subgroupID inside groupID
+// would make a group contain itself, that is if groupID is subgroupID or is
+// already nested within it.
+func CheckSubgroup(ctx context.Context, lookup GroupLookup, groupID, subgroupID uuid.UUID) error {
+	nested, err := ExpandGroups(ctx, lookup, subgroupID)
+	if err != nil {
+		return err
+	}
+	for _, g := range nested {
+		if g.ID == groupID {
+			return fmt.Errorf("nest group %s in %s: %w", subgroupID, groupID, ErrGroupCycle)
+		}
+	}
+	return nil
+}
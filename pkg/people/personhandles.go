@@ -0,0 +1,134 @@
+// FILE: pkg/people/personhandles.go
+
+package people
+
+import (
+	"net/mail"
+	"strings"
+
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+)
+
+// HandleKind is the type of identifier a Handle holds.
+type HandleKind string
+
+const (
+	// HandleEmail is an email address, stored lower-cased.
+	HandleEmail HandleKind = "email"
+	// HandlePhone is a phone number in E.164 form, such as "+353861234567".
+	HandlePhone HandleKind = "phone"
+	// HandleUserID is the person's user ID on the routing service, the ID
+	// intentions are shared to.
+	HandleUserID HandleKind = "user_id"
+)
+
+// Handle is one way of reaching or identifying a person. Two handles refer to
+// the same person when their kinds and normalized values are equal.
+type Handle struct {
+	Kind  HandleKind `json:"kind"`
+	Value string     `json:"value"`
+}
+
+// NewHandle validates a handle and puts its value into normalized form. An
+// unknown kind or a value that cannot be normalized is apperr.ErrInvalid.
+func NewHandle(kind HandleKind, value string) (Handle, error) {
+	normalized, err := normalizeHandle(kind, value)
+	if err != nil {
+		return Handle{}, err
+	}
+	return Handle{Kind: kind, Value: normalized}, nil
+}
+
+// Key is the handle as a single normalized string, such as
+// "email:alice@example.com", for stores that index handles.
+func (h Handle) Key() string {
+	return string(h.Kind) + ":" + h.normalizedValue()
+}
+
+// Equal reports whether two handles identify the same person. Values are
+// normalized first, so handles received from other systems compare correctly.
+func (h Handle) Equal(other Handle) bool {
+	if h.Kind != other.Kind {
+		return false
+	}
+	return h.normalizedValue() == other.normalizedValue()
+}
+
+func (h Handle) normalizedValue() string {
+	if normalized, err := normalizeHandle(h.Kind, h.Value); err == nil {
+		return normalized
+	}
+	return h.Value
+}
+
+func normalizeHandle(kind HandleKind, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch kind {
+	case HandleEmail:
+		addr, err := mail.ParseAddress(value)
+		if err != nil || addr.Address != value {
+			return "", apperr.Errorf(apperr.ErrInvalid, "invalid email address: %q", value)
+		}
+		return strings.ToLower(value), nil
+	case HandlePhone:
+		return normalizePhone(value)
+	case HandleUserID:
+		if value == "" {
+			return "", apperr.Errorf(apperr.ErrInvalid, "user ID cannot be empty")
+		}
+		return value, nil
+	default:
+		return "", apperr.Errorf(apperr.ErrInvalid, "unknown handle kind: %q", kind)
+	}
+}
+
+// normalizePhone converts an international number to E.164. Spaces, dots,
+// dashes and brackets are dropped and a leading "00" is read as "+". Numbers
+// without a country code are rejected, since the country cannot be guessed.
+// Only ASCII digits are accepted.
+func normalizePhone(value string) (string, error) {
+	var digits strings.Builder
+	for i, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+		case r == ' ' || r == '.' || r == '-' || r == '(' || r == ')':
+		default:
+			return "", apperr.Errorf(apperr.ErrInvalid, "invalid phone number: %q", value)
+		}
+	}
+	number := digits.String()
+	switch {
+	case strings.HasPrefix(value, "+"):
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	default:
+		return "", apperr.Errorf(apperr.ErrInvalid, "phone number %q must include a country code", value)
+	}
+	// E.164 allows at most 15 digits, and no country code starts with 0.
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", apperr.Errorf(apperr.ErrInvalid, "invalid phone number: %q", value)
+	}
+	return "+" + number, nil
+}
+
+// MergeHandles returns a followed by the handles in b that a does not already have.
+func MergeHandles(a, b []Handle) []Handle {
+	merged := append([]Handle(nil), a...)
+	for _, h := range b {
+		if !containsHandle(merged, h) {
+			merged = append(merged, h)
+		}
+	}
+	return merged
+}
+
+func containsHandle(handles []Handle, h Handle) bool {
+	for _, existing := range handles {
+		if existing.Equal(h) {
+			return true
+		}
+	}
+	return false
+}
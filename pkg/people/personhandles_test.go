@@ -0,0 +1,87 @@
+package people_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/people"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHandle(t *testing.T) {
+	testCases := []struct {
+		name  string
+		kind  people.HandleKind
+		value string
+		want  string // empty if the handle is invalid
+	}{
+		{"email is lower-cased", people.HandleEmail, " Alice@Example.COM ", "alice@example.com"},
+		{"email with a display name", people.HandleEmail, "Alice <alice@example.com>", ""},
+		{"not an email", people.HandleEmail, "alice", ""},
+		{"phone with spacing", people.HandlePhone, "+353 (86) 123-4567", "+353861234567"},
+		{"phone with 00 prefix", people.HandlePhone, "0044 20 7946 0958", "+442079460958"},
+		{"phone without country code", people.HandlePhone, "086 123 4567", ""},
+		{"phone too long", people.HandlePhone, "+1234567890123456", ""},
+		{"phone with letters", people.HandlePhone, "+353 86 CALL ME", ""},
+		{"phone with non-ASCII digits", people.HandlePhone, "+٣٥٣ ٨٦ ١٢٣ ٤٥٦٧", ""},
+		{"phone with fullwidth digits", people.HandlePhone, "+３５３８６１２３４５６７", ""},
+		{"user ID", people.HandleUserID, " user-alice ", "user-alice"},
+		{"empty user ID", people.HandleUserID, "  ", ""},
+		{"unknown kind", people.HandleKind("fax"), "+353861234567", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := people.NewHandle(tc.kind, tc.value)
+			if tc.want == "" {
+				assert.ErrorIs(t, err, apperr.ErrInvalid)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, people.Handle{Kind: tc.kind, Value: tc.want}, h)
+		})
+	}
+}
+
+func TestService_Handles(t *testing.T) {
+	ctx := context.Background()
+	svc := people.NewService(people.NewInMemoryStore())
+
+	alice, err := svc.CreatePerson(ctx, "Alice", people.Handle{Kind: people.HandleEmail, Value: "Alice@Example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []people.Handle{{Kind: people.HandleEmail, Value: "alice@example.com"}}, alice.Matcher.Handles)
+	assert.Equal(t, "Alice", alice.Matcher.Name)
+
+	_, err = svc.CreatePerson(ctx, "Alice Again", people.Handle{Kind: people.HandleEmail, Value: "alice@example.com"})
+	assert.ErrorIs(t, err, apperr.ErrConflict, "a handle belongs to one person")
+	_, err = svc.CreatePerson(ctx, "Nobody", people.Handle{Kind: people.HandlePhone, Value: "12"})
+	assert.ErrorIs(t, err, apperr.ErrInvalid)
+
+	alice, err = svc.AddHandle(ctx, alice.ID, people.HandlePhone, "+353 86 123 4567")
+	require.NoError(t, err)
+	alice, err = svc.AddHandle(ctx, alice.ID, people.HandlePhone, "00353861234567")
+	require.NoError(t, err, "adding a handle the person already has is a no-op")
+	assert.Len(t, alice.Matcher.Handles, 2)
+
+	found, err := svc.FindByHandle(ctx, people.HandlePhone, "+353-86-123-4567")
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, found.ID)
+
+	_, ok := alice.RecipientID()
+	assert.False(t, ok, "email and phone handles cannot be shared to")
+	alice, err = svc.LinkUser(ctx, alice.ID, "user-alice")
+	require.NoError(t, err)
+	recipient, ok := alice.RecipientID()
+	require.True(t, ok)
+	assert.Equal(t, "user-alice", recipient)
+	found, err = svc.FindByHandle(ctx, people.HandleUserID, "user-alice")
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, found.ID)
+
+	alice, err = svc.RemoveHandle(ctx, alice.ID, people.Handle{Kind: people.HandleEmail, Value: "ALICE@example.com"})
+	require.NoError(t, err)
+	assert.Len(t, alice.Matcher.Handles, 2)
+	_, err = svc.FindByHandle(ctx, people.HandleEmail, "alice@example.com")
+	assert.ErrorIs(t, err, apperr.ErrNotFound)
+}
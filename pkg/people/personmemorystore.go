@@ -4,10 +4,11 @@ package people
 
 import (
 	"context"
-	"fmt"
+	"slices"
 	"sync"
 
 	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 )
 
 type InMemoryStore struct {
@@ -37,7 +38,7 @@ func (s *InMemoryStore) GetPerson(ctx context.Context, id uuid.UUID) (Person, er
 	defer s.RUnlock()
 	p, ok := s.people[id]
 	if !ok {
-		return Person{}, fmt.Errorf("person %s not found", id)
+		return Person{}, apperr.Errorf(apperr.ErrNotFound, "person with ID %s not found", id)
 	}
 	return p, nil
 }
@@ -50,7 +51,18 @@ func (s *InMemoryStore) FindByGlobalID(ctx context.Context, globalID string) (Pe
 			return p, nil
 		}
 	}
-	return Person{}, fmt.Errorf("person with global ID %s not found", globalID)
+	return Person{}, apperr.Errorf(apperr.ErrNotFound, "person with global ID %s not found", globalID)
+}
+
+func (s *InMemoryStore) FindByHandle(ctx context.Context, h Handle) (Person, error) {
+	s.RLock()
+	defer s.RUnlock()
+	for _, p := range s.people {
+		if containsHandle(p.Matcher.Handles, h) {
+			return p, nil
+		}
+	}
+	return Person{}, apperr.Errorf(apperr.ErrNotFound, "person with handle %s not found", h.Key())
 }
 
 func (s *InMemoryStore) ListAllForMatching(ctx context.Context) ([]Person, error) {
@@ -77,17 +89,48 @@ func (s *InMemoryStore) GetGroup(ctx context.Context, id uuid.UUID) (Group, erro
 	defer s.RUnlock()
 	g, ok := s.groups[id]
 	if !ok {
-		return Group{}, fmt.Errorf("group with ID %s not found", id)
+		return Group{}, apperr.Errorf(apperr.ErrNotFound, "group with ID %s not found", id)
 	}
 	return g, nil
 }
 
+func (s *InMemoryStore) RenameGroup(ctx context.Context, id uuid.UUID, name string) error {
+	s.Lock()
+	defer s.Unlock()
+	g, ok := s.groups[id]
+	if !ok {
+		return apperr.Errorf(apperr.ErrNotFound, "group with ID %s not found", id)
+	}
+	g.Name = name
+	s.groups[id] = g
+	return nil
+}
+
+func (s *InMemoryStore) DeleteGroup(ctx context.Context, id uuid.UUID) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.groups[id]; !ok {
+		return apperr.Errorf(apperr.ErrNotFound, "group with ID %s not found", id)
+	}
+	delete(s.groups, id)
+	for parentID, parent := range s.groups {
+		if slices.Contains(parent.SubgroupIDs, id) {
+			parent.SubgroupIDs = without(parent.SubgroupIDs, id)
+			s.groups[parentID] = parent
+		}
+	}
+	return nil
+}
+
 func (s *InMemoryStore) AddMemberToGroup(ctx context.Context, groupID, personID uuid.UUID) error {
 	s.Lock()
 	defer s.Unlock()
 	g, ok := s.groups[groupID]
 	if !ok {
-		return fmt.Errorf("group with ID %s not found", groupID)
+		return apperr.Errorf(apperr.ErrNotFound, "group with ID %s not found", groupID)
+	}
+	if _, ok := s.people[personID]; !ok {
+		return apperr.Errorf(apperr.ErrNotFound, "person with ID %s not found", personID)
 	}
 	for _, memberID := range g.MemberIDs {
 		if memberID == personID {
@@ -98,3 +141,72 @@ func (s *InMemoryStore) AddMemberToGroup(ctx context.Context, groupID, personID
 	s.groups[groupID] = g
 	return nil
 }
+
+func (s *InMemoryStore) RemoveMemberFromGroup(ctx context.Context, groupID, personID uuid.UUID) error {
+	s.Lock()
+	defer s.Unlock()
+	g, ok := s.groups[groupID]
+	if !ok {
+		return apperr.Errorf(apperr.ErrNotFound, "group with ID %s not found", groupID)
+	}
+	g.MemberIDs = without(g.MemberIDs, personID)
+	s.groups[groupID] = g
+	return nil
+}
+
+func (s *InMemoryStore) AddSubgroup(ctx context.Context, groupID, subgroupID uuid.UUID) error {
+	s.Lock()
+	defer s.Unlock()
+	g, ok := s.groups[groupID]
+	if !ok {
+		return apperr.Errorf(apperr.ErrNotFound, "group with ID %s not found", groupID)
+	}
+	if slices.Contains(g.SubgroupIDs, subgroupID) {
+		return nil
+	}
+	if err := CheckSubgroup(ctx, s.lockedGroup, groupID, subgroupID); err != nil {
+		return err
+	}
+	g.SubgroupIDs = append(slices.Clip(g.SubgroupIDs), subgroupID)
+	s.groups[groupID] = g
+	return nil
+}
+
+func (s *InMemoryStore) RemoveSubgroup(ctx context.Context, groupID, subgroupID uuid.UUID) error {
+	s.Lock()
+	defer s.Unlock()
+	g, ok := s.groups[groupID]
+	if !ok {
+		return apperr.Errorf(apperr.ErrNotFound, "group with ID %s not found", groupID)
+	}
+	g.SubgroupIDs = without(g.SubgroupIDs, subgroupID)
+	s.groups[groupID] = g
+	return nil
+}
+
+func (s *InMemoryStore) ListGroupsForPerson(ctx context.Context, personID uuid.UUID) ([]Group, error) {
+	s.RLock()
+	defer s.RUnlock()
+	var groups []Group
+	for _, g := range s.groups {
+		if slices.Contains(g.MemberIDs, personID) {
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+// lockedGroup is a GroupLookup for use while the store's lock is held.
+func (s *InMemoryStore) lockedGroup(ctx context.Context, id uuid.UUID) (Group, error) {
+	g, ok := s.groups[id]
+	if !ok {
+		return Group{}, apperr.Errorf(apperr.ErrNotFound, "group with ID %s not found", id)
+	}
+	return g, nil
+}
+
+// without returns a copy of ids with id removed, so slices already handed to
+// callers are not modified.
+func without(ids []uuid.UUID, id uuid.UUID) []uuid.UUID {
+	return slices.DeleteFunc(slices.Clone(ids), func(other uuid.UUID) bool { return other == id })
+}
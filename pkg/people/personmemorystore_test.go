@@ -0,0 +1,14 @@
+package people_test
+
+import (
+	"testing"
+
+	"github.com/illmade-knight/action-intention/pkg/people"
+	"github.com/illmade-knight/action-intention/pkg/storetest"
+)
+
+func TestInMemoryStore_Conformance(t *testing.T) {
+	storetest.RunPeopleStoreTests(t, func(t *testing.T) people.Store {
+		return people.NewInMemoryStore()
+	})
+}
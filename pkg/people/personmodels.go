@@ -3,60 +3,110 @@
 package people
 
 import (
-	"strings"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/provenance"
 )
 
-// MatchResult mirrors the one in the locations package.
-type MatchResult string
+// nameThreshold is the name similarity below which two people are not compared further.
+const nameThreshold = 0.8
 
-const (
-	MatchNone     MatchResult = "NONE"
-	MatchPossible MatchResult = "POSSIBLE"
-	MatchExact    MatchResult = "EXACT"
-)
+// MatchScore mirrors the one in the locations package.
+type MatchScore struct {
+	Score       float64 `json:"score"`
+	Explanation string  `json:"explanation"`
+}
 
 // PersonMatcher holds data for finding a corresponding person on another system.
 type PersonMatcher struct {
-	Name   string  `json:"name"`
-	Handle *string `json:"handle,omitempty"` // e.g., email or phone
+	Name    string   `json:"name"`
+	Handles []Handle `json:"handles,omitempty"` // e.g., email, phone or user ID
 }
 
-// Match compares the matcher against a local person.
-func (m *PersonMatcher) Match(local Person) MatchResult {
-	// Strategy 1: An exact, case-insensitive match on the handle is definitive.
-	if m.Handle != nil && local.Matcher.Handle != nil {
-		if strings.EqualFold(*m.Handle, *local.Matcher.Handle) {
-			return MatchExact
+// Match scores the matcher against a local person.
+func (m *PersonMatcher) Match(local Person) MatchScore {
+	// Strategy 1: Any shared handle is definitive.
+	for _, h := range m.Handles {
+		if containsHandle(local.Matcher.Handles, h) {
+			return MatchScore{Score: 1, Explanation: handleLabel(h.Kind) + " matches"}
+		}
+	}
+	// Having handles of the same kind, none of them shared, counts against a match.
+	handlesDiffer := false
+	for _, h := range m.Handles {
+		for _, other := range local.Matcher.Handles {
+			if h.Kind == other.Kind {
+				handlesDiffer = true
+			}
 		}
 	}
 
-	// Strategy 2: An exact, case-insensitive match on the name is a strong possibility.
-	if strings.EqualFold(m.Name, local.Matcher.Name) {
-		return MatchPossible
+	// Strategy 2: A similar name is a strong possibility, scaled by how similar
+	// and a little weaker if the two have different handles.
+	similarity, viaNickname := NameSimilarity(m.Name, local.Matcher.Name)
+	if similarity >= nameThreshold {
+		explanation := "name matches"
+		switch {
+		case viaNickname:
+			explanation = fmt.Sprintf("name matches by nickname (%.2f)", similarity)
+		case similarity < 1:
+			explanation = fmt.Sprintf("name similar (%.2f)", similarity)
+		}
+		if handlesDiffer {
+			return MatchScore{Score: 0.5 * similarity, Explanation: explanation + ", handles differ"}
+		}
+		return MatchScore{Score: 0.6 * similarity, Explanation: explanation}
 	}
 
-	return MatchNone
+	return MatchScore{Score: 0, Explanation: "name differs"}
 }
 
-// Person represents an individual. This is distinct from a system User,
-// though they can be linked.
+// handleLabel names a handle kind for match explanations.
+func handleLabel(kind HandleKind) string {
+	switch kind {
+	case HandleUserID:
+		return "user ID"
+	default:
+		return string(kind)
+	}
+}
 
+// Person represents an individual. This is distinct from a system User,
+// though they can be linked through UserID.
 type Person struct {
-	ID        uuid.UUID     `json:"id"`
-	Name      string        `json:"name"`
-	GlobalID  *string       `json:"global_id,omitempty"`
-	Matcher   PersonMatcher `json:"matcher"`
-	UserID    *string       `json:"user_id,omitempty"`
-	CreatedAt time.Time     `json:"created_at"`
+	ID       uuid.UUID     `json:"id"`
+	Name     string        `json:"name"`
+	GlobalID *string       `json:"global_id,omitempty"`
+	Matcher  PersonMatcher `json:"matcher"`
+	UserID   *string       `json:"user_id,omitempty"`
+	// Provenance is set on a person created from another user's payload.
+	Provenance *provenance.Source `json:"provenance,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+// RecipientID returns the routing user ID to share intentions with: the linked
+// UserID, or failing that the person's first user ID handle.
+func (p Person) RecipientID() (string, bool) {
+	if p.UserID != nil && *p.UserID != "" {
+		return *p.UserID, true
+	}
+	for _, h := range p.Matcher.Handles {
+		if h.Kind == HandleUserID {
+			return h.Value, true
+		}
+	}
+	return "", false
 }
 
-// Group represents a collection of people.
+// Group represents a collection of people. A group can also contain other
+// groups, whose members count as its own; ResolveMembers flattens them.
 type Group struct {
-	ID        uuid.UUID
-	Name      string
-	MemberIDs []uuid.UUID // A list of Person IDs.
-	CreatedAt time.Time
+	ID          uuid.UUID
+	Name        string
+	MemberIDs   []uuid.UUID // A list of Person IDs.
+	SubgroupIDs []uuid.UUID // A list of nested Group IDs.
+	Provenance  *provenance.Source
+	CreatedAt   time.Time
 }
@@ -0,0 +1,38 @@
+package people_test
+
+import (
+	"testing"
+
+	"github.com/illmade-knight/action-intention/pkg/people"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPersonMatcherCorpus(t *testing.T) {
+	email := func(s string) []people.Handle { return []people.Handle{{Kind: people.HandleEmail, Value: s}} }
+	phone := func(s string) []people.Handle { return []people.Handle{{Kind: people.HandlePhone, Value: s}} }
+
+	testCases := []struct {
+		name     string
+		incoming people.PersonMatcher
+		local    people.PersonMatcher
+		min, max float64
+	}{
+		{"same handle", people.PersonMatcher{Name: "Jim", Handles: email("jim@example.com")}, people.PersonMatcher{Name: "James Byrne", Handles: email("JIM@example.com")}, 1, 1},
+		{"same phone, differently written", people.PersonMatcher{Name: "Jim", Handles: phone("00353 86 123 4567")}, people.PersonMatcher{Name: "Seamus", Handles: append(email("seamus@example.com"), phone("+353861234567")...)}, 1, 1},
+		{"handles of different kinds", people.PersonMatcher{Name: "Sam", Handles: email("sam@a.com")}, people.PersonMatcher{Name: "Sam", Handles: phone("+353861234567")}, 0.6, 0.6},
+		{"same name", people.PersonMatcher{Name: "Jim Byrne"}, people.PersonMatcher{Name: "jim byrne"}, 0.6, 0.6},
+		{"nickname", people.PersonMatcher{Name: "Jim"}, people.PersonMatcher{Name: "James"}, 0.5, 0.59},
+		{"nickname with surname", people.PersonMatcher{Name: "Bill O'Brien"}, people.PersonMatcher{Name: "William OBrien"}, 0.5, 0.59},
+		{"accented name", people.PersonMatcher{Name: "Zoe"}, people.PersonMatcher{Name: "Zoë"}, 0.6, 0.6},
+		{"same name, different handles", people.PersonMatcher{Name: "Sam", Handles: email("sam@a.com")}, people.PersonMatcher{Name: "Sam", Handles: email("sam@b.com")}, 0.5, 0.5},
+		{"different people", people.PersonMatcher{Name: "Jim"}, people.PersonMatcher{Name: "Tim"}, 0, 0},
+		{"different surname", people.PersonMatcher{Name: "Jim Byrne"}, people.PersonMatcher{Name: "James Murphy"}, 0, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			score := tc.incoming.Match(people.Person{Matcher: tc.local})
+			assert.GreaterOrEqual(t, score.Score, tc.min, score.Explanation)
+			assert.LessOrEqual(t, score.Score, tc.max, score.Explanation)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// FILE: people/nicknames.go
+
+package people
+
+import (
+	"strings"
+
+	"github.com/illmade-knight/action-intention/pkg/textmatch"
+)
+
+// nicknames maps common short forms of English given names to the full name.
+// Names that are their own canonical form are not listed.
+var nicknames = map[string]string{
+	"alex": "alexander", "sandy": "alexander",
+	"andy": "andrew", "drew": "andrew",
+	"ben": "benjamin", "benny": "benjamin",
+	"bill": "william", "billy": "william", "will": "william", "willy": "william", "liam": "william",
+	"bob": "robert", "bobby": "robert", "rob": "robert", "robbie": "robert", "bert": "robert",
+	"cathy": "catherine", "kate": "catherine", "katie": "catherine", "kathy": "catherine", "cat": "catherine",
+	"chris": "christopher", "kit": "christopher",
+	"dan": "daniel", "danny": "daniel",
+	"dave": "david", "davy": "david",
+	"dick": "richard", "rich": "richard", "rick": "richard", "ricky": "richard",
+	"ed": "edward", "eddie": "edward", "ted": "edward", "ned": "edward",
+	"liz": "elizabeth", "lizzie": "elizabeth", "beth": "elizabeth", "betty": "elizabeth", "eliza": "elizabeth",
+	"jim": "james", "jimmy": "james", "jamie": "james",
+	"jen": "jennifer", "jenny": "jennifer",
+	"joe": "joseph", "joey": "joseph",
+	"jack": "john", "johnny": "john",
+	"jon":    "jonathan",
+	"maggie": "margaret", "meg": "margaret", "peggy": "margaret",
+	"matt": "matthew",
+	"mike": "michael", "mick": "michael", "mickey": "michael",
+	"nick": "nicholas", "nicky": "nicholas",
+	"pat": "patrick", "paddy": "patrick",
+	"pete": "peter",
+	"sam":  "samuel", "sammy": "samuel",
+	"steve": "stephen", "stevie": "stephen",
+	"sue": "susan", "susie": "susan",
+	"tom": "thomas", "tommy": "thomas",
+	"tony": "anthony",
+}
+
+// canonicalName replaces each word of a name with the full given name it is
+// short for, if any.
+func canonicalName(name string) string {
+	tokens := textmatch.Tokens(name)
+	for i, t := range tokens {
+		if full, ok := nicknames[t]; ok {
+			tokens[i] = full
+		}
+	}
+	return strings.Join(tokens, " ")
+}
+
+// nicknameWeight discounts a match that only holds once nicknames are expanded,
+// since "Jim" and "James" are not always the same person.
+const nicknameWeight = 0.9
+
+// NameSimilarity scores how alike two person names are, from 0 to 1. Names
+// that only agree once nicknames are expanded ("Jim Smith" and "James Smith")
+// score a little below an exact match; viaNickname reports when that happened.
+func NameSimilarity(a, b string) (score float64, viaNickname bool) {
+	direct := textmatch.Similarity(a, b)
+	expanded := nicknameWeight * textmatch.Similarity(canonicalName(a), canonicalName(b))
+	if expanded > direct {
+		return expanded, true
+	}
+	return direct, false
+}
@@ -4,9 +4,12 @@ package people
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 )
 
 // Service provides logic for managing people and groups.
@@ -18,24 +21,111 @@ func NewService(store Store) *Service {
 	return &Service{store: store}
 }
 
-// CreatePerson adds a new person to the system.
-func (s *Service) CreatePerson(ctx context.Context, name string) (Person, error) {
+// CreatePerson adds a new person to the system with any handles they can be
+// reached or recognized by. Handles are normalized, and one that already
+// belongs to someone else is apperr.ErrConflict.
+func (s *Service) CreatePerson(ctx context.Context, name string, handles ...Handle) (Person, error) {
 	p := Person{
 		ID:        uuid.New(),
 		Name:      name,
+		Matcher:   PersonMatcher{Name: name},
 		CreatedAt: time.Now(),
 	}
+	for _, h := range handles {
+		if err := s.addHandle(ctx, &p, h); err != nil {
+			return Person{}, err
+		}
+	}
 	err := s.store.AddPerson(ctx, p)
 	return p, err
 }
 
+// AddHandle gives a person another handle. Adding one they already have does
+// nothing; one that belongs to someone else is apperr.ErrConflict.
+func (s *Service) AddHandle(ctx context.Context, personID uuid.UUID, kind HandleKind, value string) (Person, error) {
+	p, err := s.store.GetPerson(ctx, personID)
+	if err != nil {
+		return Person{}, err
+	}
+	if err := s.addHandle(ctx, &p, Handle{Kind: kind, Value: value}); err != nil {
+		return Person{}, err
+	}
+	if err := s.store.AddPerson(ctx, p); err != nil {
+		return Person{}, err
+	}
+	return p, nil
+}
+
+// RemoveHandle takes a handle away from a person. Removing one they do not
+// have does nothing.
+func (s *Service) RemoveHandle(ctx context.Context, personID uuid.UUID, h Handle) (Person, error) {
+	p, err := s.store.GetPerson(ctx, personID)
+	if err != nil {
+		return Person{}, err
+	}
+	p.Matcher.Handles = slices.DeleteFunc(slices.Clone(p.Matcher.Handles), h.Equal)
+	if err := s.store.AddPerson(ctx, p); err != nil {
+		return Person{}, err
+	}
+	return p, nil
+}
+
+// LinkUser records that a person is the given system user. The user ID also
+// becomes one of their handles, so it is used for matching and sharing.
+func (s *Service) LinkUser(ctx context.Context, personID uuid.UUID, userID string) (Person, error) {
+	p, err := s.store.GetPerson(ctx, personID)
+	if err != nil {
+		return Person{}, err
+	}
+	if err := s.addHandle(ctx, &p, Handle{Kind: HandleUserID, Value: userID}); err != nil {
+		return Person{}, err
+	}
+	p.UserID = &userID
+	if err := s.store.AddPerson(ctx, p); err != nil {
+		return Person{}, err
+	}
+	return p, nil
+}
+
+// FindByHandle returns the person with the given handle, normalizing the
+// value first.
+func (s *Service) FindByHandle(ctx context.Context, kind HandleKind, value string) (Person, error) {
+	h, err := NewHandle(kind, value)
+	if err != nil {
+		return Person{}, err
+	}
+	return s.store.FindByHandle(ctx, h)
+}
+
+// addHandle normalizes h and appends it to p's handles, checking that nobody
+// else has it.
+func (s *Service) addHandle(ctx context.Context, p *Person, h Handle) error {
+	h, err := NewHandle(h.Kind, h.Value)
+	if err != nil {
+		return err
+	}
+	if containsHandle(p.Matcher.Handles, h) {
+		return nil
+	}
+	owner, err := s.store.FindByHandle(ctx, h)
+	switch {
+	case err == nil && owner.ID != p.ID:
+		return apperr.Errorf(apperr.ErrConflict, "handle %s already belongs to person %s", h.Key(), owner.ID)
+	case err != nil && !errors.Is(err, apperr.ErrNotFound):
+		return err
+	}
+	p.Matcher.Handles = append(slices.Clip(p.Matcher.Handles), h)
+	return nil
+}
+
 // CreateGroup adds a new group.
 func (s *Service) CreateGroup(ctx context.Context, name string) (Group, error) {
 	g := Group{
-		ID:        uuid.New(),
-		Name:      name,
-		MemberIDs: []uuid.UUID{},
-		CreatedAt: time.Now(),
+		ID:          uuid.New(),
+		Name:        name,
+		MemberIDs:   []uuid.UUID{},
+		SubgroupIDs: []uuid.UUID{},
+		CreatedAt:   time.Now(),
 	}
 	err := s.store.AddGroup(ctx, g)
 	return g, err
@@ -45,6 +135,42 @@ func (s *Service) AddMemberToGroup(ctx context.Context, groupID, personID uuid.U
 	return s.store.AddMemberToGroup(ctx, groupID, personID)
 }
 
+func (s *Service) RemoveMemberFromGroup(ctx context.Context, groupID, personID uuid.UUID) error {
+	return s.store.RemoveMemberFromGroup(ctx, groupID, personID)
+}
+
+// AddSubgroup nests one group inside another, refusing with ErrGroupCycle if
+// that would make a group contain itself.
+func (s *Service) AddSubgroup(ctx context.Context, groupID, subgroupID uuid.UUID) error {
+	return s.store.AddSubgroup(ctx, groupID, subgroupID)
+}
+
+func (s *Service) RemoveSubgroup(ctx context.Context, groupID, subgroupID uuid.UUID) error {
+	return s.store.RemoveSubgroup(ctx, groupID, subgroupID)
+}
+
+func (s *Service) RenameGroup(ctx context.Context, id uuid.UUID, name string) error {
+	if name == "" {
+		return apperr.Errorf(apperr.ErrInvalid, "group name cannot be empty")
+	}
+	return s.store.RenameGroup(ctx, id, name)
+}
+
+func (s *Service) DeleteGroup(ctx context.Context, id uuid.UUID) error {
+	return s.store.DeleteGroup(ctx, id)
+}
+
+// ListGroupsForPerson returns the groups the person is a direct member of.
+func (s *Service) ListGroupsForPerson(ctx context.Context, personID uuid.UUID) ([]Group, error) {
+	return s.store.ListGroupsForPerson(ctx, personID)
+}
+
+// ResolveMembers returns the IDs of everyone in the given groups, including
+// the members of any nested groups.
+func (s *Service) ResolveMembers(ctx context.Context, groupIDs ...uuid.UUID) ([]uuid.UUID, error) {
+	return ResolveMembers(ctx, s.store.GetGroup, groupIDs...)
+}
+
 func (s *Service) GetPerson(ctx context.Context, id uuid.UUID) (Person, error) {
 	return s.store.GetPerson(ctx, id)
 }
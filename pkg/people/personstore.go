@@ -6,15 +6,44 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 )
 
+// ErrGroupCycle is returned when nesting a group would make it contain itself.
+// It matches apperr.ErrConflict.
+var ErrGroupCycle = apperr.New(apperr.ErrConflict, "group would contain itself")
+
 // Store is the interface for storing people and groups.
 type Store interface {
+	// AddPerson saves a person, replacing any stored person with the same ID.
 	AddPerson(ctx context.Context, p Person) error
 	GetPerson(ctx context.Context, id uuid.UUID) (Person, error)
 	AddGroup(ctx context.Context, g Group) error
 	GetGroup(ctx context.Context, id uuid.UUID) (Group, error)
+	// RenameGroup changes a group's name.
+	RenameGroup(ctx context.Context, id uuid.UUID, name string) error
+	// DeleteGroup removes a group and takes it out of any group it is nested in.
+	// The people and groups it contains are not deleted.
+	DeleteGroup(ctx context.Context, id uuid.UUID) error
+	// AddMemberToGroup appends a person to a group's members. Both must exist;
+	// adding an existing member does nothing.
 	AddMemberToGroup(ctx context.Context, groupID, personID uuid.UUID) error
+	// RemoveMemberFromGroup removes a person from a group's members. The group
+	// must exist; removing someone who is not a member does nothing.
+	RemoveMemberFromGroup(ctx context.Context, groupID, personID uuid.UUID) error
+	// AddSubgroup nests one group inside another. Both must exist; nesting a
+	// group that is already nested does nothing, and ErrGroupCycle is returned
+	// if the parent is, or is nested within, the subgroup.
+	AddSubgroup(ctx context.Context, groupID, subgroupID uuid.UUID) error
+	// RemoveSubgroup takes a nested group out of a group. The group must exist;
+	// removing a group that is not nested does nothing.
+	RemoveSubgroup(ctx context.Context, groupID, subgroupID uuid.UUID) error
+	// ListGroupsForPerson returns the groups that list the person as a direct
+	// member, not those that only contain them through a subgroup.
+	ListGroupsForPerson(ctx context.Context, personID uuid.UUID) ([]Group, error)
 	FindByGlobalID(ctx context.Context, globalID string) (Person, error)
+	// FindByHandle returns the person with a handle equal to h, or an
+	// apperr.ErrNotFound error if nobody has it.
+	FindByHandle(ctx context.Context, h Handle) (Person, error)
 	ListAllForMatching(ctx context.Context) ([]Person, error)
 }
@@ -0,0 +1,32 @@
+// FILE: pkg/provenance/provenance.go
+
+// Package provenance records where a local record that was created from a
+// received payload came from, so it can be recognized when the same sender
+// shares it again.
+package provenance
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Source identifies the sender's copy of a record.
+type Source struct {
+	// SenderID is the user who shared the record.
+	SenderID string `json:"sender_id"`
+	// SenderEntityID is the record's ID on the sender's system.
+	SenderEntityID uuid.UUID `json:"sender_entity_id"`
+	ReceivedAt     time.Time `json:"received_at"`
+}
+
+// New returns a Source for an entity received now.
+func New(senderID string, senderEntityID uuid.UUID) *Source {
+	return &Source{SenderID: senderID, SenderEntityID: senderEntityID, ReceivedAt: time.Now()}
+}
+
+// Is reports whether s records the given sender's entity. It is false for a
+// nil Source, i.e. a record that was created locally.
+func (s *Source) Is(senderID string, senderEntityID uuid.UUID) bool {
+	return s != nil && s.SenderID == senderID && s.SenderEntityID == senderEntityID
+}
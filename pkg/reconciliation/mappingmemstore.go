@@ -0,0 +1,62 @@
+// FILE: pkg/reconciliation/mappingmemstore.go
+
+package reconciliation
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+)
+
+type mappingKey struct {
+	senderID       string
+	senderEntityID uuid.UUID
+}
+
+// InMemoryMappingStore is a thread-safe, in-memory implementation of the MappingStore interface.
+type InMemoryMappingStore struct {
+	sync.RWMutex
+	mappings map[mappingKey]IdentityMapping
+}
+
+// NewInMemoryMappingStore creates a new in-memory mapping store.
+func NewInMemoryMappingStore() *InMemoryMappingStore {
+	return &InMemoryMappingStore{
+		mappings: make(map[mappingKey]IdentityMapping),
+	}
+}
+
+// GetMapping returns the mapping of a sender's entity.
+func (s *InMemoryMappingStore) GetMapping(ctx context.Context, senderID string, senderEntityID uuid.UUID) (IdentityMapping, error) {
+	s.RLock()
+	defer s.RUnlock()
+	m, ok := s.mappings[mappingKey{senderID, senderEntityID}]
+	if !ok {
+		return IdentityMapping{}, apperr.Errorf(apperr.ErrNotFound, "no mapping of %s from %s", senderEntityID, senderID)
+	}
+	return m, nil
+}
+
+// PutMappings saves mappings, replacing any for the same sender entities.
+func (s *InMemoryMappingStore) PutMappings(ctx context.Context, mappings []IdentityMapping) error {
+	s.Lock()
+	defer s.Unlock()
+	for _, m := range mappings {
+		s.mappings[mappingKey{m.SenderID, m.SenderEntityID}] = m
+	}
+	return nil
+}
+
+// DeleteMapping removes the mapping of a sender's entity.
+func (s *InMemoryMappingStore) DeleteMapping(ctx context.Context, senderID string, senderEntityID uuid.UUID) error {
+	s.Lock()
+	defer s.Unlock()
+	key := mappingKey{senderID, senderEntityID}
+	if _, ok := s.mappings[key]; !ok {
+		return apperr.Errorf(apperr.ErrNotFound, "no mapping of %s from %s", senderEntityID, senderID)
+	}
+	delete(s.mappings, key)
+	return nil
+}
@@ -0,0 +1,14 @@
+package reconciliation_test
+
+import (
+	"testing"
+
+	"github.com/illmade-knight/action-intention/pkg/reconciliation"
+	"github.com/illmade-knight/action-intention/pkg/storetest"
+)
+
+func TestInMemoryMappingStore_Conformance(t *testing.T) {
+	storetest.RunMappingStoreTests(t, func(t *testing.T) reconciliation.MappingStore {
+		return reconciliation.NewInMemoryMappingStore()
+	})
+}
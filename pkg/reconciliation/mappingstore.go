@@ -0,0 +1,32 @@
+// FILE: pkg/reconciliation/mappingstore.go
+
+package reconciliation
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdentityMapping records that one of a sender's entities is a local record,
+// so later shares of it are mapped without being matched again.
+type IdentityMapping struct {
+	SenderID       string     `json:"sender_id"`
+	SenderEntityID uuid.UUID  `json:"sender_entity_id"`
+	Kind           EntityKind `json:"kind"`
+	LocalID        uuid.UUID  `json:"local_id"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// MappingStore persists identity mappings, keyed by the sender and the
+// sender's entity ID.
+type MappingStore interface {
+	// GetMapping returns the mapping of a sender's entity, or an error matching
+	// apperr.ErrNotFound if there is none.
+	GetMapping(ctx context.Context, senderID string, senderEntityID uuid.UUID) (IdentityMapping, error)
+	// PutMappings saves mappings, replacing any for the same sender entities.
+	PutMappings(ctx context.Context, mappings []IdentityMapping) error
+	// DeleteMapping removes the mapping of a sender's entity.
+	DeleteMapping(ctx context.Context, senderID string, senderEntityID uuid.UUID) error
+}
@@ -4,126 +4,687 @@ package reconciliation
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
 	"github.com/illmade-knight/action-intention/pkg/locations"
 	"github.com/illmade-knight/action-intention/pkg/people"
+	"github.com/illmade-knight/action-intention/pkg/provenance"
 	"github.com/illmade-knight/action-intention/pkg/sharing"
+	"github.com/rs/zerolog"
 )
 
 type MappingResult struct {
 	LocationMappings map[uuid.UUID]uuid.UUID
 	PersonMappings   map[uuid.UUID]uuid.UUID
+	GroupMappings    map[uuid.UUID]uuid.UUID
+	// PossibleMatches lists the incoming entities that only matched possibly.
+	// They are left out of the mappings until a user resolves them.
+	PossibleMatches []PossibleMatch
+	// LocationCandidates, PersonCandidates and GroupCandidates hold, for every
+	// incoming entity, the best-scoring local records at or above the possible
+	// threshold, best first.
+	LocationCandidates map[uuid.UUID][]Candidate
+	PersonCandidates   map[uuid.UUID][]Candidate
+	GroupCandidates    map[uuid.UUID][]Candidate
+	// CreatedLocations, CreatedPeople and CreatedGroups list the local IDs of
+	// records created for unmatched incoming entities, which are also mapped.
+	// They are only filled in by a Reconciler built WithCreateUnmatched.
+	CreatedLocations []uuid.UUID
+	CreatedPeople    []uuid.UUID
+	CreatedGroups    []uuid.UUID
+	// Traces explains the decision made for every incoming entity, in the
+	// order they were reconciled.
+	Traces []DecisionTrace
+}
+
+// PossibleMatch is an incoming entity with the local candidates it might be.
+type PossibleMatch struct {
+	Kind           EntityKind
+	SenderEntityID uuid.UUID
+	Candidates     []Candidate
+}
+
+// Record adds a resolved mapping for an incoming entity.
+func (m *MappingResult) Record(kind EntityKind, senderID, localID uuid.UUID) {
+	switch kind {
+	case EntityLocation:
+		if m.LocationMappings == nil {
+			m.LocationMappings = make(map[uuid.UUID]uuid.UUID)
+		}
+		m.LocationMappings[senderID] = localID
+	case EntityPerson:
+		if m.PersonMappings == nil {
+			m.PersonMappings = make(map[uuid.UUID]uuid.UUID)
+		}
+		m.PersonMappings[senderID] = localID
+	case EntityGroup:
+		if m.GroupMappings == nil {
+			m.GroupMappings = make(map[uuid.UUID]uuid.UUID)
+		}
+		m.GroupMappings[senderID] = localID
+	}
+}
+
+// Trace returns the decision trace of an incoming entity.
+func (m MappingResult) Trace(kind EntityKind, senderID uuid.UUID) (DecisionTrace, bool) {
+	for _, t := range m.Traces {
+		if t.Kind == kind && t.SenderEntityID == senderID {
+			return t, true
+		}
+	}
+	return DecisionTrace{}, false
+}
+
+// recordCreated maps an incoming entity to the local record created for it.
+func (m *MappingResult) recordCreated(kind EntityKind, senderID, localID uuid.UUID) {
+	m.Record(kind, senderID, localID)
+	for i := range m.Traces {
+		if m.Traces[i].Kind == kind && m.Traces[i].SenderEntityID == senderID {
+			m.Traces[i].Outcome = OutcomeCreated
+			m.Traces[i].LocalID = localID
+		}
+	}
+}
+
+// Thresholds decide what a match score means.
+type Thresholds struct {
+	// Exact is the score at or above which the best candidate is mapped
+	// automatically, provided no other candidate scores as high.
+	Exact float64
+	// Possible is the score at or above which a local record is a candidate.
+	Possible float64
+	// MaxCandidates caps the candidates kept per incoming entity.
+	MaxCandidates int
+}
+
+// DefaultThresholds returns the thresholds used unless WithThresholds is given.
+func DefaultThresholds() Thresholds {
+	return Thresholds{Exact: 0.9, Possible: 0.5, MaxCandidates: 3}
+}
+
+// ReconcilerOption configures a Reconciler.
+type ReconcilerOption func(*Reconciler)
+
+// WithThresholds replaces the default thresholds. A zero MaxCandidates keeps
+// the default.
+func WithThresholds(t Thresholds) ReconcilerOption {
+	return func(r *Reconciler) {
+		if t.MaxCandidates <= 0 {
+			t.MaxCandidates = DefaultThresholds().MaxCandidates
+		}
+		r.thresholds = t
+	}
+}
+
+// WithCreateUnmatched makes ProcessPayload create a local record for every
+// incoming location, person and group that has no candidate at all, instead
+// of leaving it unmapped.
+func WithCreateUnmatched() ReconcilerOption {
+	return func(r *Reconciler) {
+		r.createUnmatched = true
+	}
+}
+
+// WithMappingStore makes ProcessPayload map a sender's entities the way they
+// were mapped before, and save every mapping it makes for the next share.
+func WithMappingStore(store MappingStore) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.mappings = store
+	}
+}
+
+// WithLogger sets the logger for the reconciler's decisions, which are logged
+// at debug level, and every comparison, at trace level. Without it nothing is
+// logged.
+func WithLogger(logger zerolog.Logger) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.logger = logger
+	}
 }
 
 type Reconciler struct {
 	localLocationStore locations.Store
 	localPersonStore   people.Store
+	mappings           MappingStore
+	thresholds         Thresholds
+	createUnmatched    bool
+	logger             zerolog.Logger
 }
 
-func NewReconciler(locStore locations.Store, personStore people.Store) *Reconciler {
-	return &Reconciler{
+func NewReconciler(locStore locations.Store, personStore people.Store, opts ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{
 		localLocationStore: locStore,
 		localPersonStore:   personStore,
+		thresholds:         DefaultThresholds(),
+		logger:             zerolog.Nop(),
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
-func (r *Reconciler) ProcessPayload(ctx context.Context, payload sharing.SharedPayload) (MappingResult, error) {
+// ProcessPayload maps the sender's locations, people and groups onto local
+// records, returning the mappings, the candidates left for the user to resolve
+// and a trace of each decision.
+func (r *Reconciler) ProcessPayload(ctx context.Context, recipientID string, payload sharing.SharedPayload) (MappingResult, error) {
 	result := MappingResult{
-		LocationMappings: make(map[uuid.UUID]uuid.UUID),
-		PersonMappings:   make(map[uuid.UUID]uuid.UUID),
+		LocationMappings:   make(map[uuid.UUID]uuid.UUID),
+		PersonMappings:     make(map[uuid.UUID]uuid.UUID),
+		GroupMappings:      make(map[uuid.UUID]uuid.UUID),
+		LocationCandidates: make(map[uuid.UUID][]Candidate),
+		PersonCandidates:   make(map[uuid.UUID][]Candidate),
+		GroupCandidates:    make(map[uuid.UUID][]Candidate),
 	}
+	sender := payload.Intention.User
 
-	// --- Reconcile Locations ---
-	localLocations, _ := r.localLocationStore.ListAllForMatching(ctx)
-	log.Printf("[Reconciler] Found %d local locations for matching.", len(localLocations))
-	for senderIDkey, incomingLoc := range payload.Locations {
-		senderID, _ := uuid.Parse(senderIDkey)
-		log.Printf("[Reconciler] --- Processing incoming location '%s' (ID: %s) ---", incomingLoc.Name, senderID)
+	// The payload's Locations and People already include everything referenced
+	// by any target kind, including those of RelatedIntentions, so no
+	// per-target handling is needed here. Each entity is mapped the same way as
+	// an earlier share if a mapping store remembers one, then by GlobalID, then
+	// by scoring local records. decide maps a single best candidate at or above
+	// the exact threshold and leaves the rest in PossibleMatches; with
+	// WithCreateUnmatched, an entity without any candidate is created locally,
+	// marked with its provenance. Every decision is traced, and WithLogger logs
+	// them.
 
-		var finalMatchID *uuid.UUID
+	// --- Reconcile Locations ---
+	// Each list is loaded at most once: every local location for incoming
+	// locations without coordinates, and the local locations without
+	// coordinates, which the spatial index cannot find, for the others.
+	var localLocations, unlocated []locations.Location
+	allLoaded, unlocatedLoaded := false, false
+	for _, senderIDkey := range sortedKeys(payload.Locations) {
+		incomingLoc := payload.Locations[senderIDkey]
+		senderID, err := parseSenderID(EntityLocation, senderIDkey)
+		if err != nil {
+			return MappingResult{}, err
+		}
+		trace := DecisionTrace{Kind: EntityLocation, SenderEntityID: senderID, Name: incomingLoc.Name, Rule: RuleMappedBefore, Considered: 1}
 
-		if incomingLoc.GlobalID != nil {
-			if loc, err := r.localLocationStore.FindByGlobalID(ctx, *incomingLoc.GlobalID); err == nil {
-				finalMatchID = &loc.ID
+		candidates, err := r.knownMapping(ctx, sender, EntityLocation, senderID)
+		if err != nil {
+			return MappingResult{}, err
+		}
+		// A global ID that is not found locally is simply not a match, but any
+		// other store error is returned rather than treated as one.
+		if candidates == nil && incomingLoc.GlobalID != nil {
+			loc, err := r.localLocationStore.FindByGlobalID(ctx, *incomingLoc.GlobalID)
+			switch {
+			case err == nil:
+				candidates = []Candidate{{LocalID: loc.ID, Name: loc.Name, Score: 1, Explanation: "same global ID"}}
+				trace.Rule = RuleGlobalID
+			case !errors.Is(err, apperr.ErrNotFound):
+				return MappingResult{}, fmt.Errorf("failed to look up location %s by global ID: %w", senderID, err)
 			}
 		}
-
-		if finalMatchID == nil {
-			var bestMatchID *uuid.UUID
-			bestMatchLevel := locations.MatchNone
-			for _, localLoc := range localLocations {
-				log.Printf("[Reconciler] -> Checking against local location: '%s' (ID: %s)", localLoc.Name, localLoc.ID)
-				matchLevel := incomingLoc.Matcher.Match(localLoc)
-				if matchLevel == locations.MatchExact {
-					bestMatchID = &localLoc.ID
-					log.Printf("[Reconciler] ----> Found EXACT match!")
-					break
+		// A location with coordinates is scored against the local locations
+		// within MaxMatchDistanceKm, found through the store's spatial index,
+		// and against those without coordinates; others against every one.
+		if candidates == nil {
+			nearby, hasCoordinates, err := r.nearbyLocations(ctx, incomingLoc)
+			if err != nil {
+				return MappingResult{}, err
+			}
+			switch {
+			case hasCoordinates && !unlocatedLoaded:
+				if unlocated, err = r.localLocationStore.ListWithoutCoordinatesForMatching(ctx); err != nil {
+					return MappingResult{}, fmt.Errorf("failed to list local locations without coordinates: %w", err)
 				}
-				if matchLevel == locations.MatchPossible && bestMatchLevel == locations.MatchNone {
-					bestMatchID = &localLoc.ID
-					bestMatchLevel = locations.MatchPossible
-					log.Printf("[Reconciler] ----> Found POSSIBLE match.")
+				unlocatedLoaded = true
+				r.logger.Debug().Int("count", len(unlocated)).Msg("Loaded local locations without coordinates for matching")
+			case !hasCoordinates && !allLoaded:
+				if localLocations, err = r.localLocationStore.ListAllForMatching(ctx); err != nil {
+					return MappingResult{}, fmt.Errorf("failed to list local locations: %w", err)
 				}
+				allLoaded = true
+				r.logger.Debug().Int("count", len(localLocations)).Msg("Loaded local locations for matching")
+			}
+			if hasCoordinates {
+				nearby = append(nearby, unlocated...)
+			} else {
+				nearby = localLocations
 			}
-			if bestMatchID != nil {
-				finalMatchID = bestMatchID
+			trace.Rule = ""
+			for _, localLoc := range nearby {
+				if localLoc.Provenance.Is(sender, senderID) {
+					candidates = append(candidates, r.scored(trace, Candidate{LocalID: localLoc.ID, Name: localLoc.Name, Score: 1, Explanation: "created from this location"}))
+					continue
+				}
+				score := incomingLoc.Matcher.Match(localLoc)
+				candidates = append(candidates, r.scored(trace, Candidate{LocalID: localLoc.ID, Name: localLoc.Name, Score: score.Score, Explanation: score.Explanation}))
 			}
+			trace.Considered = len(nearby)
 		}
-
-		if finalMatchID != nil {
-			result.LocationMappings[senderID] = *finalMatchID
-			log.Printf("[Reconciler] ===> MAPPED incoming location %s to local %s", senderID, *finalMatchID)
-		} else {
-			log.Printf("[Reconciler] ===> NO MATCH FOUND for incoming location %s", senderID)
+		// Locations that are not shared are owned by the recipient.
+		if r.decide(&result, trace, candidates) && r.createUnmatched {
+			loc := NewLocalLocation(incomingLoc, sender, senderID, recipientID)
+			if err := r.localLocationStore.Add(ctx, loc); err != nil {
+				return MappingResult{}, fmt.Errorf("failed to create location for %s: %w", senderID, err)
+			}
+			result.recordCreated(EntityLocation, senderID, loc.ID)
+			result.CreatedLocations = append(result.CreatedLocations, loc.ID)
+			r.logger.Debug().Stringer("sender_entity_id", senderID).Stringer("local_id", loc.ID).Msg("Created local location for unmatched incoming location")
 		}
 	}
 
 	// --- Reconcile People ---
-	localPeople, _ := r.localPersonStore.ListAllForMatching(ctx)
-	log.Printf("[Reconciler] Found %d local people for matching.", len(localPeople))
-	for senderIDkey, incomingPerson := range payload.People {
-		log.Printf("[Reconciler] --- Processing incoming person '%s' (ID: %s) ---", incomingPerson.Name, senderIDkey)
-		senderID, _ := uuid.Parse(senderIDkey)
-
-		var finalMatchID *uuid.UUID
+	localPeople, err := r.localPersonStore.ListAllForMatching(ctx)
+	if err != nil {
+		return MappingResult{}, fmt.Errorf("failed to list local people: %w", err)
+	}
+	r.logger.Debug().Int("count", len(localPeople)).Msg("Loaded local people for matching")
+	for _, senderIDkey := range sortedKeys(payload.People) {
+		incomingPerson := payload.People[senderIDkey]
+		senderID, err := parseSenderID(EntityPerson, senderIDkey)
+		if err != nil {
+			return MappingResult{}, err
+		}
+		trace := DecisionTrace{Kind: EntityPerson, SenderEntityID: senderID, Name: incomingPerson.Name, Rule: RuleMappedBefore, Considered: 1}
 
-		if incomingPerson.GlobalID != nil {
-			if p, err := r.localPersonStore.FindByGlobalID(ctx, *incomingPerson.GlobalID); err == nil {
-				finalMatchID = &p.ID
+		candidates, err := r.knownMapping(ctx, sender, EntityPerson, senderID)
+		if err != nil {
+			return MappingResult{}, err
+		}
+		if candidates == nil && incomingPerson.GlobalID != nil {
+			p, err := r.localPersonStore.FindByGlobalID(ctx, *incomingPerson.GlobalID)
+			switch {
+			case err == nil:
+				candidates = []Candidate{{LocalID: p.ID, Name: p.Name, Score: 1, Explanation: "same global ID"}}
+				trace.Rule = RuleGlobalID
+			case !errors.Is(err, apperr.ErrNotFound):
+				return MappingResult{}, fmt.Errorf("failed to look up person %s by global ID: %w", senderID, err)
 			}
 		}
-
-		if finalMatchID == nil {
-			var bestMatchID *uuid.UUID
-			bestMatchLevel := people.MatchNone
+		if candidates == nil {
+			trace.Rule = ""
 			for _, localPerson := range localPeople {
-				log.Printf("[Reconciler] -> Checking against local person: '%s' (ID: %s)", localPerson.Name, localPerson.ID)
-				matchLevel := incomingPerson.Matcher.Match(localPerson)
-				if matchLevel == people.MatchExact {
-					bestMatchID = &localPerson.ID
-					log.Printf("[Reconciler] ----> Found EXACT match!")
-					break
-				}
-				if matchLevel == people.MatchPossible && bestMatchLevel == people.MatchNone {
-					bestMatchID = &localPerson.ID
-					bestMatchLevel = people.MatchPossible
-					log.Printf("[Reconciler] ----> Found POSSIBLE match.")
+				if localPerson.Provenance.Is(sender, senderID) {
+					candidates = append(candidates, r.scored(trace, Candidate{LocalID: localPerson.ID, Name: localPerson.Name, Score: 1, Explanation: "created from this person"}))
+					continue
 				}
+				score := incomingPerson.Matcher.Match(localPerson)
+				candidates = append(candidates, r.scored(trace, Candidate{LocalID: localPerson.ID, Name: localPerson.Name, Score: score.Score, Explanation: score.Explanation}))
 			}
-			if bestMatchID != nil {
-				finalMatchID = bestMatchID
+			trace.Considered = len(localPeople)
+		}
+		if r.decide(&result, trace, candidates) && r.createUnmatched {
+			person := NewLocalPerson(incomingPerson, sender, senderID)
+			if err := r.localPersonStore.AddPerson(ctx, person); err != nil {
+				return MappingResult{}, fmt.Errorf("failed to create person for %s: %w", senderID, err)
 			}
+			result.recordCreated(EntityPerson, senderID, person.ID)
+			result.CreatedPeople = append(result.CreatedPeople, person.ID)
+			r.logger.Debug().Stringer("sender_entity_id", senderID).Stringer("local_id", person.ID).Msg("Created local person for unmatched incoming person")
 		}
+	}
 
-		if finalMatchID != nil {
-			result.PersonMappings[senderID] = *finalMatchID
-			log.Printf("[Reconciler] ===> MAPPED incoming person %s to local %s", senderIDkey, *finalMatchID)
-		} else {
-			log.Printf("[Reconciler] ===> NO MATCH FOUND for incoming person %s", senderIDkey)
+	// --- Reconcile Groups ---
+	// Groups are reconciled after people, by how many of their mapped members
+	// they share with the local groups those members belong to. A group is only
+	// mapped at the exact threshold; there are no group tasks, so a group with
+	// candidates below it, or tied at it, is left unmatched rather than
+	// duplicated. Created groups are stored once every group is mapped, so
+	// subgroups that were created too can be translated, and keep only their
+	// mapped members and subgroups.
+	var created []people.Group
+	for _, senderIDkey := range sortedKeys(payload.Groups) {
+		incomingGroup := payload.Groups[senderIDkey]
+		senderID, err := parseSenderID(EntityGroup, senderIDkey)
+		if err != nil {
+			return MappingResult{}, err
+		}
+		trace := DecisionTrace{Kind: EntityGroup, SenderEntityID: senderID, Name: incomingGroup.Name, Rule: RuleMappedBefore, Considered: 1}
+
+		candidates, err := r.knownMapping(ctx, sender, EntityGroup, senderID)
+		if err != nil {
+			return MappingResult{}, err
 		}
+		if candidates == nil {
+			trace.Rule = ""
+			if candidates, err = r.groupCandidates(ctx, sender, senderID, incomingGroup, result.PersonMappings); err != nil {
+				return MappingResult{}, err
+			}
+			for _, c := range candidates {
+				r.scored(trace, c)
+			}
+			trace.Considered = len(candidates)
+		}
+		if r.decide(&result, trace, candidates) && r.createUnmatched {
+			group := people.Group{
+				ID:         uuid.New(),
+				Name:       incomingGroup.Name,
+				Provenance: provenance.New(sender, senderID),
+				CreatedAt:  time.Now(),
+			}
+			result.recordCreated(EntityGroup, senderID, group.ID)
+			created = append(created, group)
+		}
+	}
+	for _, group := range created {
+		senderGroup := payload.Groups[group.Provenance.SenderEntityID.String()]
+		group.MemberIDs = mappedIDs(senderGroup.MemberIDs, result.PersonMappings)
+		group.SubgroupIDs = mappedIDs(senderGroup.SubgroupIDs, result.GroupMappings)
+		if err := r.localPersonStore.AddGroup(ctx, group); err != nil {
+			return MappingResult{}, fmt.Errorf("failed to create group for %s: %w", group.Provenance.SenderEntityID, err)
+		}
+		result.CreatedGroups = append(result.CreatedGroups, group.ID)
+		r.logger.Debug().Stringer("sender_entity_id", group.Provenance.SenderEntityID).Stringer("local_id", group.ID).Msg("Created local group for unmatched incoming group")
 	}
 
+	if err := r.saveMappings(ctx, sender, result); err != nil {
+		return MappingResult{}, err
+	}
 	return result, nil
 }
+
+// knownMapping returns the local record a sender's entity was mapped to before
+// as its only candidate, or nil if there is none. A mapping whose local record
+// has since been deleted is dropped, so the entity is matched again.
+func (r *Reconciler) knownMapping(ctx context.Context, sender string, kind EntityKind, senderID uuid.UUID) ([]Candidate, error) {
+	if r.mappings == nil {
+		return nil, nil
+	}
+	m, err := r.mappings.GetMapping(ctx, sender, senderID)
+	if errors.Is(err, apperr.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up mapping of %s %s: %w", kind, senderID, err)
+	}
+	if m.Kind != kind {
+		return nil, nil
+	}
+	name, err := r.localName(ctx, kind, m.LocalID)
+	if errors.Is(err, apperr.ErrNotFound) {
+		r.logger.Info().Str("kind", string(kind)).Stringer("sender_entity_id", senderID).Stringer("local_id", m.LocalID).Msg("Dropping mapping to deleted local record")
+		if err := r.mappings.DeleteMapping(ctx, sender, senderID); err != nil && !errors.Is(err, apperr.ErrNotFound) {
+			return nil, fmt.Errorf("failed to delete mapping of %s %s: %w", kind, senderID, err)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up local %s %s: %w", kind, m.LocalID, err)
+	}
+	return []Candidate{{LocalID: m.LocalID, Name: name, Score: 1, Explanation: "mapped before"}}, nil
+}
+
+// localName fetches the name of a local record, or an error matching
+// apperr.ErrNotFound if it does not exist.
+func (r *Reconciler) localName(ctx context.Context, kind EntityKind, id uuid.UUID) (string, error) {
+	switch kind {
+	case EntityLocation:
+		loc, err := r.localLocationStore.GetByID(ctx, id)
+		return loc.Name, err
+	case EntityPerson:
+		person, err := r.localPersonStore.GetPerson(ctx, id)
+		return person.Name, err
+	case EntityGroup:
+		group, err := r.localPersonStore.GetGroup(ctx, id)
+		return group.Name, err
+	default:
+		return "", apperr.Errorf(apperr.ErrInvalid, "unknown entity kind %q", kind)
+	}
+}
+
+// saveMappings records every mapping in result for the sender's next share.
+func (r *Reconciler) saveMappings(ctx context.Context, sender string, result MappingResult) error {
+	if r.mappings == nil {
+		return nil
+	}
+	now := time.Now()
+	var mappings []IdentityMapping
+	for kind, ids := range map[EntityKind]map[uuid.UUID]uuid.UUID{
+		EntityLocation: result.LocationMappings,
+		EntityPerson:   result.PersonMappings,
+		EntityGroup:    result.GroupMappings,
+	} {
+		for senderID, localID := range ids {
+			mappings = append(mappings, IdentityMapping{SenderID: sender, SenderEntityID: senderID, Kind: kind, LocalID: localID, UpdatedAt: now})
+		}
+	}
+	if err := r.mappings.PutMappings(ctx, mappings); err != nil {
+		return fmt.Errorf("failed to save mappings: %w", err)
+	}
+	return nil
+}
+
+// CorrectMapping maps a sender's entity onto a different local record, e.g.
+// after it was matched to the wrong one. Later shares use the new mapping;
+// intentions already stored are not changed.
+func (r *Reconciler) CorrectMapping(ctx context.Context, sender string, kind EntityKind, senderID, localID uuid.UUID) error {
+	if r.mappings == nil {
+		return apperr.Errorf(apperr.ErrInvalid, "reconciler has no mapping store")
+	}
+	if _, err := r.localName(ctx, kind, localID); err != nil {
+		return err
+	}
+	return r.mappings.PutMappings(ctx, []IdentityMapping{{SenderID: sender, SenderEntityID: senderID, Kind: kind, LocalID: localID, UpdatedAt: time.Now()}})
+}
+
+// groupCandidates scores the local groups that any mapped member of an
+// incoming group belongs to, by the share of members the two have in common.
+// Members that are not mapped count against every local group.
+func (r *Reconciler) groupCandidates(ctx context.Context, sender string, senderID uuid.UUID, incoming people.Group, personMappings map[uuid.UUID]uuid.UUID) ([]Candidate, error) {
+	members := make(map[uuid.UUID]bool)
+	for _, memberID := range incoming.MemberIDs {
+		if localID, ok := personMappings[memberID]; ok {
+			members[localID] = true
+		} else {
+			members[memberID] = true
+		}
+	}
+
+	var candidates []Candidate
+	seen := make(map[uuid.UUID]bool)
+	for _, memberID := range incoming.MemberIDs {
+		localID, ok := personMappings[memberID]
+		if !ok {
+			continue
+		}
+		groups, err := r.localPersonStore.ListGroupsForPerson(ctx, localID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list groups for person %s: %w", localID, err)
+		}
+		for _, g := range groups {
+			if seen[g.ID] {
+				continue
+			}
+			seen[g.ID] = true
+			if g.Provenance.Is(sender, senderID) {
+				candidates = append(candidates, Candidate{LocalID: g.ID, Name: g.Name, Score: 1, Explanation: "created from this group"})
+				continue
+			}
+			shared, union := 0, len(members)
+			for _, id := range g.MemberIDs {
+				if members[id] {
+					shared++
+				} else {
+					union++
+				}
+			}
+			candidates = append(candidates, Candidate{
+				LocalID:     g.ID,
+				Name:        g.Name,
+				Score:       float64(shared) / float64(union),
+				Explanation: fmt.Sprintf("%d of %d members shared", shared, union),
+			})
+		}
+	}
+	return candidates, nil
+}
+
+// NewLocalLocation copies an incoming location into a new local record marked
+// with where it came from. Unless it is shared, it becomes owned by recipientID.
+func NewLocalLocation(incoming locations.Location, sender string, senderID uuid.UUID, recipientID string) locations.Location {
+	loc := incoming
+	loc.ID = uuid.New()
+	loc.CreatedAt = time.Now()
+	loc.Provenance = provenance.New(sender, senderID)
+	if loc.Type != locations.LocationTypeShared {
+		loc.Type = locations.LocationTypeUser
+		loc.UserID = &recipientID
+	}
+	return loc
+}
+
+// NewLocalPerson copies an incoming person into a new local record marked
+// with where it came from.
+func NewLocalPerson(incoming people.Person, sender string, senderID uuid.UUID) people.Person {
+	person := incoming
+	person.ID = uuid.New()
+	person.CreatedAt = time.Now()
+	person.Provenance = provenance.New(sender, senderID)
+	return person
+}
+
+// mappedIDs translates the IDs that have a mapping and drops the rest.
+func mappedIDs(ids []uuid.UUID, mappings map[uuid.UUID]uuid.UUID) []uuid.UUID {
+	var local []uuid.UUID
+	for _, id := range ids {
+		if localID, ok := mappings[id]; ok {
+			local = append(local, localID)
+		}
+	}
+	return local
+}
+
+// nearbyLocations looks up the local locations with coordinates close enough
+// to match an incoming location with coordinates, using the store's spatial
+// index. The index cannot find local locations without coordinates, so the
+// caller scores those as well. It reports false if the incoming location has
+// no coordinates.
+func (r *Reconciler) nearbyLocations(ctx context.Context, incoming locations.Location) ([]locations.Location, bool, error) {
+	if incoming.Matcher.Lat == nil || incoming.Matcher.Lon == nil {
+		return nil, false, nil
+	}
+	nearby, err := r.localLocationStore.NearbyForMatching(ctx, *incoming.Matcher.Lat, *incoming.Matcher.Lon, locations.MaxMatchDistanceKm)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to find local locations near '%s': %w", incoming.Name, err)
+	}
+	r.logger.Debug().Str("name", incoming.Name).Int("count", len(nearby)).Msg("Found local locations near incoming location")
+	return nearby, true, nil
+}
+
+// decide ranks the scored local records for one incoming entity, keeps the
+// top candidates and either maps the best one or records a possible match. The
+// decision is completed in trace, which is added to the result; a trace with
+// no Rule has scored candidates, otherwise Rule names where its single
+// candidate came from. It reports whether the entity has no candidates at all,
+// so that it can be created locally. A group with candidates that could not be
+// mapped is unmatched too, but is not reported, since a similar local group
+// already exists.
+func (r *Reconciler) decide(result *MappingResult, trace DecisionTrace, scored []Candidate) bool {
+	kind, senderID := trace.Kind, trace.SenderEntityID
+	candidates := rankCandidates(scored, r.thresholds)
+	switch kind {
+	case EntityLocation:
+		result.LocationCandidates[senderID] = candidates
+	case EntityPerson:
+		result.PersonCandidates[senderID] = candidates
+	case EntityGroup:
+		result.GroupCandidates[senderID] = candidates
+	}
+	trace.Candidates = candidates
+	if trace.Rule == "" {
+		trace.Rule = RuleExactScore
+	}
+	defer func() {
+		result.Traces = append(result.Traces, trace)
+		r.logger.Debug().
+			Str("kind", string(kind)).
+			Stringer("sender_entity_id", senderID).
+			Str("name", trace.Name).
+			Int("considered", trace.Considered).
+			Int("candidates", len(candidates)).
+			Str("rule", string(trace.Rule)).
+			Str("outcome", string(trace.Outcome)).
+			Msg("Reconciled incoming entity")
+	}()
+
+	if len(candidates) == 0 {
+		trace.Rule, trace.Outcome = RuleNoCandidates, OutcomeUnmatched
+		return true
+	}
+	best := candidates[0]
+	tied := len(candidates) > 1 && candidates[1].Score >= best.Score
+	if best.Score >= r.thresholds.Exact && !tied {
+		result.Record(kind, senderID, best.LocalID)
+		trace.Outcome, trace.LocalID = OutcomeMapped, best.LocalID
+		return false
+	}
+	if trace.Rule == RuleExactScore {
+		trace.Rule = RuleBelowExact
+		if best.Score >= r.thresholds.Exact {
+			trace.Rule = RuleTiedScore
+		}
+	}
+	if kind == EntityGroup {
+		trace.Outcome = OutcomeUnmatched
+		return false
+	}
+	result.PossibleMatches = append(result.PossibleMatches, PossibleMatch{Kind: kind, SenderEntityID: senderID, Candidates: candidates})
+	trace.Outcome = OutcomePossible
+	return false
+}
+
+// scored logs one comparison of an incoming entity with a local record at
+// trace level and returns the candidate.
+func (r *Reconciler) scored(trace DecisionTrace, c Candidate) Candidate {
+	r.logger.Trace().
+		Str("kind", string(trace.Kind)).
+		Stringer("sender_entity_id", trace.SenderEntityID).
+		Str("name", trace.Name).
+		Stringer("local_id", c.LocalID).
+		Str("local_name", c.Name).
+		Float64("score", c.Score).
+		Str("explanation", c.Explanation).
+		Msg("Scored local record")
+	return c
+}
+
+// rankCandidates drops candidates below the possible threshold and returns the
+// rest best first, capped at MaxCandidates. Equal scores are ordered by local
+// ID so the result does not depend on store iteration order.
+func rankCandidates(scored []Candidate, t Thresholds) []Candidate {
+	var candidates []Candidate
+	for _, c := range scored {
+		if c.Score >= t.Possible {
+			candidates = append(candidates, c)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].LocalID.String() < candidates[j].LocalID.String()
+	})
+	if len(candidates) > t.MaxCandidates {
+		candidates = candidates[:t.MaxCandidates]
+	}
+	return candidates
+}
+
+// parseSenderID parses a payload map key, which must be the sender's ID for the entity.
+func parseSenderID(kind EntityKind, key string) (uuid.UUID, error) {
+	id, err := uuid.Parse(key)
+	if err != nil {
+		return uuid.Nil, apperr.Errorf(apperr.ErrInvalid, "invalid sender %s ID %q: %v", kind, key, err)
+	}
+	return id, nil
+}
+
+// sortedKeys returns a payload map's keys in order so results are reproducible.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
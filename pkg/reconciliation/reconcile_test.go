@@ -0,0 +1,116 @@
+package reconciliation_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/illmade-knight/action-intention/pkg/locations"
+	"github.com/illmade-knight/action-intention/pkg/people"
+	"github.com/illmade-knight/action-intention/pkg/reconciliation"
+	"github.com/illmade-knight/action-intention/pkg/sharing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scanCountingStore counts the full scans of a location store.
+type scanCountingStore struct {
+	*locations.InMemoryStore
+	fullScans int
+}
+
+func (s *scanCountingStore) ListAllForMatching(ctx context.Context) ([]locations.Location, error) {
+	s.fullScans++
+	return s.InMemoryStore.ListAllForMatching(ctx)
+}
+
+func TestReconciler_ProcessPayload_LocationsWithoutCoordinates(t *testing.T) {
+	ctx := context.Background()
+	lat, lon := 55.9533, -3.1883
+	senderLoc := locations.Location{ID: uuid.New(), Name: "Corner Cafe", Type: locations.LocationTypeShared, Matcher: locations.LocationMatcher{Name: "Corner Cafe", Category: "Cafe", Lat: &lat, Lon: &lon}}
+	payload := sharing.SharedPayload{
+		Intention: intentions.Intention{ID: uuid.New(), User: "alice", Action: "Coffee", Targets: []intentions.Target{intentions.LocationTarget{LocationID: senderLoc.ID}}, StartTime: time.Now(), EndTime: time.Now().Add(time.Hour)},
+		Locations: map[string]locations.Location{senderLoc.ID.String(): senderLoc},
+	}
+
+	locStore := &scanCountingStore{InMemoryStore: locations.NewInMemoryStore()}
+	localCafe := locations.Location{ID: uuid.New(), Name: "Corner Cafe", Type: locations.LocationTypeShared, Matcher: locations.LocationMatcher{Name: "Corner Cafe", Category: "Cafe"}}
+	require.NoError(t, locStore.Add(ctx, localCafe))
+	r := reconciliation.NewReconciler(locStore, people.NewInMemoryStore())
+
+	result, err := r.ProcessPayload(ctx, "bob", payload)
+
+	require.NoError(t, err)
+	assert.Equal(t, localCafe.ID, result.LocationMappings[senderLoc.ID], "a local location without coordinates is matched by name and category")
+	assert.Empty(t, result.PossibleMatches)
+	trace, ok := result.Trace(reconciliation.EntityLocation, senderLoc.ID)
+	require.True(t, ok)
+	assert.Equal(t, 1, trace.Considered)
+	assert.Zero(t, locStore.fullScans, "a located incoming location does not scan every local location")
+}
+
+func TestReconciler_ProcessPayload_CreatesOnlyGroupsWithoutCandidates(t *testing.T) {
+	ctx := context.Background()
+	globalID := "jim@example.com"
+	senderJim := people.Person{ID: uuid.New(), Name: "Jim", GlobalID: &globalID}
+	senderClimbers := people.Group{ID: uuid.New(), Name: "Climbers", MemberIDs: []uuid.UUID{senderJim.ID}}
+	senderReaders := people.Group{ID: uuid.New(), Name: "Readers"}
+	payload := sharing.SharedPayload{
+		Intention: intentions.Intention{ID: uuid.New(), User: "alice", Action: "Climb", Targets: []intentions.Target{intentions.ProximityTarget{GroupIDs: []uuid.UUID{senderClimbers.ID, senderReaders.ID}}}, StartTime: time.Now(), EndTime: time.Now().Add(time.Hour)},
+		People:    map[string]people.Person{senderJim.ID.String(): senderJim},
+		Groups:    map[string]people.Group{senderClimbers.ID.String(): senderClimbers, senderReaders.ID.String(): senderReaders},
+	}
+
+	personStore := people.NewInMemoryStore()
+	localJim := people.Person{ID: uuid.New(), Name: "Jim", GlobalID: &globalID}
+	require.NoError(t, personStore.AddPerson(ctx, localJim))
+	localClimbers := people.Group{ID: uuid.New(), Name: "Climbers", MemberIDs: []uuid.UUID{localJim.ID, uuid.New()}}
+	require.NoError(t, personStore.AddGroup(ctx, localClimbers))
+	r := reconciliation.NewReconciler(locations.NewInMemoryStore(), personStore, reconciliation.WithCreateUnmatched())
+
+	result, err := r.ProcessPayload(ctx, "bob", payload)
+
+	require.NoError(t, err)
+	assert.Equal(t, localJim.ID, result.PersonMappings[senderJim.ID])
+	require.Len(t, result.CreatedGroups, 1, "only the group without candidates is created")
+	assert.Equal(t, result.GroupMappings[senderReaders.ID], result.CreatedGroups[0])
+
+	_, mapped := result.GroupMappings[senderClimbers.ID]
+	assert.False(t, mapped, "half the members shared is below the exact threshold")
+	require.Len(t, result.GroupCandidates[senderClimbers.ID], 1)
+	assert.Equal(t, localClimbers.ID, result.GroupCandidates[senderClimbers.ID][0].LocalID)
+	trace, ok := result.Trace(reconciliation.EntityGroup, senderClimbers.ID)
+	require.True(t, ok)
+	assert.Equal(t, reconciliation.RuleBelowExact, trace.Rule)
+	assert.Equal(t, reconciliation.OutcomeUnmatched, trace.Outcome)
+	groups, err := personStore.ListGroupsForPerson(ctx, localJim.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []people.Group{localClimbers}, groups, "no duplicate Climbers group is created")
+}
+
+func TestReconciler_ProcessPayload_RejectsInvalidSenderIDs(t *testing.T) {
+	ctx := context.Background()
+	intent := intentions.Intention{ID: uuid.New(), User: "alice", Action: "Climb", StartTime: time.Now(), EndTime: time.Now().Add(time.Hour)}
+
+	testCases := []struct {
+		name    string
+		payload sharing.SharedPayload
+	}{
+		{"location", sharing.SharedPayload{Intention: intent, Locations: map[string]locations.Location{"not-a-uuid": {Name: "Crag"}}}},
+		{"person", sharing.SharedPayload{Intention: intent, People: map[string]people.Person{"not-a-uuid": {Name: "Jim"}}}},
+		{"group", sharing.SharedPayload{Intention: intent, Groups: map[string]people.Group{"not-a-uuid": {Name: "Climbers"}}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := reconciliation.NewReconciler(locations.NewInMemoryStore(), people.NewInMemoryStore(), reconciliation.WithCreateUnmatched())
+
+			_, err := r.ProcessPayload(ctx, "bob", tc.payload)
+
+			assert.ErrorIs(t, err, apperr.ErrInvalid)
+			assert.ErrorContains(t, err, "not-a-uuid")
+		})
+	}
+}
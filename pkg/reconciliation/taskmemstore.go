@@ -0,0 +1,148 @@
+// FILE: pkg/reconciliation/taskmemstore.go
+
+package reconciliation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+)
+
+// InMemoryTaskStore is a thread-safe, in-memory implementation of the TaskStore interface.
+type InMemoryTaskStore struct {
+	sync.RWMutex
+	pending map[uuid.UUID]PendingIntention
+	tasks   map[uuid.UUID]Task
+}
+
+// NewInMemoryTaskStore creates a new in-memory task store.
+func NewInMemoryTaskStore() *InMemoryTaskStore {
+	return &InMemoryTaskStore{
+		pending: make(map[uuid.UUID]PendingIntention),
+		tasks:   make(map[uuid.UUID]Task),
+	}
+}
+
+// AddPending saves a held-back intention together with its tasks.
+func (s *InMemoryTaskStore) AddPending(ctx context.Context, pending PendingIntention, tasks []Task) error {
+	s.Lock()
+	defer s.Unlock()
+	s.pending[pending.ID] = copyPending(pending)
+	for _, task := range tasks {
+		s.tasks[task.ID] = task
+	}
+	return nil
+}
+
+// GetPending retrieves a held-back intention by its ID.
+func (s *InMemoryTaskStore) GetPending(ctx context.Context, id uuid.UUID) (PendingIntention, error) {
+	s.RLock()
+	defer s.RUnlock()
+	pending, ok := s.pending[id]
+	if !ok {
+		return PendingIntention{}, apperr.Errorf(apperr.ErrNotFound, "pending intention with ID %s not found", id)
+	}
+	return copyPending(pending), nil
+}
+
+// DeletePending removes a finalized intention.
+func (s *InMemoryTaskStore) DeletePending(ctx context.Context, id uuid.UUID) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.pending[id]; !ok {
+		return apperr.Errorf(apperr.ErrNotFound, "pending intention with ID %s not found", id)
+	}
+	delete(s.pending, id)
+	return nil
+}
+
+// GetTask retrieves a task by its ID.
+func (s *InMemoryTaskStore) GetTask(ctx context.Context, id uuid.UUID) (Task, error) {
+	s.RLock()
+	defer s.RUnlock()
+	task, ok := s.tasks[id]
+	if !ok {
+		return Task{}, apperr.Errorf(apperr.ErrNotFound, "reconciliation task with ID %s not found", id)
+	}
+	return task, nil
+}
+
+// ListTasks returns tasks with the given status, oldest first.
+func (s *InMemoryTaskStore) ListTasks(ctx context.Context, status TaskStatus) ([]Task, error) {
+	s.RLock()
+	defer s.RUnlock()
+	var results []Task
+	for _, task := range s.tasks {
+		if status == "" || task.Status == status {
+			results = append(results, task)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if !results[i].CreatedAt.Equal(results[j].CreatedAt) {
+			return results[i].CreatedAt.Before(results[j].CreatedAt)
+		}
+		return results[i].ID.String() < results[j].ID.String()
+	})
+	return results, nil
+}
+
+// ResolveTask marks a pending task resolved and records its mapping.
+func (s *InMemoryTaskStore) ResolveTask(ctx context.Context, id uuid.UUID, status TaskStatus, localID uuid.UUID, resolvedAt time.Time) (PendingIntention, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return PendingIntention{}, false, apperr.Errorf(apperr.ErrNotFound, "reconciliation task with ID %s not found", id)
+	}
+	if task.Status != TaskPending {
+		return PendingIntention{}, false, fmt.Errorf("task %s is %s: %w", id, task.Status, ErrTaskResolved)
+	}
+	pending, ok := s.pending[task.PendingID]
+	if !ok {
+		return PendingIntention{}, false, apperr.Errorf(apperr.ErrNotFound, "pending intention with ID %s not found", task.PendingID)
+	}
+
+	task.Status = status
+	task.ResolvedID = &localID
+	task.ResolvedAt = &resolvedAt
+	s.tasks[id] = task
+
+	pending = copyPending(pending)
+	pending.Mappings.Record(task.Kind, task.SenderEntityID, localID)
+	s.pending[pending.ID] = pending
+
+	complete := true
+	for _, taskID := range pending.TaskIDs {
+		if s.tasks[taskID].Status == TaskPending {
+			complete = false
+			break
+		}
+	}
+	return copyPending(pending), complete, nil
+}
+
+// copyPending copies the mapping tables so callers cannot alter the stored copy.
+func copyPending(p PendingIntention) PendingIntention {
+	locationMappings := make(map[uuid.UUID]uuid.UUID, len(p.Mappings.LocationMappings))
+	for k, v := range p.Mappings.LocationMappings {
+		locationMappings[k] = v
+	}
+	personMappings := make(map[uuid.UUID]uuid.UUID, len(p.Mappings.PersonMappings))
+	for k, v := range p.Mappings.PersonMappings {
+		personMappings[k] = v
+	}
+	groupMappings := make(map[uuid.UUID]uuid.UUID, len(p.Mappings.GroupMappings))
+	for k, v := range p.Mappings.GroupMappings {
+		groupMappings[k] = v
+	}
+	p.Mappings.LocationMappings = locationMappings
+	p.Mappings.PersonMappings = personMappings
+	p.Mappings.GroupMappings = groupMappings
+	return p
+}
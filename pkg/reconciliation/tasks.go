@@ -0,0 +1,91 @@
+// FILE: pkg/reconciliation/tasks.go
+
+package reconciliation
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/illmade-knight/action-intention/pkg/locations"
+	"github.com/illmade-knight/action-intention/pkg/people"
+)
+
+// EntityKind says which kind of local record an incoming entity is matched against.
+type EntityKind string
+
+const (
+	EntityLocation EntityKind = "location"
+	EntityPerson   EntityKind = "person"
+	// EntityGroup is matched by membership. Groups are never queued as tasks.
+	EntityGroup EntityKind = "group"
+	// EntityIntention is only used for unresolved EventTarget references.
+	EntityIntention EntityKind = "intention"
+)
+
+// Candidate is a local record that an incoming entity might correspond to.
+type Candidate struct {
+	LocalID uuid.UUID `json:"local_id"`
+	Name    string    `json:"name"`
+	// Score ranks candidates from 0 (no match) to 1 (certain match).
+	Score float64 `json:"score"`
+	// Explanation says why the matcher gave this score, e.g. "name matches, 120 m apart".
+	Explanation string `json:"explanation"`
+}
+
+// TaskStatus records whether and how a reconciliation task was resolved.
+type TaskStatus string
+
+const (
+	// TaskPending is waiting for the user to decide.
+	TaskPending TaskStatus = "pending"
+	// TaskConfirmed mapped the incoming entity onto one of the candidates.
+	TaskConfirmed TaskStatus = "confirmed"
+	// TaskCreated rejected the candidates and created a new local record.
+	TaskCreated TaskStatus = "created"
+	// TaskMerged mapped onto a candidate and copied the incoming details into it.
+	TaskMerged TaskStatus = "merged"
+)
+
+// Task asks the user to resolve one possible match from a received payload.
+// Exactly one of Location and Person holds the incoming entity, as the sender
+// described it.
+type Task struct {
+	ID uuid.UUID `json:"id"`
+	// PendingID is the PendingIntention waiting on this task.
+	PendingID      uuid.UUID           `json:"pending_id"`
+	Kind           EntityKind          `json:"kind"`
+	SenderID       string              `json:"sender_id"`
+	SenderEntityID uuid.UUID           `json:"sender_entity_id"`
+	Location       *locations.Location `json:"location,omitempty"`
+	Person         *people.Person      `json:"person,omitempty"`
+	Candidates     []Candidate         `json:"candidates"`
+	Status         TaskStatus          `json:"status"`
+	// ResolvedID is the local record the entity was mapped to once resolved.
+	ResolvedID *uuid.UUID `json:"resolved_id,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// HasCandidate reports whether localID is one of the task's candidates.
+func (t Task) HasCandidate(localID uuid.UUID) bool {
+	for _, c := range t.Candidates {
+		if c.LocalID == localID {
+			return true
+		}
+	}
+	return false
+}
+
+// PendingIntention is a received intention held back until all of its
+// reconciliation tasks are resolved. The intention keeps the sender's IDs;
+// Mappings grows as tasks are resolved.
+type PendingIntention struct {
+	ID          uuid.UUID            `json:"id"`
+	SenderID    string               `json:"sender_id"`
+	RecipientID string               `json:"recipient_id"`
+	Intention   intentions.Intention `json:"intention"`
+	Mappings    MappingResult        `json:"mappings"`
+	TaskIDs     []uuid.UUID          `json:"task_ids"`
+	CreatedAt   time.Time            `json:"created_at"`
+}
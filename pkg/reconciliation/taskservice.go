@@ -0,0 +1,272 @@
+// FILE: pkg/reconciliation/taskservice.go
+
+package reconciliation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/locations"
+	"github.com/illmade-knight/action-intention/pkg/people"
+	"github.com/illmade-knight/action-intention/pkg/sharing"
+	"github.com/rs/zerolog"
+)
+
+// TaskService turns possible matches into tasks and applies the user's
+// decisions to them. Each resolve method returns the pending intention and
+// whether it is complete, i.e. all of its tasks are resolved and it can be
+// finalized with its Mappings. Each decision is also saved in the mapping
+// store, so the sender's next share of the entity is mapped the same way.
+type TaskService struct {
+	store       TaskStore
+	mappings    MappingStore
+	locations   locations.Store
+	personStore people.Store
+	logger      zerolog.Logger
+}
+
+func NewTaskService(store TaskStore, mappings MappingStore, locStore locations.Store, personStore people.Store, logger zerolog.Logger) *TaskService {
+	return &TaskService{
+		store:       store,
+		mappings:    mappings,
+		locations:   locStore,
+		personStore: personStore,
+		logger:      logger,
+	}
+}
+
+// OpenTasks holds back a received intention and creates a task for each of its
+// possible matches. It must only be called when mappings.PossibleMatches is
+// not empty.
+func (s *TaskService) OpenTasks(ctx context.Context, senderID, recipientID string, payload sharing.SharedPayload, mappings MappingResult) (PendingIntention, error) {
+	now := time.Now()
+	pending := PendingIntention{
+		ID:          uuid.New(),
+		SenderID:    senderID,
+		RecipientID: recipientID,
+		Intention:   payload.Intention,
+		Mappings:    MappingResult{LocationMappings: mappings.LocationMappings, PersonMappings: mappings.PersonMappings, GroupMappings: mappings.GroupMappings},
+		CreatedAt:   now,
+	}
+
+	tasks := make([]Task, 0, len(mappings.PossibleMatches))
+	for _, match := range mappings.PossibleMatches {
+		task := Task{
+			ID:             uuid.New(),
+			PendingID:      pending.ID,
+			Kind:           match.Kind,
+			SenderID:       senderID,
+			SenderEntityID: match.SenderEntityID,
+			Candidates:     match.Candidates,
+			Status:         TaskPending,
+			CreatedAt:      now,
+		}
+		switch match.Kind {
+		case EntityLocation:
+			loc, ok := payload.Locations[match.SenderEntityID.String()]
+			if !ok {
+				return PendingIntention{}, apperr.Errorf(apperr.ErrInvalid, "payload has no location %s", match.SenderEntityID)
+			}
+			task.Location = &loc
+		case EntityPerson:
+			person, ok := payload.People[match.SenderEntityID.String()]
+			if !ok {
+				return PendingIntention{}, apperr.Errorf(apperr.ErrInvalid, "payload has no person %s", match.SenderEntityID)
+			}
+			task.Person = &person
+		default:
+			return PendingIntention{}, apperr.Errorf(apperr.ErrInvalid, "unknown entity kind %q", match.Kind)
+		}
+		tasks = append(tasks, task)
+		pending.TaskIDs = append(pending.TaskIDs, task.ID)
+	}
+
+	if err := s.store.AddPending(ctx, pending, tasks); err != nil {
+		return PendingIntention{}, fmt.Errorf("failed to save reconciliation tasks: %w", err)
+	}
+	return pending, nil
+}
+
+// ListTasks returns tasks with the given status, oldest first. An empty status
+// lists every task.
+func (s *TaskService) ListTasks(ctx context.Context, status TaskStatus) ([]Task, error) {
+	return s.store.ListTasks(ctx, status)
+}
+
+// GetTask fetches a single task.
+func (s *TaskService) GetTask(ctx context.Context, id uuid.UUID) (Task, error) {
+	return s.store.GetTask(ctx, id)
+}
+
+// GetPending fetches an intention that is waiting on tasks.
+func (s *TaskService) GetPending(ctx context.Context, id uuid.UUID) (PendingIntention, error) {
+	return s.store.GetPending(ctx, id)
+}
+
+// ClosePending discards a pending intention once it has been finalized.
+func (s *TaskService) ClosePending(ctx context.Context, id uuid.UUID) error {
+	return s.store.DeletePending(ctx, id)
+}
+
+// ConfirmTask maps the incoming entity onto one of the task's candidates.
+func (s *TaskService) ConfirmTask(ctx context.Context, id, localID uuid.UUID) (PendingIntention, bool, error) {
+	task, err := s.candidateTask(ctx, id, localID)
+	if err != nil {
+		return PendingIntention{}, false, err
+	}
+	return s.resolve(ctx, task, TaskConfirmed, localID)
+}
+
+// RejectTask rejects every candidate and creates a new local record from the
+// incoming entity, marked with where it came from. The record is stored before
+// the task is resolved, so a failed create leaves the task open to retry. Its
+// ID is derived from the task's, so retries and concurrent rejects all write
+// the same record rather than leaving orphans.
+func (s *TaskService) RejectTask(ctx context.Context, id uuid.UUID) (PendingIntention, bool, error) {
+	task, err := s.pendingTask(ctx, id)
+	if err != nil {
+		return PendingIntention{}, false, err
+	}
+	pending, err := s.store.GetPending(ctx, task.PendingID)
+	if err != nil {
+		return PendingIntention{}, false, err
+	}
+
+	localID := createdRecordID(task.ID)
+	switch task.Kind {
+	case EntityLocation:
+		loc := NewLocalLocation(*task.Location, task.SenderID, task.SenderEntityID, pending.RecipientID)
+		loc.ID = localID
+		err = s.locations.Add(ctx, loc)
+	case EntityPerson:
+		person := NewLocalPerson(*task.Person, task.SenderID, task.SenderEntityID)
+		person.ID = localID
+		err = s.personStore.AddPerson(ctx, person)
+	default:
+		return PendingIntention{}, false, apperr.Errorf(apperr.ErrInvalid, "unknown entity kind %q", task.Kind)
+	}
+	if err != nil {
+		return PendingIntention{}, false, fmt.Errorf("failed to create %s for task %s: %w", task.Kind, id, err)
+	}
+	return s.resolve(ctx, task, TaskCreated, localID)
+}
+
+// MergeTask maps the incoming entity onto one of the task's candidates and
+// fills in any details the local record is missing, such as a GlobalID,
+// coordinates, an address or more handles. The task is resolved first, so a
+// merge is only applied by the call that resolved it. The details are only
+// extra information, so failing to merge them is logged rather than failing a
+// decision that has already been recorded.
+func (s *TaskService) MergeTask(ctx context.Context, id, localID uuid.UUID) (PendingIntention, bool, error) {
+	task, err := s.candidateTask(ctx, id, localID)
+	if err != nil {
+		return PendingIntention{}, false, err
+	}
+	pending, complete, err := s.resolve(ctx, task, TaskMerged, localID)
+	if err != nil {
+		return PendingIntention{}, false, err
+	}
+	if err := s.mergeDetails(ctx, task, localID); err != nil {
+		s.logger.Warn().Err(err).Str("kind", string(task.Kind)).Stringer("local_id", localID).Msg("Failed to merge details for resolved task")
+	}
+	return pending, complete, nil
+}
+
+// mergeDetails copies the details a local record is missing from the task's
+// incoming entity.
+func (s *TaskService) mergeDetails(ctx context.Context, task Task, localID uuid.UUID) error {
+	switch task.Kind {
+	case EntityLocation:
+		local, err := s.locations.GetByID(ctx, localID)
+		if err != nil {
+			return err
+		}
+		incoming := task.Location
+		if local.GlobalID == nil {
+			local.GlobalID = incoming.GlobalID
+		}
+		if local.Category == "" {
+			local.Category = incoming.Category
+			local.Matcher.Category = incoming.Matcher.Category
+		}
+		if local.Matcher.Lat == nil || local.Matcher.Lon == nil {
+			local.Matcher.Lat, local.Matcher.Lon = incoming.Matcher.Lat, incoming.Matcher.Lon
+			local.Coordinates = incoming.Coordinates
+		}
+		if local.Address == nil {
+			local.Address = incoming.Address
+		}
+		if local.RadiusMeters == nil {
+			local.RadiusMeters = incoming.RadiusMeters
+		}
+		if err := s.locations.Update(ctx, local); err != nil {
+			return fmt.Errorf("failed to merge location: %w", err)
+		}
+	case EntityPerson:
+		local, err := s.personStore.GetPerson(ctx, localID)
+		if err != nil {
+			return err
+		}
+		incoming := task.Person
+		if local.GlobalID == nil {
+			local.GlobalID = incoming.GlobalID
+		}
+		local.Matcher.Handles = people.MergeHandles(local.Matcher.Handles, incoming.Matcher.Handles)
+		if local.UserID == nil {
+			local.UserID = incoming.UserID
+		}
+		// AddPerson overwrites the stored person with the merged one.
+		if err := s.personStore.AddPerson(ctx, local); err != nil {
+			return fmt.Errorf("failed to merge person: %w", err)
+		}
+	}
+	return nil
+}
+
+// resolve records the decision on a task and saves it as the entity's mapping.
+// The mapping is only a shortcut for later shares, so failing to save it is
+// logged rather than failing a decision that has already been recorded.
+func (s *TaskService) resolve(ctx context.Context, task Task, status TaskStatus, localID uuid.UUID) (PendingIntention, bool, error) {
+	now := time.Now()
+	pending, complete, err := s.store.ResolveTask(ctx, task.ID, status, localID, now)
+	if err != nil {
+		return PendingIntention{}, false, err
+	}
+	mapping := IdentityMapping{SenderID: task.SenderID, SenderEntityID: task.SenderEntityID, Kind: task.Kind, LocalID: localID, UpdatedAt: now}
+	if err := s.mappings.PutMappings(ctx, []IdentityMapping{mapping}); err != nil {
+		s.logger.Warn().Err(err).Str("kind", string(task.Kind)).Stringer("sender_entity_id", task.SenderEntityID).Msg("Failed to save mapping for resolved task")
+	}
+	return pending, complete, nil
+}
+
+// createdRecordID derives the ID of the local record a rejected task creates.
+func createdRecordID(taskID uuid.UUID) uuid.UUID {
+	return uuid.NewSHA1(taskID, []byte("created"))
+}
+
+// pendingTask fetches a task and checks it is still waiting for a decision.
+func (s *TaskService) pendingTask(ctx context.Context, id uuid.UUID) (Task, error) {
+	task, err := s.store.GetTask(ctx, id)
+	if err != nil {
+		return Task{}, err
+	}
+	if task.Status != TaskPending {
+		return Task{}, fmt.Errorf("task %s is %s: %w", id, task.Status, ErrTaskResolved)
+	}
+	return task, nil
+}
+
+// candidateTask fetches a pending task and checks localID is one of its candidates.
+func (s *TaskService) candidateTask(ctx context.Context, id, localID uuid.UUID) (Task, error) {
+	task, err := s.pendingTask(ctx, id)
+	if err != nil {
+		return Task{}, err
+	}
+	if !task.HasCandidate(localID) {
+		return Task{}, apperr.Errorf(apperr.ErrInvalid, "%s is not a candidate for task %s", localID, id)
+	}
+	return task, nil
+}
@@ -0,0 +1,110 @@
+package reconciliation_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/illmade-knight/action-intention/pkg/locations"
+	"github.com/illmade-knight/action-intention/pkg/people"
+	"github.com/illmade-knight/action-intention/pkg/reconciliation"
+	"github.com/illmade-knight/action-intention/pkg/sharing"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openLocationTask holds back an intention with one possible location match
+// and returns its task.
+func openLocationTask(t *testing.T, svc *reconciliation.TaskService) reconciliation.Task {
+	t.Helper()
+	ctx := context.Background()
+	senderLoc := locations.Location{ID: uuid.New(), Name: "Corner Cafe", Type: locations.LocationTypeShared, Matcher: locations.LocationMatcher{Name: "Corner Cafe", Category: "Cafe"}}
+	payload := sharing.SharedPayload{
+		Intention: intentions.Intention{ID: uuid.New(), User: "alice", Action: "Coffee", Targets: []intentions.Target{intentions.LocationTarget{LocationID: senderLoc.ID}}, StartTime: time.Now(), EndTime: time.Now().Add(time.Hour)},
+		Locations: map[string]locations.Location{senderLoc.ID.String(): senderLoc},
+	}
+	mappings := reconciliation.MappingResult{
+		PossibleMatches: []reconciliation.PossibleMatch{{
+			Kind:           reconciliation.EntityLocation,
+			SenderEntityID: senderLoc.ID,
+			Candidates:     []reconciliation.Candidate{{LocalID: uuid.New(), Name: "Corner Cafe", Score: 0.6, Explanation: "name matches, category differs"}},
+		}},
+	}
+	pending, err := svc.OpenTasks(ctx, "alice", "bob", payload, mappings)
+	require.NoError(t, err)
+	task, err := svc.GetTask(ctx, pending.TaskIDs[0])
+	require.NoError(t, err)
+	return task
+}
+
+func TestTaskService_RejectTask(t *testing.T) {
+	ctx := context.Background()
+	newService := func() (*reconciliation.TaskService, *locations.InMemoryStore) {
+		locStore := locations.NewInMemoryStore()
+		svc := reconciliation.NewTaskService(reconciliation.NewInMemoryTaskStore(), reconciliation.NewInMemoryMappingStore(), locStore, people.NewInMemoryStore(), zerolog.Nop())
+		return svc, locStore
+	}
+
+	t.Run("Creates a local record", func(t *testing.T) {
+		svc, locStore := newService()
+		task := openLocationTask(t, svc)
+
+		pending, complete, err := svc.RejectTask(ctx, task.ID)
+
+		require.NoError(t, err)
+		assert.True(t, complete)
+		localID := pending.Mappings.LocationMappings[task.SenderEntityID]
+		created, err := locStore.GetByID(ctx, localID)
+		require.NoError(t, err)
+		assert.True(t, created.Provenance.Is("alice", task.SenderEntityID))
+	})
+
+	t.Run("A second reject creates nothing", func(t *testing.T) {
+		svc, locStore := newService()
+		task := openLocationTask(t, svc)
+		_, _, err := svc.RejectTask(ctx, task.ID)
+		require.NoError(t, err)
+
+		_, _, err = svc.RejectTask(ctx, task.ID)
+
+		assert.ErrorIs(t, err, reconciliation.ErrTaskResolved)
+		assert.ErrorIs(t, err, apperr.ErrConflict)
+		all, err := locStore.ListAllForMatching(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+	})
+
+	t.Run("Concurrent rejects create one record", func(t *testing.T) {
+		svc, locStore := newService()
+		task := openLocationTask(t, svc)
+
+		const callers = 5
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		succeeded := 0
+		for i := 0; i < callers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, _, err := svc.RejectTask(ctx, task.ID); err == nil {
+					mu.Lock()
+					succeeded++
+					mu.Unlock()
+				} else {
+					assert.ErrorIs(t, err, reconciliation.ErrTaskResolved)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, succeeded)
+		all, err := locStore.ListAllForMatching(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+	})
+}
@@ -0,0 +1,33 @@
+// FILE: pkg/reconciliation/taskstore.go
+
+package reconciliation
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+)
+
+// ErrTaskResolved is returned when resolving a task that is no longer pending.
+// It matches apperr.ErrConflict.
+var ErrTaskResolved = apperr.New(apperr.ErrConflict, "reconciliation task has already been resolved")
+
+// TaskStore persists reconciliation tasks and the intentions waiting on them.
+type TaskStore interface {
+	// AddPending saves a held-back intention together with its tasks.
+	AddPending(ctx context.Context, pending PendingIntention, tasks []Task) error
+	GetPending(ctx context.Context, id uuid.UUID) (PendingIntention, error)
+	// DeletePending removes a finalized intention. Its tasks are kept as a record
+	// of how each match was resolved.
+	DeletePending(ctx context.Context, id uuid.UUID) error
+	GetTask(ctx context.Context, id uuid.UUID) (Task, error)
+	// ListTasks returns tasks with the given status, oldest first. An empty
+	// status lists every task.
+	ListTasks(ctx context.Context, status TaskStatus) ([]Task, error)
+	// ResolveTask atomically marks a pending task resolved, records its mapping
+	// on the pending intention and returns that intention. complete is true for
+	// exactly one caller: the one that resolved the intention's last task.
+	ResolveTask(ctx context.Context, id uuid.UUID, status TaskStatus, localID uuid.UUID, resolvedAt time.Time) (pending PendingIntention, complete bool, err error)
+}
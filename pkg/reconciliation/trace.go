@@ -0,0 +1,73 @@
+// FILE: pkg/reconciliation/trace.go
+
+package reconciliation
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// DecisionRule is the rule that decided how an incoming entity was mapped.
+type DecisionRule string
+
+const (
+	// RuleMappedBefore: the sender's entity was mapped by an earlier share.
+	RuleMappedBefore DecisionRule = "mapped_before"
+	// RuleGlobalID: a local record has the same global ID.
+	RuleGlobalID DecisionRule = "global_id"
+	// RuleExactScore: the best candidate scored at or above the exact
+	// threshold and no other candidate scored as high.
+	RuleExactScore DecisionRule = "exact_score"
+	// RuleTiedScore: the best candidate scored at or above the exact
+	// threshold, but so did another.
+	RuleTiedScore DecisionRule = "tied_score"
+	// RuleBelowExact: no candidate scored at or above the exact threshold.
+	RuleBelowExact DecisionRule = "below_exact"
+	// RuleNoCandidates: no local record scored at or above the possible
+	// threshold.
+	RuleNoCandidates DecisionRule = "no_candidates"
+)
+
+// DecisionOutcome is what ProcessPayload did with an incoming entity.
+type DecisionOutcome string
+
+const (
+	OutcomeMapped    DecisionOutcome = "mapped"
+	OutcomePossible  DecisionOutcome = "possible"
+	OutcomeUnmatched DecisionOutcome = "unmatched"
+	OutcomeCreated   DecisionOutcome = "created"
+)
+
+// DecisionTrace explains how one incoming entity was mapped: how many local
+// records were considered, the candidates that were kept with their scores,
+// and the rule that decided the outcome. LocalID is set when the outcome is
+// mapped or created.
+type DecisionTrace struct {
+	Kind           EntityKind      `json:"kind"`
+	SenderEntityID uuid.UUID       `json:"sender_entity_id"`
+	Name           string          `json:"name"`
+	Considered     int             `json:"considered"`
+	Candidates     []Candidate     `json:"candidates"`
+	Rule           DecisionRule    `json:"rule"`
+	Outcome        DecisionOutcome `json:"outcome"`
+	LocalID        uuid.UUID       `json:"local_id,omitempty"`
+}
+
+// Summary describes the decision in a sentence that can be shown to a user.
+func (t DecisionTrace) Summary() string {
+	switch t.Outcome {
+	case OutcomeMapped:
+		best := t.Candidates[0]
+		return fmt.Sprintf("%s %q is your %q: %s (score %.2f)", t.Kind, t.Name, best.Name, best.Explanation, best.Score)
+	case OutcomePossible:
+		return fmt.Sprintf("%s %q might be one of %d of yours; best is %q: %s (score %.2f)", t.Kind, t.Name, len(t.Candidates), t.Candidates[0].Name, t.Candidates[0].Explanation, t.Candidates[0].Score)
+	case OutcomeCreated:
+		return fmt.Sprintf("%s %q matched none of yours and was added", t.Kind, t.Name)
+	default:
+		if len(t.Candidates) > 0 {
+			return fmt.Sprintf("%s %q could not be matched for certain; best is %q: %s (score %.2f)", t.Kind, t.Name, t.Candidates[0].Name, t.Candidates[0].Explanation, t.Candidates[0].Score)
+		}
+		return fmt.Sprintf("%s %q matched none of yours", t.Kind, t.Name)
+	}
+}
@@ -0,0 +1,130 @@
+// FILE: pkg/reconciliation/translate.go
+
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+)
+
+// UnresolvedReference is a target reference of a received intention that has
+// no local mapping. The translated intention keeps the sender's ID for it.
+type UnresolvedReference struct {
+	Kind     EntityKind `json:"kind"`
+	SenderID uuid.UUID  `json:"sender_id"`
+}
+
+// Translation is a received intention rewritten into local IDs.
+type Translation struct {
+	Intention  intentions.Intention
+	Unresolved []UnresolvedReference
+}
+
+// Translator applies a MappingResult to a received intention and saves the
+// local copy.
+type Translator struct {
+	intentions *intentions.IntentionService
+}
+
+func NewTranslator(intentionSvc *intentions.IntentionService) *Translator {
+	return &Translator{intentions: intentionSvc}
+}
+
+// Translate rewrites the location, person and group references of a received
+// intention through the mappings. An intention reference, as in an
+// EventTarget, is rewritten to the local copy of the sender's intention it
+// refers to, if one has been received. Targets are rewritten through their
+// codec's References and Rewrite hooks, so registered custom kinds are
+// translated too; every reference of a kind without a Rewrite hook keeps the
+// sender's ID. References that cannot be translated are listed, once each, in
+// Unresolved.
+func (t *Translator) Translate(ctx context.Context, intent intentions.Intention, mappings MappingResult) (Translation, error) {
+	var unresolved []UnresolvedReference
+	seen := make(map[UnresolvedReference]bool)
+	unresolve := func(kind EntityKind, id uuid.UUID) {
+		ref := UnresolvedReference{Kind: kind, SenderID: id}
+		if !seen[ref] {
+			seen[ref] = true
+			unresolved = append(unresolved, ref)
+		}
+	}
+	mapID := func(kind EntityKind, ids map[uuid.UUID]uuid.UUID, id uuid.UUID) uuid.UUID {
+		if local, ok := ids[id]; ok {
+			return local
+		}
+		unresolve(kind, id)
+		return id
+	}
+	mapIDs := func(kind EntityKind, ids map[uuid.UUID]uuid.UUID, senderIDs []uuid.UUID) []uuid.UUID {
+		if senderIDs == nil {
+			return nil
+		}
+		local := make([]uuid.UUID, len(senderIDs))
+		for i, id := range senderIDs {
+			local[i] = mapID(kind, ids, id)
+		}
+		return local
+	}
+	// intentionIDs maps the sender's intentions to the local copies received
+	// so far; there is no MappingResult for them.
+	intentionIDs := make(map[uuid.UUID]uuid.UUID)
+
+	targets := make([]intentions.Target, len(intent.Targets))
+	for i, target := range intent.Targets {
+		refs := intentions.TargetReferences(target)
+		for _, id := range refs.IntentionIDs {
+			local, err := t.intentions.FindReceivedIntention(ctx, intent.User, id)
+			switch {
+			case err == nil:
+				intentionIDs[id] = local.ID
+			case !errors.Is(err, apperr.ErrNotFound):
+				return Translation{}, fmt.Errorf("failed to look up intention %s: %w", id, err)
+			}
+		}
+		local := intentions.TargetRefs{
+			LocationIDs:  mapIDs(EntityLocation, mappings.LocationMappings, refs.LocationIDs),
+			PersonIDs:    mapIDs(EntityPerson, mappings.PersonMappings, refs.PersonIDs),
+			GroupIDs:     mapIDs(EntityGroup, mappings.GroupMappings, refs.GroupIDs),
+			IntentionIDs: mapIDs(EntityIntention, intentionIDs, refs.IntentionIDs),
+		}
+		rewritten, ok := intentions.RewriteTargetReferences(target, local)
+		if !ok {
+			for _, id := range refs.LocationIDs {
+				unresolve(EntityLocation, id)
+			}
+			for _, id := range refs.PersonIDs {
+				unresolve(EntityPerson, id)
+			}
+			for _, id := range refs.GroupIDs {
+				unresolve(EntityGroup, id)
+			}
+			for _, id := range refs.IntentionIDs {
+				unresolve(EntityIntention, id)
+			}
+		}
+		targets[i] = rewritten
+	}
+	intent.Targets = targets
+	return Translation{Intention: intent, Unresolved: unresolved}, nil
+}
+
+// Save translates a received intention and stores it with
+// SaveReceivedIntention, which links the copy to the sender's original. The
+// returned Translation holds the stored copy.
+func (t *Translator) Save(ctx context.Context, intent intentions.Intention, mappings MappingResult) (Translation, error) {
+	translation, err := t.Translate(ctx, intent, mappings)
+	if err != nil {
+		return Translation{}, err
+	}
+	local, err := t.intentions.SaveReceivedIntention(ctx, translation.Intention)
+	if err != nil {
+		return Translation{}, err
+	}
+	translation.Intention = local
+	return translation, nil
+}
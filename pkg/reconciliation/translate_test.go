@@ -0,0 +1,83 @@
+package reconciliation_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/illmade-knight/action-intention/pkg/reconciliation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// meetupTarget is a custom target kind with a Rewrite hook.
+type meetupTarget struct {
+	LocationID uuid.UUID `json:"location_id"`
+	HostID     uuid.UUID `json:"host_id"`
+}
+
+func (meetupTarget) Type() string        { return "TestMeetup" }
+func (meetupTarget) Description() string { return "a meetup" }
+
+// badgeTarget is a custom target kind that reports its references but cannot
+// rewrite them.
+type badgeTarget struct {
+	PersonID uuid.UUID `json:"person_id"`
+}
+
+func (badgeTarget) Type() string        { return "TestBadge" }
+func (badgeTarget) Description() string { return "a badge" }
+
+func init() {
+	meetup := intentions.JSONTargetCodec[meetupTarget]()
+	meetup.References = func(t intentions.Target) intentions.TargetRefs {
+		m, _ := t.(meetupTarget)
+		return intentions.TargetRefs{LocationIDs: []uuid.UUID{m.LocationID}, PersonIDs: []uuid.UUID{m.HostID}}
+	}
+	meetup.Rewrite = func(t intentions.Target, refs intentions.TargetRefs) intentions.Target {
+		m, _ := t.(meetupTarget)
+		m.LocationID, m.HostID = refs.LocationIDs[0], refs.PersonIDs[0]
+		return m
+	}
+	intentions.RegisterTarget(meetupTarget{}.Type(), meetup)
+
+	badge := intentions.JSONTargetCodec[badgeTarget]()
+	badge.References = func(t intentions.Target) intentions.TargetRefs {
+		b, _ := t.(badgeTarget)
+		return intentions.TargetRefs{PersonIDs: []uuid.UUID{b.PersonID}}
+	}
+	intentions.RegisterTarget(badgeTarget{}.Type(), badge)
+}
+
+func TestTranslator_Translate_CustomTargets(t *testing.T) {
+	ctx := context.Background()
+	translator := reconciliation.NewTranslator(intentions.NewIntentionService(intentions.NewInMemoryStore()))
+	senderHall, localHall := uuid.New(), uuid.New()
+	senderHost, localHost := uuid.New(), uuid.New()
+	senderGuest := uuid.New()
+	mappings := reconciliation.MappingResult{
+		LocationMappings: map[uuid.UUID]uuid.UUID{senderHall: localHall},
+		PersonMappings:   map[uuid.UUID]uuid.UUID{senderHost: localHost, senderGuest: uuid.New()},
+	}
+	intent := intentions.Intention{
+		ID:     uuid.New(),
+		User:   "alice",
+		Action: "Meetup",
+		Targets: []intentions.Target{
+			meetupTarget{LocationID: senderHall, HostID: senderHost},
+			badgeTarget{PersonID: senderGuest},
+		},
+		StartTime: time.Now(),
+		EndTime:   time.Now().Add(time.Hour),
+	}
+
+	translation, err := translator.Translate(ctx, intent, mappings)
+
+	require.NoError(t, err)
+	require.Len(t, translation.Intention.Targets, 2)
+	assert.Equal(t, meetupTarget{LocationID: localHall, HostID: localHost}, translation.Intention.Targets[0])
+	assert.Equal(t, badgeTarget{PersonID: senderGuest}, translation.Intention.Targets[1], "a kind without a Rewrite hook keeps the sender's IDs")
+	assert.Equal(t, []reconciliation.UnresolvedReference{{Kind: reconciliation.EntityPerson, SenderID: senderGuest}}, translation.Unresolved)
+}
@@ -3,16 +3,64 @@
 package sharing
 
 import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/illmade-knight/action-intention/pkg/intentions"
 	"github.com/illmade-knight/action-intention/pkg/locations"
 	"github.com/illmade-knight/action-intention/pkg/people"
 )
 
+// PayloadKind tells the recipient how to interpret a decrypted envelope.
+type PayloadKind string
+
+const (
+	// PayloadKindShare is a SharedPayload sent for information. Payloads
+	// without a kind are treated as shares.
+	PayloadKindShare PayloadKind = "share"
+	// PayloadKindInvite is a SharedPayload inviting the recipient to take part.
+	PayloadKindInvite PayloadKind = "invite"
+	// PayloadKindRSVP is an RSVPResponse replying to an invite.
+	PayloadKindRSVP PayloadKind = "rsvp"
+)
+
+// KindOf reads the kind of a decrypted payload without decoding the rest of it.
+func KindOf(data []byte) (PayloadKind, error) {
+	var header struct {
+		Kind PayloadKind `json:"kind"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return "", fmt.Errorf("failed to read payload kind: %w", err)
+	}
+	if header.Kind == "" {
+		return PayloadKindShare, nil
+	}
+	return header.Kind, nil
+}
+
 // SharedPayload is a self-contained, portable representation of an intention
 // and all its related data (the "sub-graph").
 type SharedPayload struct {
-	Intention intentions.Intention          `json:"intention"`
-	Locations map[string]locations.Location `json:"locations"`
-	People    map[string]people.Person      `json:"people"`
-	Groups    map[string]people.Group       `json:"groups"`
+	Kind PayloadKind `json:"kind,omitempty"`
+	// Intention carries its lifecycle status and transition times, so sharing
+	// it again after a status change keeps recipients up to date.
+	Intention intentions.Intention `json:"intention"`
+	// RelatedIntentions holds intentions referenced by the shared one, such as
+	// the event behind an EventTarget, keyed by the sender's intention ID.
+	RelatedIntentions map[string]intentions.Intention `json:"related_intentions,omitempty"`
+	Locations         map[string]locations.Location   `json:"locations"`
+	People            map[string]people.Person        `json:"people"`
+	Groups            map[string]people.Group         `json:"groups"`
+}
+
+// RSVPResponse is a participant's reply to an invite, sent back to the owner of
+// the intention in its own signed envelope.
+type RSVPResponse struct {
+	Kind        PayloadKind     `json:"kind"`
+	IntentionID uuid.UUID       `json:"intention_id"`
+	Participant string          `json:"participant"`
+	RSVP        intentions.RSVP `json:"rsvp"`
+	RespondedAt time.Time       `json:"responded_at"`
 }
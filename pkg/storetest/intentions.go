@@ -0,0 +1,309 @@
+// FILE: pkg/storetest/intentions.go
+
+package storetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/intentions"
+	"github.com/illmade-knight/action-intention/pkg/provenance"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunIntentionStoreTests runs the intentions.Store conformance suite. newStore
+// must return an empty store each time it is called.
+func RunIntentionStoreTests(t *testing.T, newStore func(t *testing.T) intentions.Store) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	t.Run("Get", func(t *testing.T) {
+		store := newStore(t)
+		intent := newIntention("user-alice", "Work", now, time.Hour)
+		require.NoError(t, store.Add(ctx, intent))
+
+		got, err := store.Get(ctx, intent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, intent.Action, got.Action)
+		assert.Equal(t, intent.Targets, got.Targets)
+		assert.True(t, intent.StartTime.Equal(got.StartTime))
+
+		_, err = store.Get(ctx, uuid.New())
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+	})
+
+	t.Run("Add rejects unregistered targets", func(t *testing.T) {
+		store := newStore(t)
+		intent := newIntention("user-alice", "Mystery", now, time.Hour)
+		intent.Targets = []intentions.Target{unregisteredTarget{}}
+
+		assert.Error(t, store.Add(ctx, intent))
+		_, err := store.Get(ctx, intent.ID)
+		assert.ErrorIs(t, err, apperr.ErrNotFound, "nothing is stored")
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		store := newStore(t)
+		intent := newIntention("user-alice", "Work", now, time.Hour)
+		require.NoError(t, store.Add(ctx, intent))
+
+		changed := intent
+		changed.Action = "Work from home"
+		require.NoError(t, store.Update(ctx, changed))
+		got, err := store.Get(ctx, intent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Work from home", got.Action)
+		assert.Equal(t, intent.Version+1, got.Version)
+
+		assert.ErrorIs(t, store.Update(ctx, intent), intentions.ErrVersionConflict, "the caller's copy is stale")
+
+		missing := newIntention("user-alice", "Nothing", now, time.Hour)
+		err = store.Update(ctx, missing)
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+		assert.NotErrorIs(t, err, intentions.ErrVersionConflict, "a missing intention is not a conflict")
+	})
+
+	t.Run("Concurrent updates", func(t *testing.T) {
+		store := newStore(t)
+		intent := newIntention("user-alice", "Work", now, time.Hour)
+		require.NoError(t, store.Add(ctx, intent))
+
+		// Every writer read the same version, so exactly one may win.
+		const writers = 5
+		errs := make(chan error, writers)
+		var wg sync.WaitGroup
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				changed := intent
+				changed.Action = "Changed"
+				errs <- store.Update(ctx, changed)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		succeeded := 0
+		for err := range errs {
+			if err == nil {
+				succeeded++
+				continue
+			}
+			assert.True(t, errors.Is(err, intentions.ErrVersionConflict), "unexpected error: %v", err)
+		}
+		assert.Equal(t, 1, succeeded)
+		got, err := store.Get(ctx, intent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, intent.Version+1, got.Version)
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		store := newStore(t)
+		intent := newIntention("user-alice", "Work", now, time.Hour)
+		require.NoError(t, store.Add(ctx, intent))
+
+		require.NoError(t, store.Cancel(ctx, intent.ID))
+		got, err := store.Get(ctx, intent.ID)
+		require.NoError(t, err)
+		assert.Equal(t, intentions.StatusCancelled, got.Status)
+		assert.NotNil(t, got.CancelledAt)
+		assert.Equal(t, intent.Version+1, got.Version)
+
+		assert.ErrorIs(t, store.Cancel(ctx, uuid.New()), apperr.ErrNotFound)
+	})
+
+	t.Run("Cancel enforces the status transitions", func(t *testing.T) {
+		store := newStore(t)
+		done := newIntention("user-alice", "Work", now, time.Hour)
+		done.Status = intentions.StatusDone
+		require.NoError(t, store.Add(ctx, done))
+
+		assert.ErrorIs(t, store.Cancel(ctx, done.ID), apperr.ErrConflict)
+		got, err := store.Get(ctx, done.ID)
+		require.NoError(t, err)
+		assert.Equal(t, intentions.StatusDone, got.Status, "a finished intention is left unchanged")
+		assert.Nil(t, got.CancelledAt)
+		assert.Equal(t, done.Version, got.Version)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		store := newStore(t)
+		intent := newIntention("user-alice", "Work", now, time.Hour)
+		require.NoError(t, store.Add(ctx, intent))
+
+		require.NoError(t, store.Delete(ctx, intent.ID))
+		_, err := store.Get(ctx, intent.ID)
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+		assert.ErrorIs(t, store.Delete(ctx, intent.ID), apperr.ErrNotFound, "deleting a missing intention fails")
+	})
+
+	t.Run("FindByProvenance", func(t *testing.T) {
+		store := newStore(t)
+		senderIntentionID := uuid.New()
+		local := newIntention("user-bob", "Climb", now, time.Hour)
+		local.Provenance = provenance.New("user-bob", senderIntentionID)
+		require.NoError(t, store.Add(ctx, local))
+		require.NoError(t, store.Add(ctx, newIntention("user-bob", "Run", now, time.Hour)))
+
+		got, err := store.FindByProvenance(ctx, "user-bob", senderIntentionID)
+		require.NoError(t, err)
+		assert.Equal(t, local.ID, got.ID)
+		require.NotNil(t, got.Provenance)
+		assert.Equal(t, senderIntentionID, got.Provenance.SenderEntityID)
+
+		_, err = store.FindByProvenance(ctx, "user-carol", senderIntentionID)
+		assert.ErrorIs(t, err, apperr.ErrNotFound, "the sender is part of the provenance")
+		_, err = store.FindByProvenance(ctx, "user-bob", local.ID)
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+	})
+
+	t.Run("Query filters", func(t *testing.T) {
+		store := newStore(t)
+		park, friend := uuid.New(), uuid.New()
+		morning := newIntention("user-alice", "Morning run", now.Add(1*time.Hour), time.Hour)
+		morning.Targets = []intentions.Target{intentions.LocationTarget{LocationID: park}}
+		lunch := newIntention("user-alice", "Lunch", now.Add(3*time.Hour), time.Hour)
+		lunch.Participants = []string{"user-bob"}
+		lunch.Targets = []intentions.Target{intentions.ProximityTarget{PersonIDs: []uuid.UUID{friend}}}
+		evening := newIntention("user-alice", "Evening run", now.Add(5*time.Hour), time.Hour)
+		evening.Targets = []intentions.Target{intentions.LocationTarget{LocationID: park}}
+		other := newIntention("user-bob", "Evening run", now.Add(5*time.Hour), time.Hour)
+		for _, intent := range []intentions.Intention{evening, other, morning, lunch} {
+			require.NoError(t, store.Add(ctx, intent))
+		}
+
+		alice := "user-alice"
+		byUser, err := store.Query(ctx, intentions.QuerySpec{User: &alice})
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{morning.ID, lunch.ID, evening.ID}, intentionIDs(byUser), "results are ordered by start time")
+
+		from, to := now.Add(90*time.Minute), now.Add(210*time.Minute)
+		byRange, err := store.Query(ctx, intentions.QuerySpec{From: &from, To: &to})
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{morning.ID, lunch.ID}, intentionIDs(byRange))
+
+		activeAt := now.Add(90 * time.Minute)
+		byActive, err := store.Query(ctx, intentions.QuerySpec{ActiveAt: &activeAt})
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{morning.ID}, intentionIDs(byActive))
+
+		action := "RUN"
+		byLocation, err := store.Query(ctx, intentions.QuerySpec{User: &alice, LocationID: &park, ActionContains: &action})
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{morning.ID, evening.ID}, intentionIDs(byLocation))
+
+		participant := "user-bob"
+		byPerson, err := store.Query(ctx, intentions.QuerySpec{PersonID: &friend, Participant: &participant})
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{lunch.ID}, intentionIDs(byPerson))
+
+		nobody := "user-carol"
+		none, err := store.Query(ctx, intentions.QuerySpec{User: &nobody})
+		require.NoError(t, err)
+		assert.Empty(t, none)
+	})
+
+	t.Run("Query pagination", func(t *testing.T) {
+		store := newStore(t)
+		// Two intentions share a start and end time, so the ID breaks the tie.
+		first := newIntention("user-alice", "First", now, time.Hour)
+		tiedA := newIntention("user-alice", "Tied", now.Add(time.Hour), time.Hour)
+		tiedB := newIntention("user-alice", "Tied", now.Add(time.Hour), time.Hour)
+		last := newIntention("user-alice", "Last", now.Add(2*time.Hour), time.Hour)
+		all := []intentions.Intention{first, tiedA, tiedB, last}
+		for _, intent := range all {
+			require.NoError(t, store.Add(ctx, intent))
+		}
+		intentions.SortIntentions(all)
+
+		alice := "user-alice"
+		var paged []intentions.Intention
+		spec := intentions.QuerySpec{User: &alice, Limit: 3}
+		for {
+			page, err := store.Query(ctx, spec)
+			require.NoError(t, err)
+			paged = append(paged, page...)
+			if len(page) < spec.Limit {
+				break
+			}
+			spec.After = intentions.CursorAfter(page[len(page)-1])
+		}
+		assert.Equal(t, intentionIDs(all), intentionIDs(paged))
+
+		_, err := store.Query(ctx, intentions.QuerySpec{After: "not a cursor"})
+		assert.ErrorIs(t, err, apperr.ErrInvalid)
+	})
+
+	t.Run("Query recurring", func(t *testing.T) {
+		store := newStore(t)
+		weekly := newIntention("user-alice", "Five-a-side", now, time.Hour)
+		weekly.Recurrence = &intentions.Recurrence{Frequency: intentions.FrequencyWeekly, Interval: 1, Count: 4}
+		require.NoError(t, store.Add(ctx, weekly))
+
+		activeAt := now.Add(14*24*time.Hour + 30*time.Minute)
+		during, err := store.Query(ctx, intentions.QuerySpec{ActiveAt: &activeAt})
+		require.NoError(t, err)
+		require.Len(t, during, 1)
+		assert.Equal(t, weekly.ID, during[0].ID)
+		assert.True(t, now.Add(14*24*time.Hour).Equal(during[0].StartTime), "the occurrence is returned, not the first instance")
+
+		between := now.Add(3 * 24 * time.Hour)
+		none, err := store.Query(ctx, intentions.QuerySpec{ActiveAt: &between})
+		require.NoError(t, err)
+		assert.Empty(t, none)
+	})
+
+	t.Run("Concurrent adds", func(t *testing.T) {
+		store := newStore(t)
+		const writers = 10
+		var wg sync.WaitGroup
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				assert.NoError(t, store.Add(ctx, newIntention("user-alice", "Work", now.Add(time.Duration(i)*time.Hour), time.Hour)))
+			}(i)
+		}
+		wg.Wait()
+
+		alice := "user-alice"
+		results, err := store.Query(ctx, intentions.QuerySpec{User: &alice})
+		require.NoError(t, err)
+		assert.Len(t, results, writers)
+	})
+}
+
+// unregisteredTarget is a Target the intentions codec does not know.
+type unregisteredTarget struct{}
+
+func (unregisteredTarget) Type() string        { return "Unregistered" }
+func (unregisteredTarget) Description() string { return "not registered" }
+
+func newIntention(user, action string, start time.Time, duration time.Duration) intentions.Intention {
+	return intentions.Intention{
+		ID:        uuid.New(),
+		User:      user,
+		Action:    action,
+		Targets:   []intentions.Target{intentions.OnlineTarget{Platform: "video"}},
+		StartTime: start,
+		EndTime:   start.Add(duration),
+		CreatedAt: start,
+		Status:    intentions.StatusPlanned,
+		Version:   1,
+	}
+}
+
+func intentionIDs(results []intentions.Intention) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(results))
+	for _, intent := range results {
+		ids = append(ids, intent.ID)
+	}
+	return ids
+}
@@ -0,0 +1,246 @@
+// FILE: pkg/storetest/locations.go
+
+// Package storetest holds conformance suites that every Store adapter must
+// pass, so the in-memory stores and the Firestore stores behave alike.
+package storetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/locations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunLocationStoreTests runs the locations.Store conformance suite. newStore
+// must return an empty store each time it is called.
+func RunLocationStoreTests(t *testing.T, newStore func(t *testing.T) locations.Store) {
+	ctx := context.Background()
+
+	t.Run("GetByID", func(t *testing.T) {
+		store := newStore(t)
+		loc := newLocation("Home", "Residence", nil)
+		require.NoError(t, store.Add(ctx, loc))
+
+		retrieved, err := store.GetByID(ctx, loc.ID)
+		require.NoError(t, err)
+		assert.Equal(t, loc.ID, retrieved.ID)
+		assert.Equal(t, loc.Name, retrieved.Name)
+
+		_, err = store.GetByID(ctx, uuid.New())
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		store := newStore(t)
+		loc := newLocation("Home", "Residence", nil)
+		require.NoError(t, store.Add(ctx, loc))
+
+		loc.Name = "Old Home"
+		loc.Matcher.Name = "Old Home"
+		require.NoError(t, store.Update(ctx, loc))
+		retrieved, err := store.GetByID(ctx, loc.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Old Home", retrieved.Name)
+
+		err = store.Update(ctx, newLocation("Nowhere", "", nil))
+		assert.ErrorIs(t, err, apperr.ErrNotFound, "updating a missing location fails")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		store := newStore(t)
+		loc := newLocation("Cafe", "Cafe", nil)
+		loc.SetCoordinates(locations.Coordinates{Lat: 55.9533, Lon: -3.1883})
+		require.NoError(t, store.Add(ctx, loc))
+
+		require.NoError(t, store.Delete(ctx, loc.ID))
+		_, err := store.GetByID(ctx, loc.ID)
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+		nearby, err := store.NearbyForMatching(ctx, 55.9533, -3.1883, 0.5)
+		require.NoError(t, err)
+		assert.Empty(t, nearby, "a deleted location is not found by the spatial index")
+
+		assert.ErrorIs(t, store.Delete(ctx, loc.ID), apperr.ErrNotFound, "deleting a missing location fails")
+	})
+
+	t.Run("ListByUserID and ListShared", func(t *testing.T) {
+		store := newStore(t)
+		alice, bob := "alice", "bob"
+		aliceHome := newLocation("Home", "Residence", &alice)
+		bobHome := newLocation("Home", "Residence", &bob)
+		park := newLocation("Park", "Recreation", nil)
+		for _, loc := range []locations.Location{aliceHome, bobHome, park} {
+			require.NoError(t, store.Add(ctx, loc))
+		}
+
+		aliceLocations, err := store.ListByUserID(ctx, alice)
+		require.NoError(t, err)
+		require.Len(t, aliceLocations, 1)
+		assert.Equal(t, aliceHome.ID, aliceLocations[0].ID)
+
+		none, err := store.ListByUserID(ctx, "carol")
+		require.NoError(t, err)
+		assert.Empty(t, none)
+
+		shared, err := store.ListShared(ctx)
+		require.NoError(t, err)
+		require.Len(t, shared, 1)
+		assert.Equal(t, park.ID, shared[0].ID)
+	})
+
+	t.Run("FindByGlobalID", func(t *testing.T) {
+		store := newStore(t)
+		globalID := "place-abc"
+		park := newLocation("Park", "Recreation", nil)
+		park.GlobalID = &globalID
+		require.NoError(t, store.Add(ctx, park))
+
+		found, err := store.FindByGlobalID(ctx, globalID)
+		require.NoError(t, err)
+		assert.Equal(t, park.ID, found.ID)
+
+		_, err = store.FindByGlobalID(ctx, "place-xyz")
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		store := newStore(t)
+		corner := newLocation("Corner Cafe", "Cafe", nil)
+		cornerBar := newLocation("corner bar", "Bar", nil)
+		central := newLocation("Central Perk", "Cafe", nil)
+		for _, loc := range []locations.Location{corner, cornerBar, central} {
+			require.NoError(t, store.Add(ctx, loc))
+		}
+
+		byPrefix, err := store.Search(ctx, "CORNER", "")
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{cornerBar.ID, corner.ID}, locationIDs(byPrefix), "prefix ignores case; results are ordered by name")
+
+		byBoth, err := store.Search(ctx, "corner", "Cafe")
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{corner.ID}, locationIDs(byBoth))
+
+		byCategory, err := store.Search(ctx, "", "Cafe")
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{central.ID, corner.ID}, locationIDs(byCategory))
+
+		all, err := store.Search(ctx, "", "")
+		require.NoError(t, err)
+		assert.Len(t, all, 3)
+
+		none, err := store.Search(ctx, "Zoo", "")
+		require.NoError(t, err)
+		assert.Empty(t, none)
+	})
+
+	t.Run("NearbyForMatching", func(t *testing.T) {
+		store := newStore(t)
+		nextDoor := newLocation("Corner Cafe", "Cafe", nil)
+		nextDoor.SetCoordinates(locations.Coordinates{Lat: 55.9534, Lon: -3.1885})
+		acrossTown := newLocation("Harbour Cafe", "Cafe", nil)
+		acrossTown.SetCoordinates(locations.Coordinates{Lat: 55.9760, Lon: -3.1700})
+		noCoordinates := newLocation("Corner Cafe", "Cafe", nil)
+		for _, loc := range []locations.Location{nextDoor, acrossTown, noCoordinates} {
+			require.NoError(t, store.Add(ctx, loc))
+		}
+
+		nearby, err := store.NearbyForMatching(ctx, 55.9533, -3.1883, 0.5)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{nextDoor.ID}, locationIDs(nearby))
+
+		nearby, err = store.NearbyForMatching(ctx, 55.9533, -3.1883, 5)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []uuid.UUID{nextDoor.ID, acrossTown.ID}, locationIDs(nearby))
+
+		// Moving a location moves it in the index.
+		acrossTown.SetCoordinates(locations.Coordinates{Lat: 55.9535, Lon: -3.1880})
+		require.NoError(t, store.Update(ctx, acrossTown))
+		nearby, err = store.NearbyForMatching(ctx, 55.9533, -3.1883, 0.5)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []uuid.UUID{nextDoor.ID, acrossTown.ID}, locationIDs(nearby))
+	})
+
+	t.Run("ListWithoutCoordinatesForMatching", func(t *testing.T) {
+		store := newStore(t)
+		located := newLocation("Corner Cafe", "Cafe", nil)
+		located.SetCoordinates(locations.Coordinates{Lat: 55.9534, Lon: -3.1885})
+		unlocated := newLocation("Corner Cafe", "Cafe", nil)
+		for _, loc := range []locations.Location{located, unlocated} {
+			require.NoError(t, store.Add(ctx, loc))
+		}
+
+		found, err := store.ListWithoutCoordinatesForMatching(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{unlocated.ID}, locationIDs(found))
+
+		// Giving a location coordinates takes it off the list.
+		unlocated.SetCoordinates(locations.Coordinates{Lat: 55.9535, Lon: -3.1880})
+		require.NoError(t, store.Update(ctx, unlocated))
+		found, err = store.ListWithoutCoordinatesForMatching(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, found)
+	})
+
+	t.Run("Concurrent adds", func(t *testing.T) {
+		store := newStore(t)
+		const writers = 10
+		var wg sync.WaitGroup
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				loc := newLocation("Stall", "Market", nil)
+				loc.SetCoordinates(locations.Coordinates{Lat: 55.9533 + float64(i)*0.0001, Lon: -3.1883})
+				assert.NoError(t, store.Add(ctx, loc))
+			}(i)
+		}
+		wg.Wait()
+
+		all, err := store.ListAllForMatching(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, writers)
+		nearby, err := store.NearbyForMatching(ctx, 55.9533, -3.1883, 0.5)
+		require.NoError(t, err)
+		assert.Len(t, nearby, writers, "every location is indexed")
+	})
+
+	t.Run("ListAllForMatching", func(t *testing.T) {
+		store := newStore(t)
+		require.NoError(t, store.Add(ctx, newLocation("Home", "Residence", nil)))
+		require.NoError(t, store.Add(ctx, newLocation("Park", "Recreation", nil)))
+
+		all, err := store.ListAllForMatching(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+	})
+}
+
+// newLocation builds a location owned by userID, or a shared one if userID is nil.
+func newLocation(name, category string, userID *string) locations.Location {
+	loc := locations.Location{
+		ID:        uuid.New(),
+		Name:      name,
+		Category:  category,
+		Matcher:   locations.LocationMatcher{Name: name, Category: category},
+		Type:      locations.LocationTypeShared,
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+	if userID != nil {
+		loc.Type = locations.LocationTypeUser
+		loc.UserID = userID
+	}
+	return loc
+}
+
+func locationIDs(locs []locations.Location) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(locs))
+	for _, loc := range locs {
+		ids = append(ids, loc.ID)
+	}
+	return ids
+}
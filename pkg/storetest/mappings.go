@@ -0,0 +1,92 @@
+// FILE: pkg/storetest/mappings.go
+
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/reconciliation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunMappingStoreTests runs the reconciliation.MappingStore conformance suite.
+// newStore must return an empty store each time it is called.
+func RunMappingStoreTests(t *testing.T, newStore func(t *testing.T) reconciliation.MappingStore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	t.Run("GetMapping", func(t *testing.T) {
+		store := newStore(t)
+		cafe := newMapping("user-alice", reconciliation.EntityLocation, now)
+		jim := newMapping("user-alice", reconciliation.EntityPerson, now)
+		require.NoError(t, store.PutMappings(ctx, []reconciliation.IdentityMapping{cafe, jim}))
+
+		got, err := store.GetMapping(ctx, "user-alice", cafe.SenderEntityID)
+		require.NoError(t, err)
+		assert.Equal(t, cafe.Kind, got.Kind)
+		assert.Equal(t, cafe.LocalID, got.LocalID)
+		assert.True(t, cafe.UpdatedAt.Equal(got.UpdatedAt))
+		got, err = store.GetMapping(ctx, "user-alice", jim.SenderEntityID)
+		require.NoError(t, err)
+		assert.Equal(t, jim.LocalID, got.LocalID)
+
+		_, err = store.GetMapping(ctx, "user-alice", uuid.New())
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+	})
+
+	t.Run("PutMappings replaces", func(t *testing.T) {
+		store := newStore(t)
+		cafe := newMapping("user-alice", reconciliation.EntityLocation, now)
+		require.NoError(t, store.PutMappings(ctx, []reconciliation.IdentityMapping{cafe}))
+
+		corrected := cafe
+		corrected.LocalID = uuid.New()
+		corrected.UpdatedAt = now.Add(time.Minute)
+		require.NoError(t, store.PutMappings(ctx, []reconciliation.IdentityMapping{corrected}))
+		got, err := store.GetMapping(ctx, "user-alice", cafe.SenderEntityID)
+		require.NoError(t, err)
+		assert.Equal(t, corrected.LocalID, got.LocalID)
+	})
+
+	t.Run("Mappings are kept per sender", func(t *testing.T) {
+		store := newStore(t)
+		cafe := newMapping("user-alice", reconciliation.EntityLocation, now)
+		spoofed := cafe
+		spoofed.SenderID = "user/mallory"
+		spoofed.LocalID = uuid.New()
+		require.NoError(t, store.PutMappings(ctx, []reconciliation.IdentityMapping{cafe, spoofed}))
+
+		got, err := store.GetMapping(ctx, "user-alice", cafe.SenderEntityID)
+		require.NoError(t, err)
+		assert.Equal(t, cafe.LocalID, got.LocalID, "another sender cannot take over a mapping by reusing an ID")
+		got, err = store.GetMapping(ctx, "user/mallory", cafe.SenderEntityID)
+		require.NoError(t, err)
+		assert.Equal(t, spoofed.LocalID, got.LocalID)
+	})
+
+	t.Run("DeleteMapping", func(t *testing.T) {
+		store := newStore(t)
+		cafe := newMapping("user-alice", reconciliation.EntityLocation, now)
+		require.NoError(t, store.PutMappings(ctx, []reconciliation.IdentityMapping{cafe}))
+
+		require.NoError(t, store.DeleteMapping(ctx, "user-alice", cafe.SenderEntityID))
+		_, err := store.GetMapping(ctx, "user-alice", cafe.SenderEntityID)
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+		assert.ErrorIs(t, store.DeleteMapping(ctx, "user-alice", cafe.SenderEntityID), apperr.ErrNotFound, "deleting a missing mapping fails")
+	})
+}
+
+func newMapping(senderID string, kind reconciliation.EntityKind, updatedAt time.Time) reconciliation.IdentityMapping {
+	return reconciliation.IdentityMapping{
+		SenderID:       senderID,
+		SenderEntityID: uuid.New(),
+		Kind:           kind,
+		LocalID:        uuid.New(),
+		UpdatedAt:      updatedAt,
+	}
+}
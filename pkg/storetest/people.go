@@ -0,0 +1,316 @@
+// FILE: pkg/storetest/people.go
+
+package storetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/illmade-knight/action-intention/pkg/apperr"
+	"github.com/illmade-knight/action-intention/pkg/people"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunPeopleStoreTests runs the people.Store conformance suite. newStore must
+// return an empty store each time it is called.
+func RunPeopleStoreTests(t *testing.T, newStore func(t *testing.T) people.Store) {
+	ctx := context.Background()
+
+	t.Run("GetPerson", func(t *testing.T) {
+		store := newStore(t)
+		alice := newPerson("Alice")
+		alice.Matcher.Handles = []people.Handle{{Kind: people.HandleEmail, Value: "alice@example.com"}}
+		require.NoError(t, store.AddPerson(ctx, alice))
+
+		got, err := store.GetPerson(ctx, alice.ID)
+		require.NoError(t, err)
+		assert.Equal(t, alice.Name, got.Name)
+		assert.Equal(t, alice.Matcher, got.Matcher)
+
+		_, err = store.GetPerson(ctx, uuid.New())
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+	})
+
+	t.Run("AddPerson replaces", func(t *testing.T) {
+		store := newStore(t)
+		alice := newPerson("Alice")
+		require.NoError(t, store.AddPerson(ctx, alice))
+
+		alice.Name = "Alice Smith"
+		require.NoError(t, store.AddPerson(ctx, alice))
+		got, err := store.GetPerson(ctx, alice.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Alice Smith", got.Name)
+
+		all, err := store.ListAllForMatching(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 1)
+	})
+
+	t.Run("FindByGlobalID", func(t *testing.T) {
+		store := newStore(t)
+		globalID := "person-abc"
+		alice := newPerson("Alice")
+		alice.GlobalID = &globalID
+		require.NoError(t, store.AddPerson(ctx, alice))
+		require.NoError(t, store.AddPerson(ctx, newPerson("Bob")))
+
+		found, err := store.FindByGlobalID(ctx, globalID)
+		require.NoError(t, err)
+		assert.Equal(t, alice.ID, found.ID)
+
+		_, err = store.FindByGlobalID(ctx, "person-xyz")
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+	})
+
+	t.Run("FindByHandle", func(t *testing.T) {
+		store := newStore(t)
+		alice, bob := newPerson("Alice"), newPerson("Bob")
+		alice.Matcher.Handles = []people.Handle{
+			{Kind: people.HandleEmail, Value: "alice@example.com"},
+			{Kind: people.HandlePhone, Value: "+353861234567"},
+		}
+		bob.Matcher.Handles = []people.Handle{{Kind: people.HandleUserID, Value: "user-bob"}}
+		require.NoError(t, store.AddPerson(ctx, alice))
+		require.NoError(t, store.AddPerson(ctx, bob))
+
+		found, err := store.FindByHandle(ctx, people.Handle{Kind: people.HandlePhone, Value: "+353861234567"})
+		require.NoError(t, err)
+		assert.Equal(t, alice.ID, found.ID)
+		assert.Equal(t, alice.Matcher.Handles, found.Matcher.Handles)
+		found, err = store.FindByHandle(ctx, people.Handle{Kind: people.HandleUserID, Value: "user-bob"})
+		require.NoError(t, err)
+		assert.Equal(t, bob.ID, found.ID)
+
+		_, err = store.FindByHandle(ctx, people.Handle{Kind: people.HandleUserID, Value: "alice@example.com"})
+		assert.ErrorIs(t, err, apperr.ErrNotFound, "the kind must match too")
+		_, err = store.FindByHandle(ctx, people.Handle{Kind: people.HandleEmail, Value: "carol@example.com"})
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+	})
+
+	t.Run("ListAllForMatching", func(t *testing.T) {
+		store := newStore(t)
+		empty, err := store.ListAllForMatching(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, empty)
+
+		require.NoError(t, store.AddPerson(ctx, newPerson("Alice")))
+		require.NoError(t, store.AddPerson(ctx, newPerson("Bob")))
+		all, err := store.ListAllForMatching(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+	})
+
+	t.Run("GetGroup", func(t *testing.T) {
+		store := newStore(t)
+		group := newGroup("Developers")
+		require.NoError(t, store.AddGroup(ctx, group))
+
+		got, err := store.GetGroup(ctx, group.ID)
+		require.NoError(t, err)
+		assert.Equal(t, group.Name, got.Name)
+		assert.Empty(t, got.MemberIDs)
+
+		_, err = store.GetGroup(ctx, uuid.New())
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+	})
+
+	t.Run("AddMemberToGroup", func(t *testing.T) {
+		store := newStore(t)
+		alice, bob := newPerson("Alice"), newPerson("Bob")
+		require.NoError(t, store.AddPerson(ctx, alice))
+		require.NoError(t, store.AddPerson(ctx, bob))
+		group := newGroup("Developers")
+		require.NoError(t, store.AddGroup(ctx, group))
+
+		require.NoError(t, store.AddMemberToGroup(ctx, group.ID, alice.ID))
+		require.NoError(t, store.AddMemberToGroup(ctx, group.ID, bob.ID))
+		require.NoError(t, store.AddMemberToGroup(ctx, group.ID, alice.ID), "adding a member again is a no-op")
+
+		got, err := store.GetGroup(ctx, group.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{alice.ID, bob.ID}, got.MemberIDs, "members keep the order they were added in")
+
+		assert.ErrorIs(t, store.AddMemberToGroup(ctx, uuid.New(), alice.ID), apperr.ErrNotFound, "the group must exist")
+		assert.ErrorIs(t, store.AddMemberToGroup(ctx, group.ID, uuid.New()), apperr.ErrNotFound, "the person must exist")
+	})
+
+	t.Run("RemoveMemberFromGroup", func(t *testing.T) {
+		store := newStore(t)
+		alice, bob := newPerson("Alice"), newPerson("Bob")
+		require.NoError(t, store.AddPerson(ctx, alice))
+		require.NoError(t, store.AddPerson(ctx, bob))
+		group := newGroup("Developers")
+		require.NoError(t, store.AddGroup(ctx, group))
+		require.NoError(t, store.AddMemberToGroup(ctx, group.ID, alice.ID))
+		require.NoError(t, store.AddMemberToGroup(ctx, group.ID, bob.ID))
+
+		require.NoError(t, store.RemoveMemberFromGroup(ctx, group.ID, alice.ID))
+		require.NoError(t, store.RemoveMemberFromGroup(ctx, group.ID, alice.ID), "removing a non-member is a no-op")
+
+		got, err := store.GetGroup(ctx, group.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{bob.ID}, got.MemberIDs)
+		_, err = store.GetPerson(ctx, alice.ID)
+		assert.NoError(t, err, "the person is not deleted")
+
+		assert.ErrorIs(t, store.RemoveMemberFromGroup(ctx, uuid.New(), bob.ID), apperr.ErrNotFound)
+	})
+
+	t.Run("RenameGroup", func(t *testing.T) {
+		store := newStore(t)
+		group := newGroup("Developers")
+		require.NoError(t, store.AddGroup(ctx, group))
+
+		require.NoError(t, store.RenameGroup(ctx, group.ID, "Engineers"))
+		got, err := store.GetGroup(ctx, group.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "Engineers", got.Name)
+
+		assert.ErrorIs(t, store.RenameGroup(ctx, uuid.New(), "Nobody"), apperr.ErrNotFound)
+	})
+
+	t.Run("ListGroupsForPerson", func(t *testing.T) {
+		store := newStore(t)
+		alice, bob := newPerson("Alice"), newPerson("Bob")
+		require.NoError(t, store.AddPerson(ctx, alice))
+		require.NoError(t, store.AddPerson(ctx, bob))
+		devs, climbers, outer := newGroup("Developers"), newGroup("Climbers"), newGroup("Everyone")
+		for _, g := range []people.Group{devs, climbers, outer} {
+			require.NoError(t, store.AddGroup(ctx, g))
+		}
+		require.NoError(t, store.AddMemberToGroup(ctx, devs.ID, alice.ID))
+		require.NoError(t, store.AddMemberToGroup(ctx, climbers.ID, alice.ID))
+		require.NoError(t, store.AddMemberToGroup(ctx, climbers.ID, bob.ID))
+		require.NoError(t, store.AddSubgroup(ctx, outer.ID, devs.ID))
+
+		groups, err := store.ListGroupsForPerson(ctx, alice.ID)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []uuid.UUID{devs.ID, climbers.ID}, groupIDs(groups), "only direct membership counts")
+
+		groups, err = store.ListGroupsForPerson(ctx, uuid.New())
+		require.NoError(t, err)
+		assert.Empty(t, groups)
+	})
+
+	t.Run("Nested groups", func(t *testing.T) {
+		store := newStore(t)
+		alice, bob, carol := newPerson("Alice"), newPerson("Bob"), newPerson("Carol")
+		for _, p := range []people.Person{alice, bob, carol} {
+			require.NoError(t, store.AddPerson(ctx, p))
+		}
+		company, engineering, backend := newGroup("Company"), newGroup("Engineering"), newGroup("Backend")
+		for _, g := range []people.Group{company, engineering, backend} {
+			require.NoError(t, store.AddGroup(ctx, g))
+		}
+		require.NoError(t, store.AddMemberToGroup(ctx, company.ID, alice.ID))
+		require.NoError(t, store.AddMemberToGroup(ctx, engineering.ID, bob.ID))
+		require.NoError(t, store.AddMemberToGroup(ctx, backend.ID, bob.ID))
+		require.NoError(t, store.AddMemberToGroup(ctx, backend.ID, carol.ID))
+		require.NoError(t, store.AddSubgroup(ctx, company.ID, engineering.ID))
+		require.NoError(t, store.AddSubgroup(ctx, engineering.ID, backend.ID))
+		require.NoError(t, store.AddSubgroup(ctx, company.ID, engineering.ID), "nesting again is a no-op")
+
+		got, err := store.GetGroup(ctx, company.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{engineering.ID}, got.SubgroupIDs)
+
+		members, err := people.ResolveMembers(ctx, store.GetGroup, company.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{alice.ID, bob.ID, carol.ID}, members, "each person appears once")
+
+		// Any nesting that would make a group contain itself is refused.
+		assert.ErrorIs(t, store.AddSubgroup(ctx, backend.ID, company.ID), people.ErrGroupCycle)
+		assert.ErrorIs(t, store.AddSubgroup(ctx, backend.ID, backend.ID), apperr.ErrConflict)
+		assert.ErrorIs(t, store.AddSubgroup(ctx, company.ID, uuid.New()), apperr.ErrNotFound)
+		assert.ErrorIs(t, store.AddSubgroup(ctx, uuid.New(), company.ID), apperr.ErrNotFound)
+
+		require.NoError(t, store.RemoveSubgroup(ctx, engineering.ID, backend.ID))
+		require.NoError(t, store.RemoveSubgroup(ctx, engineering.ID, backend.ID), "removing a group that is not nested is a no-op")
+		members, err = people.ResolveMembers(ctx, store.GetGroup, company.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{alice.ID, bob.ID}, members)
+		assert.NoError(t, store.AddSubgroup(ctx, backend.ID, company.ID), "the cycle is gone once the nesting is removed")
+	})
+
+	t.Run("DeleteGroup", func(t *testing.T) {
+		store := newStore(t)
+		alice := newPerson("Alice")
+		require.NoError(t, store.AddPerson(ctx, alice))
+		outer, inner := newGroup("Everyone"), newGroup("Developers")
+		require.NoError(t, store.AddGroup(ctx, outer))
+		require.NoError(t, store.AddGroup(ctx, inner))
+		require.NoError(t, store.AddMemberToGroup(ctx, inner.ID, alice.ID))
+		require.NoError(t, store.AddSubgroup(ctx, outer.ID, inner.ID))
+
+		require.NoError(t, store.DeleteGroup(ctx, inner.ID))
+		_, err := store.GetGroup(ctx, inner.ID)
+		assert.ErrorIs(t, err, apperr.ErrNotFound)
+		got, err := store.GetGroup(ctx, outer.ID)
+		require.NoError(t, err)
+		assert.Empty(t, got.SubgroupIDs, "the deleted group is no longer nested")
+		members, err := people.ResolveMembers(ctx, store.GetGroup, outer.ID)
+		require.NoError(t, err)
+		assert.Empty(t, members)
+		_, err = store.GetPerson(ctx, alice.ID)
+		assert.NoError(t, err, "members are not deleted with the group")
+
+		assert.ErrorIs(t, store.DeleteGroup(ctx, inner.ID), apperr.ErrNotFound)
+	})
+
+	t.Run("Concurrent AddMemberToGroup", func(t *testing.T) {
+		store := newStore(t)
+		group := newGroup("Everyone")
+		require.NoError(t, store.AddGroup(ctx, group))
+		const members = 10
+		var ids []uuid.UUID
+		for i := 0; i < members; i++ {
+			person := newPerson("Member")
+			require.NoError(t, store.AddPerson(ctx, person))
+			ids = append(ids, person.ID)
+		}
+
+		var wg sync.WaitGroup
+		for _, id := range ids {
+			wg.Add(1)
+			go func(id uuid.UUID) {
+				defer wg.Done()
+				assert.NoError(t, store.AddMemberToGroup(ctx, group.ID, id))
+			}(id)
+		}
+		wg.Wait()
+
+		got, err := store.GetGroup(ctx, group.ID)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, ids, got.MemberIDs, "no concurrent addition is lost")
+	})
+}
+
+func newPerson(name string) people.Person {
+	return people.Person{
+		ID:        uuid.New(),
+		Name:      name,
+		Matcher:   people.PersonMatcher{Name: name},
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+}
+
+func newGroup(name string) people.Group {
+	return people.Group{
+		ID:        uuid.New(),
+		Name:      name,
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+}
+
+func groupIDs(groups []people.Group) []uuid.UUID {
+	ids := make([]uuid.UUID, len(groups))
+	for i, g := range groups {
+		ids[i] = g.ID
+	}
+	return ids
+}
@@ -0,0 +1,143 @@
+// FILE: pkg/textmatch/textmatch.go
+
+// Package textmatch provides the fuzzy name comparison used by the location
+// and person matchers: normalization, edit distance and token-set similarity.
+package textmatch
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// stopWords are dropped from names before comparing them.
+var stopWords = map[string]bool{
+	"the": true, "a": true, "an": true, "of": true, "and": true,
+	"mr": true, "mrs": true, "ms": true, "dr": true,
+}
+
+// Normalize folds a name into a canonical form: accents are removed, letters
+// are lower-cased, apostrophes are dropped ("Alice's" becomes "alices"), other
+// punctuation separates words, and stop words such as "the" are removed unless
+// nothing else is left.
+func Normalize(name string) string {
+	return strings.Join(Tokens(name), " ")
+}
+
+// Tokens returns the words of a normalized name.
+func Tokens(name string) []string {
+	stripMarks := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(stripMarks, name)
+	if err != nil {
+		folded = name
+	}
+
+	var b strings.Builder
+	for _, r := range folded {
+		switch {
+		case r == '\'' || r == '’' || r == '`':
+			// Possessives and elisions join their word: "Alice's" -> "alices".
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	words := strings.Fields(b.String())
+	var tokens []string
+	for _, w := range words {
+		if !stopWords[w] {
+			tokens = append(tokens, w)
+		}
+	}
+	if len(tokens) == 0 {
+		return words
+	}
+	return tokens
+}
+
+// EditDistance returns the Levenshtein distance between two strings, counted
+// in runes.
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// EditSimilarity scales the edit distance between two strings to a score from
+// 0 (nothing in common) to 1 (identical).
+func EditSimilarity(a, b string) float64 {
+	longest := max(len([]rune(a)), len([]rune(b)))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(EditDistance(a, b))/float64(longest)
+}
+
+// TokenSetSimilarity compares two names word by word, ignoring word order.
+// Each word of the shorter name is paired with its closest word in the other;
+// the result averages how well the shorter name is covered and how much of the
+// longer one is accounted for, so "Corner Cafe" scores well against
+// "Corner Cafe Dublin" but not perfectly.
+func TokenSetSimilarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		if len(a) == len(b) {
+			return 1
+		}
+		return 0
+	}
+	short, long := a, b
+	if len(short) > len(long) {
+		short, long = long, short
+	}
+
+	used := make([]bool, len(long))
+	total := 0.0
+	for _, s := range short {
+		best, bestIdx := 0.0, -1
+		for i, l := range long {
+			if used[i] {
+				continue
+			}
+			if sim := EditSimilarity(s, l); sim > best {
+				best, bestIdx = sim, i
+			}
+		}
+		if bestIdx >= 0 {
+			used[bestIdx] = true
+			total += best
+		}
+	}
+	return (total/float64(len(short)) + total/float64(len(long))) / 2
+}
+
+// Similarity scores how alike two names are, from 0 to 1, after normalizing
+// them. It is the better of the whole-name edit similarity and the token-set
+// similarity, so both typos and reordered or extra words are tolerated.
+func Similarity(a, b string) float64 {
+	ta, tb := Tokens(a), Tokens(b)
+	na, nb := strings.Join(ta, " "), strings.Join(tb, " ")
+	if na == nb {
+		return 1
+	}
+	return max(EditSimilarity(na, nb), TokenSetSimilarity(ta, tb))
+}
@@ -0,0 +1,74 @@
+package textmatch_test
+
+import (
+	"testing"
+
+	"github.com/illmade-knight/action-intention/pkg/textmatch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"lower-cases", "Corner CAFE", "corner cafe"},
+		{"removes accents", "Alices Café", "alices cafe"},
+		{"joins possessives", "Alice's Cafe", "alices cafe"},
+		{"joins curly possessives", "Alice’s Cafe", "alices cafe"},
+		{"splits on punctuation", "St. Stephen's Green/North", "st stephens green north"},
+		{"drops stop words", "The Bank of Ireland", "bank ireland"},
+		{"keeps a name made only of stop words", "The The", "the the"},
+		{"folds compatibility forms", "Ｃａｆｅ", "cafe"},
+		{"drops honorifics", "Dr. Jane Doe", "jane doe"},
+		{"empty", "  ", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, textmatch.Normalize(tc.in))
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, textmatch.EditDistance("cafe", "cafe"))
+	assert.Equal(t, 1, textmatch.EditDistance("cafe", "caf"))
+	assert.Equal(t, 3, textmatch.EditDistance("kitten", "sitting"))
+	assert.Equal(t, 1, textmatch.EditDistance("café", "cafe"), "distance is counted in runes")
+	assert.Equal(t, 4, textmatch.EditDistance("", "park"))
+}
+
+// similarityCorpus pairs names with the band their similarity must fall in.
+var similarityCorpus = []struct {
+	a, b     string
+	min, max float64
+}{
+	// Same name written differently.
+	{"Alice's Cafe", "Alices Café", 1, 1},
+	{"The Central Perk", "Central Perk", 1, 1},
+	{"fairview park", "Fairview Park", 1, 1},
+	{"Park, Fairview", "Fairview Park", 1, 1},
+	// Typos.
+	{"Fairview Park", "Fairveiw Park", 0.8, 0.99},
+	{"Corner Cafe", "Corner Caffe", 0.9, 0.99},
+	{"Stephens Green", "St Stephens Green", 0.8, 0.99},
+	// Extra words.
+	{"Corner Cafe", "Corner Cafe Dublin", 0.8, 0.99},
+	// Different places.
+	{"Corner Cafe", "Corner Shop", 0, 0.79},
+	{"Fairview Park", "Phoenix Park", 0, 0.79},
+	{"The Central Perk", "Central Station", 0, 0.79},
+	{"Cafe", "Bar", 0, 0.3},
+}
+
+func TestSimilarity(t *testing.T) {
+	for _, tc := range similarityCorpus {
+		t.Run(tc.a+" vs "+tc.b, func(t *testing.T) {
+			got := textmatch.Similarity(tc.a, tc.b)
+			assert.GreaterOrEqual(t, got, tc.min)
+			assert.LessOrEqual(t, got, tc.max)
+			assert.InDelta(t, got, textmatch.Similarity(tc.b, tc.a), 1e-9, "similarity is symmetric")
+		})
+	}
+}